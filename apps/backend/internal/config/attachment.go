@@ -0,0 +1,145 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+type AttachmentConfig struct {
+	// MaxFileSize is the largest attachment in bytes a client may upload
+	MaxFileSize int64 `koanf:"max_file_size"`
+	// UploadURLExpiry is how long a presigned upload URL or POST policy stays valid
+	UploadURLExpiry time.Duration `koanf:"upload_url_expiry"`
+	// DownloadURLExpiry is how long a presigned download link stays valid when the client does not ask for a lifetime
+	DownloadURLExpiry time.Duration `koanf:"download_url_expiry"`
+	// MaxDownloadURLExpiry caps the lifetime a client may ask for on a presigned download link
+	MaxDownloadURLExpiry time.Duration `koanf:"max_download_url_expiry"`
+	// PendingUploadTTL is how long a requested upload may wait for confirmation before it is cleaned up
+	PendingUploadTTL time.Duration `koanf:"pending_upload_ttl"`
+	// CleanupInterval is how often unconfirmed uploads are swept
+	CleanupInterval time.Duration `koanf:"cleanup_interval"`
+	// AllowedMimeTypes limits what may be uploaded, entries like "image/*" allow a whole family.
+	// Empty allows any type.
+	AllowedMimeTypes []string `koanf:"allowed_mime_types"`
+	// ArchiveMaxSize caps the total bytes of attachments one ZIP archive may hold
+	ArchiveMaxSize int64 `koanf:"archive_max_size"`
+	// ArchiveStreamMaxSize is the largest archive streamed straight to the client, bigger ones
+	// are built by a job and handed out as a presigned link
+	ArchiveStreamMaxSize int64 `koanf:"archive_stream_max_size"`
+	// ArchiveTTL is how long an archive built by a job is kept for download
+	ArchiveTTL time.Duration `koanf:"archive_ttl"`
+	// MaxVersions is how many versions of an attachment are kept, the current one included.
+	// Older versions and their objects are deleted once a new version pushes them past the limit.
+	MaxVersions int `koanf:"max_versions"`
+	// UserQuota is how many bytes of attachments a single user may store
+	UserQuota int64 `koanf:"user_quota"`
+	// ReconcileInterval is how often stored objects are compared against attachment records
+	ReconcileInterval time.Duration `koanf:"reconcile_interval"`
+	// ReconcileDryRun only reports orphaned objects instead of deleting them
+	ReconcileDryRun bool `koanf:"reconcile_dry_run"`
+	// OrphanGracePeriod protects objects younger than this from reconciliation,
+	// so an upload whose record is still being written is not mistaken for an orphan
+	OrphanGracePeriod time.Duration `koanf:"orphan_grace_period"`
+}
+
+func DefaultAttachmentConfig() *AttachmentConfig {
+	return &AttachmentConfig{
+		MaxFileSize:          25 << 20,
+		UploadURLExpiry:      15 * time.Minute,
+		DownloadURLExpiry:    time.Hour,
+		MaxDownloadURLExpiry: 24 * time.Hour,
+		PendingUploadTTL:     time.Hour,
+		CleanupInterval:      15 * time.Minute,
+		ArchiveMaxSize:       2 << 30,
+		ArchiveStreamMaxSize: 100 << 20,
+		ArchiveTTL:           24 * time.Hour,
+		MaxVersions:          10,
+		UserQuota:            1 << 30,
+		ReconcileInterval:    24 * time.Hour,
+		OrphanGracePeriod:    24 * time.Hour,
+	}
+}
+
+// fillDefaults replaces unset values so a partially configured block stays usable
+func (c *AttachmentConfig) fillDefaults() {
+	defaults := DefaultAttachmentConfig()
+	if c.MaxFileSize <= 0 {
+		c.MaxFileSize = defaults.MaxFileSize
+	}
+	if c.UploadURLExpiry <= 0 {
+		c.UploadURLExpiry = defaults.UploadURLExpiry
+	}
+	if c.DownloadURLExpiry <= 0 {
+		c.DownloadURLExpiry = defaults.DownloadURLExpiry
+	}
+	if c.MaxDownloadURLExpiry <= 0 {
+		c.MaxDownloadURLExpiry = defaults.MaxDownloadURLExpiry
+	}
+	if c.DownloadURLExpiry > c.MaxDownloadURLExpiry {
+		c.DownloadURLExpiry = c.MaxDownloadURLExpiry
+	}
+	if c.PendingUploadTTL <= 0 {
+		c.PendingUploadTTL = defaults.PendingUploadTTL
+	}
+	// an upload must stay confirmable for at least as long as its URL can be used
+	if c.PendingUploadTTL < c.UploadURLExpiry {
+		c.PendingUploadTTL = c.UploadURLExpiry
+	}
+	if c.CleanupInterval <= 0 {
+		c.CleanupInterval = defaults.CleanupInterval
+	}
+	if c.ArchiveMaxSize <= 0 {
+		c.ArchiveMaxSize = defaults.ArchiveMaxSize
+	}
+	if c.ArchiveStreamMaxSize <= 0 {
+		c.ArchiveStreamMaxSize = defaults.ArchiveStreamMaxSize
+	}
+	if c.ArchiveStreamMaxSize > c.ArchiveMaxSize {
+		c.ArchiveStreamMaxSize = c.ArchiveMaxSize
+	}
+	if c.ArchiveTTL <= 0 {
+		c.ArchiveTTL = defaults.ArchiveTTL
+	}
+	if c.MaxVersions <= 0 {
+		c.MaxVersions = defaults.MaxVersions
+	}
+	if c.UserQuota <= 0 {
+		c.UserQuota = defaults.UserQuota
+	}
+	if c.ReconcileInterval <= 0 {
+		c.ReconcileInterval = defaults.ReconcileInterval
+	}
+	if c.OrphanGracePeriod <= 0 {
+		c.OrphanGracePeriod = defaults.OrphanGracePeriod
+	}
+
+	// env values arrive as one comma separated entry
+	allowed := []string{}
+	for _, entry := range c.AllowedMimeTypes {
+		for _, mimeType := range strings.Split(entry, ",") {
+			if mimeType = strings.ToLower(strings.TrimSpace(mimeType)); mimeType != "" {
+				allowed = append(allowed, mimeType)
+			}
+		}
+	}
+	c.AllowedMimeTypes = allowed
+}
+
+// AllowsMimeType reports whether attachments of the media type may be uploaded
+func (c *AttachmentConfig) AllowsMimeType(mimeType string) bool {
+	if len(c.AllowedMimeTypes) == 0 {
+		return true
+	}
+
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	for _, allowed := range c.AllowedMimeTypes {
+		if allowed == mimeType || allowed == "*/*" {
+			return true
+		}
+		if family, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mimeType, family+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	_ "github.com/joho/godotenv/autoload"
@@ -19,7 +20,12 @@ type Config struct {
 	Redis         RedisConfig          `koanf:"redis" validate:"required"`
 	Integration   IntegrationConfig    `koanf:"integration" validate:"required"`
 	Observability *ObservabilityConfig `koanf:"observability"`
-	AWS           AWSConfig            `koanf:"aws" validate:"required"`
+	AWS           AWSConfig            `koanf:"aws"`
+	Realtime      *RealtimeConfig      `koanf:"realtime"`
+	Attachment    *AttachmentConfig    `koanf:"attachment"`
+	Scanner       *ScannerConfig       `koanf:"scanner"`
+	Extractor     *ExtractorConfig     `koanf:"extractor"`
+	Storage       *StorageConfig       `koanf:"storage"`
 }
 
 type Primary struct {
@@ -56,13 +62,18 @@ type IntegrationConfig struct {
 
 type AuthConfig struct {
 	SecretKey string `koanf:"secret_key" validate:"required"`
+	// MembershipCacheTTL is how long organization member lists fetched from Clerk are reused
+	MembershipCacheTTL time.Duration `koanf:"membership_cache_ttl"`
 }
 
+const DefaultMembershipCacheTTL = 30 * time.Second
+
+// AWSConfig is only required when attachments are stored in S3, see StorageConfig.Validate
 type AWSConfig struct {
-	Region          string `koanf:"region" validate:"required"`
-	AccessKeyID     string `koanf:"access_key_id" validate:"required"`
-	SecretAccessKey string `koanf:"secret_access_key" validate:"required"`
-	UploadBucket    string `koanf:"upload_bucket" validate:"required"`
+	Region          string `koanf:"region"`
+	AccessKeyID     string `koanf:"access_key_id"`
+	SecretAccessKey string `koanf:"secret_access_key"`
+	UploadBucket    string `koanf:"upload_bucket"`
 	EndPointURL     string `koanf:"endpoint_url" `
 }
 
@@ -106,5 +117,49 @@ func LoadConfig() (*Config, error) {
 		logger.Fatal().Err(err).Msg("invalid observability config")
 	}
 
+	// Set default realtime config if not provided
+	if mainConfig.Realtime == nil {
+		mainConfig.Realtime = DefaultRealtimeConfig()
+	}
+	mainConfig.Realtime.fillDefaults()
+
+	if mainConfig.Auth.MembershipCacheTTL <= 0 {
+		mainConfig.Auth.MembershipCacheTTL = DefaultMembershipCacheTTL
+	}
+
+	// Set default attachment config if not provided
+	if mainConfig.Attachment == nil {
+		mainConfig.Attachment = DefaultAttachmentConfig()
+	}
+	mainConfig.Attachment.fillDefaults()
+
+	// Set default scanner config if not provided
+	if mainConfig.Scanner == nil {
+		mainConfig.Scanner = DefaultScannerConfig()
+	}
+	mainConfig.Scanner.fillDefaults()
+
+	// Set default extractor config if not provided
+	if mainConfig.Extractor == nil {
+		mainConfig.Extractor = DefaultExtractorConfig()
+	}
+	mainConfig.Extractor.fillDefaults()
+
+	// Set default storage config if not provided
+	if mainConfig.Storage == nil {
+		mainConfig.Storage = DefaultStorageConfig()
+	}
+	mainConfig.Storage.fillDefaults()
+	if mainConfig.Storage.PublicURL == "" {
+		mainConfig.Storage.PublicURL = "http://localhost:" + mainConfig.Server.Port
+	}
+	if mainConfig.Storage.SigningKey == "" {
+		mainConfig.Storage.SigningKey = mainConfig.Auth.SecretKey
+	}
+
+	if err := mainConfig.Storage.Validate(mainConfig.AWS); err != nil {
+		logger.Fatal().Err(err).Msg("invalid storage config")
+	}
+
 	return mainConfig, nil
 }
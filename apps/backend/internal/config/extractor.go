@@ -0,0 +1,53 @@
+package config
+
+import "time"
+
+const (
+	ExtractorDriverNoop    = "noop"
+	ExtractorDriverBuiltin = "builtin"
+	ExtractorDriverTika    = "tika"
+)
+
+type ExtractorConfig struct {
+	// Driver selects the text extractor, "builtin" reads text, PDF and office documents in process,
+	// "tika" sends them to an Apache Tika server and "noop" turns extraction off
+	Driver string `koanf:"driver"`
+	// TikaURL is the base URL of the Tika server
+	TikaURL string `koanf:"tika_url"`
+	// Timeout bounds a single extraction including reading the file from storage
+	Timeout time.Duration `koanf:"timeout"`
+	// MaxFileSize is the largest attachment text is extracted from, larger ones are not searchable
+	MaxFileSize int64 `koanf:"max_file_size"`
+	// MaxTextSize is how many bytes of extracted text are kept per attachment
+	MaxTextSize int `koanf:"max_text_size"`
+}
+
+func DefaultExtractorConfig() *ExtractorConfig {
+	return &ExtractorConfig{
+		Driver:      ExtractorDriverBuiltin,
+		TikaURL:     "http://localhost:9998",
+		Timeout:     time.Minute,
+		MaxFileSize: 20 << 20,
+		MaxTextSize: 1 << 20,
+	}
+}
+
+// fillDefaults replaces unset values so a partially configured block stays usable
+func (c *ExtractorConfig) fillDefaults() {
+	defaults := DefaultExtractorConfig()
+	if c.Driver == "" {
+		c.Driver = defaults.Driver
+	}
+	if c.TikaURL == "" {
+		c.TikaURL = defaults.TikaURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+	if c.MaxFileSize <= 0 {
+		c.MaxFileSize = defaults.MaxFileSize
+	}
+	if c.MaxTextSize <= 0 {
+		c.MaxTextSize = defaults.MaxTextSize
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import "time"
+
+type RealtimeConfig struct {
+	// ReplayBufferSize is the number of events kept per user for Last-Event-ID resume
+	ReplayBufferSize int64 `koanf:"replay_buffer_size"`
+	// ReplayTTL is how long a user's replay buffer survives without new events
+	ReplayTTL time.Duration `koanf:"replay_ttl"`
+	// HeartbeatInterval is how often idle streams receive a keep-alive
+	HeartbeatInterval time.Duration `koanf:"heartbeat_interval"`
+	// SubscriberBufferSize is the number of undelivered events a single connection may queue
+	SubscriberBufferSize int `koanf:"subscriber_buffer_size"`
+}
+
+func DefaultRealtimeConfig() *RealtimeConfig {
+	return &RealtimeConfig{
+		ReplayBufferSize:     500,
+		ReplayTTL:            24 * time.Hour,
+		HeartbeatInterval:    25 * time.Second,
+		SubscriberBufferSize: 64,
+	}
+}
+
+// fillDefaults replaces unset values so a partially configured block stays usable
+func (c *RealtimeConfig) fillDefaults() {
+	defaults := DefaultRealtimeConfig()
+	if c.ReplayBufferSize <= 0 {
+		c.ReplayBufferSize = defaults.ReplayBufferSize
+	}
+	if c.ReplayTTL <= 0 {
+		c.ReplayTTL = defaults.ReplayTTL
+	}
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = defaults.HeartbeatInterval
+	}
+	if c.SubscriberBufferSize <= 0 {
+		c.SubscriberBufferSize = defaults.SubscriberBufferSize
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import "time"
+
+const (
+	ScannerDriverNoop  = "noop"
+	ScannerDriverClamd = "clamd"
+)
+
+type ScannerConfig struct {
+	// Driver selects the attachment scanner, "noop" marks everything clean and "clamd" uses ClamAV
+	Driver string `koanf:"driver"`
+	// ClamdAddress is the host:port of the clamd TCP socket
+	ClamdAddress string `koanf:"clamd_address"`
+	// Timeout bounds a single scan including streaming the file to the scanner
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+func DefaultScannerConfig() *ScannerConfig {
+	return &ScannerConfig{
+		Driver:       ScannerDriverNoop,
+		ClamdAddress: "localhost:3310",
+		Timeout:      2 * time.Minute,
+	}
+}
+
+// fillDefaults replaces unset values so a partially configured block stays usable
+func (c *ScannerConfig) fillDefaults() {
+	defaults := DefaultScannerConfig()
+	if c.Driver == "" {
+		c.Driver = defaults.Driver
+	}
+	if c.ClamdAddress == "" {
+		c.ClamdAddress = defaults.ClamdAddress
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	StorageDriverS3     = "s3"
+	StorageDriverLocal  = "local"
+	StorageDriverMemory = "memory"
+)
+
+type StorageConfig struct {
+	// Driver selects where attachments are kept, "s3", "local" for a directory or "memory" for tests
+	Driver string `koanf:"driver"`
+	// LocalPath is the directory the local driver stores objects in
+	LocalPath string `koanf:"local_path"`
+	// PublicURL is the externally reachable base URL of this API, the local and memory drivers
+	// build their signed download and upload URLs on it
+	PublicURL string `koanf:"public_url"`
+	// SigningKey signs URLs served by the API itself, it falls back to the auth secret
+	SigningKey string `koanf:"signing_key"`
+	// MaxSignedURLExpiry caps how long a URL signed by the API stays valid
+	MaxSignedURLExpiry time.Duration `koanf:"max_signed_url_expiry"`
+}
+
+func DefaultStorageConfig() *StorageConfig {
+	return &StorageConfig{
+		Driver:             StorageDriverS3,
+		LocalPath:          "./data/storage",
+		MaxSignedURLExpiry: 7 * 24 * time.Hour,
+	}
+}
+
+// fillDefaults replaces unset values so a partially configured block stays usable
+func (c *StorageConfig) fillDefaults() {
+	defaults := DefaultStorageConfig()
+	if c.Driver == "" {
+		c.Driver = defaults.Driver
+	}
+	if c.LocalPath == "" {
+		c.LocalPath = defaults.LocalPath
+	}
+	if c.MaxSignedURLExpiry <= 0 {
+		c.MaxSignedURLExpiry = defaults.MaxSignedURLExpiry
+	}
+}
+
+// Validate checks the driver and, for S3, that the AWS block is complete
+func (c *StorageConfig) Validate(aws AWSConfig) error {
+	switch c.Driver {
+	case StorageDriverS3:
+		if aws.Region == "" || aws.AccessKeyID == "" || aws.SecretAccessKey == "" || aws.UploadBucket == "" {
+			return fmt.Errorf("the s3 storage driver needs aws region, access_key_id, secret_access_key and upload_bucket")
+		}
+	case StorageDriverLocal, StorageDriverMemory:
+	default:
+		return fmt.Errorf("invalid storage driver: %s (must be one of: s3, local, memory)", c.Driver)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+CREATE TABLE todo_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    owner_id TEXT NOT NULL,
+    grantee_id TEXT NOT NULL,
+    category_id UUID REFERENCES todo_categories(id) ON DELETE CASCADE,
+    todo_id UUID REFERENCES todos(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'viewer',
+    status TEXT NOT NULL DEFAULT 'pending',
+    responded_at TIMESTAMPTZ,
+
+    CONSTRAINT todo_shares_single_target CHECK ((category_id IS NULL) <> (todo_id IS NULL)),
+    CONSTRAINT todo_shares_valid_role CHECK (role IN ('viewer', 'commenter', 'editor')),
+    CONSTRAINT todo_shares_valid_status CHECK (status IN ('pending', 'accepted', 'declined')),
+    CONSTRAINT todo_shares_not_self CHECK (owner_id <> grantee_id)
+);
+
+CREATE INDEX idx_todo_shares_owner_id ON todo_shares(owner_id);
+CREATE INDEX idx_todo_shares_grantee_status ON todo_shares(grantee_id, status);
+CREATE UNIQUE INDEX todo_shares_unique_category_grantee ON todo_shares(category_id, grantee_id) WHERE category_id IS NOT NULL;
+CREATE UNIQUE INDEX todo_shares_unique_todo_grantee ON todo_shares(todo_id, grantee_id) WHERE todo_id IS NOT NULL;
+
+CREATE TRIGGER set_updated_at_todo_shares
+    BEFORE UPDATE ON todo_shares
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
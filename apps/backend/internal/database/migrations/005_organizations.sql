@@ -0,0 +1,12 @@
+-- Rows with an organization_id belong to that organization's workspace;
+-- rows without one stay in their owner's personal workspace
+ALTER TABLE todo_categories ADD COLUMN organization_id TEXT;
+ALTER TABLE todos ADD COLUMN organization_id TEXT;
+
+CREATE INDEX idx_todo_categories_organization_id ON todo_categories(organization_id) WHERE organization_id IS NOT NULL;
+CREATE INDEX idx_todos_organization_id ON todos(organization_id) WHERE organization_id IS NOT NULL;
+
+-- category names are unique per workspace instead of per user
+DROP INDEX todo_categories_unique_name;
+CREATE UNIQUE INDEX todo_categories_unique_name ON todo_categories(user_id, name) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX todo_categories_unique_org_name ON todo_categories(organization_id, name) WHERE organization_id IS NOT NULL;
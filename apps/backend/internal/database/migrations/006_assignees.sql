@@ -0,0 +1,10 @@
+CREATE TABLE todo_assignees (
+    todo_id UUID NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    assigned_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE INDEX idx_todo_assignees_user_id ON todo_assignees(user_id);
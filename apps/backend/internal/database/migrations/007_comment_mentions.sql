@@ -0,0 +1,13 @@
+CREATE TABLE comment_mentions (
+    comment_id UUID NOT NULL REFERENCES todo_comments(id) ON DELETE CASCADE,
+    todo_id UUID NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    mentioned_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- set when an edit drops the mention; the row is kept so re-adding it does not notify again
+    removed_at TIMESTAMPTZ,
+
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX idx_comment_mentions_user_id ON comment_mentions(user_id, created_at DESC) WHERE removed_at IS NULL;
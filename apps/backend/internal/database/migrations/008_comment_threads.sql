@@ -0,0 +1,17 @@
+ALTER TABLE todo_comments
+    ADD COLUMN parent_comment_id UUID REFERENCES todo_comments(id) ON DELETE CASCADE,
+    -- set when a comment with replies is deleted; the row stays as a placeholder for the thread
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD CONSTRAINT todo_comments_not_self_parent CHECK (id <> parent_comment_id);
+
+CREATE INDEX idx_todo_comments_thread ON todo_comments(todo_id, parent_comment_id, created_at, id);
+CREATE INDEX idx_todo_comments_parent_comment_id ON todo_comments(parent_comment_id, created_at, id);
+
+CREATE TABLE comment_reactions (
+    comment_id UUID NOT NULL REFERENCES todo_comments(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (comment_id, user_id, emoji)
+);
@@ -0,0 +1,16 @@
+-- Rendered, sanitized HTML of the CommonMark source, written by the API on every change.
+-- Existing text predates markdown support, so it is carried over as escaped plain text.
+CREATE OR REPLACE FUNCTION plain_text_html(input TEXT) RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN input = '' THEN ''
+        ELSE '<p>' || replace(replace(replace(replace(replace(input, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), E'\n', '<br>') || '</p>'
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE todo_comments ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+UPDATE todo_comments SET content_html = plain_text_html(content);
+
+ALTER TABLE todos ADD COLUMN description_html TEXT;
+UPDATE todos SET description_html = plain_text_html(description) WHERE description IS NOT NULL;
+
+DROP FUNCTION plain_text_html(TEXT);
@@ -0,0 +1,18 @@
+ALTER TABLE todo_comments
+    ADD COLUMN edited_at TIMESTAMPTZ,
+    ADD COLUMN deleted_by TEXT,
+    ADD COLUMN hidden_at TIMESTAMPTZ,
+    ADD COLUMN hidden_by TEXT;
+
+-- every version a comment had before an edit replaced it
+CREATE TABLE comment_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    comment_id UUID NOT NULL REFERENCES todo_comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    content_html TEXT NOT NULL,
+    edited_by TEXT NOT NULL
+);
+
+CREATE INDEX idx_comment_revisions_comment_id ON comment_revisions(comment_id, created_at DESC);
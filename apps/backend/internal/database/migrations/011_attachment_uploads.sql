@@ -0,0 +1,19 @@
+-- direct-to-storage uploads that were requested but not confirmed yet.
+-- todo_id is nulled rather than cascaded so the cleanup job still sees the row
+-- and can remove the uploaded object after its todo is gone
+CREATE TABLE attachment_uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    todo_id UUID REFERENCES todos(id) ON DELETE SET NULL,
+    uploaded_by TEXT NOT NULL,
+    upload_key TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    file_size BIGINT NOT NULL CHECK (file_size > 0),
+    method TEXT NOT NULL CHECK (method IN ('PUT', 'POST')),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_attachment_uploads_todo_id ON attachment_uploads(todo_id);
+CREATE INDEX idx_attachment_uploads_expires_at ON attachment_uploads(expires_at);
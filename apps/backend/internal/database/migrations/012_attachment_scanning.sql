@@ -0,0 +1,12 @@
+-- attachments that existed before scanning are trusted, new ones wait for the scanner
+ALTER TABLE todo_attachments
+    ADD COLUMN scan_status TEXT NOT NULL DEFAULT 'clean' CHECK (scan_status IN ('pending', 'clean', 'quarantined')),
+    ADD COLUMN scan_signature TEXT,
+    ADD COLUMN scanned_at TIMESTAMPTZ;
+
+ALTER TABLE todo_attachments
+    ALTER COLUMN scan_status SET DEFAULT 'pending';
+
+CREATE INDEX idx_todo_attachments_pending_scan ON todo_attachments(created_at)
+WHERE
+    scan_status = 'pending';
@@ -0,0 +1,7 @@
+-- filled in by the media processing job once an attachment is scanned clean
+ALTER TABLE todo_attachments
+    ADD COLUMN width INTEGER CHECK (width > 0),
+    ADD COLUMN height INTEGER CHECK (height > 0),
+    ADD COLUMN page_count INTEGER CHECK (page_count > 0),
+    ADD COLUMN thumbnails JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN processed_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- storage reconciliation looks objects up by key
+CREATE INDEX idx_todo_attachments_download_key ON todo_attachments(download_key);
+
+CREATE INDEX idx_todo_attachments_thumbnails ON todo_attachments USING GIN (thumbnails jsonb_path_ops);
@@ -0,0 +1,49 @@
+-- the todo_attachments row always describes the current version of an attachment,
+-- the objects of every version are stored under their version_id
+ALTER TABLE todo_attachments
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1 CHECK (version > 0),
+    ADD COLUMN version_id UUID,
+    ADD COLUMN versioned_at TIMESTAMPTZ;
+
+UPDATE todo_attachments
+SET
+    version_id = id,
+    versioned_at = created_at;
+
+ALTER TABLE todo_attachments
+    ALTER COLUMN version_id SET NOT NULL,
+    ALTER COLUMN versioned_at SET NOT NULL,
+    ALTER COLUMN versioned_at SET DEFAULT CURRENT_TIMESTAMP;
+
+-- earlier versions move here when a new one is uploaded or an old one restored
+CREATE TABLE todo_attachment_versions (
+    id UUID PRIMARY KEY,
+    attachment_id UUID NOT NULL REFERENCES todo_attachments(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL CHECK (version > 0),
+    created_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    name TEXT NOT NULL,
+    uploaded_by TEXT NOT NULL,
+    download_key TEXT NOT NULL,
+    file_size BIGINT,
+    mime_type TEXT,
+    scan_status TEXT NOT NULL CHECK (scan_status IN ('pending', 'clean', 'quarantined')),
+    scan_signature TEXT,
+    scanned_at TIMESTAMPTZ,
+    width INTEGER,
+    height INTEGER,
+    page_count INTEGER,
+    thumbnails JSONB NOT NULL DEFAULT '[]',
+    processed_at TIMESTAMPTZ,
+
+    UNIQUE (attachment_id, version)
+);
+
+CREATE INDEX idx_todo_attachment_versions_uploaded_by ON todo_attachment_versions(uploaded_by);
+CREATE INDEX idx_todo_attachment_versions_download_key ON todo_attachment_versions(download_key);
+CREATE INDEX idx_todo_attachment_versions_thumbnails ON todo_attachment_versions USING GIN (thumbnails jsonb_path_ops);
+
+-- a direct upload may replace an existing attachment instead of creating one
+ALTER TABLE attachment_uploads
+    ADD COLUMN attachment_id UUID;
@@ -0,0 +1,53 @@
+-- attachment objects are stored once per distinct content and shared by every attachment
+-- version with the same SHA-256. ref_count is kept by the triggers below, a blob whose count
+-- dropped to zero is released together with its object by the storage cleanup.
+CREATE TABLE attachment_blobs (
+    checksum TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    storage_key TEXT NOT NULL UNIQUE,
+    file_size BIGINT NOT NULL,
+    mime_type TEXT NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 0 CHECK (ref_count >= 0)
+);
+
+CREATE INDEX idx_attachment_blobs_released ON attachment_blobs(updated_at) WHERE ref_count = 0;
+
+CREATE TRIGGER set_updated_at_attachment_blobs
+    BEFORE UPDATE ON attachment_blobs
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- attachments stored before deduplication have no checksum until the backfill job hashes them
+ALTER TABLE todo_attachments ADD COLUMN checksum TEXT;
+ALTER TABLE todo_attachment_versions ADD COLUMN checksum TEXT;
+
+CREATE OR REPLACE FUNCTION adjust_attachment_blob_refs()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.checksum IS NOT DISTINCT FROM NEW.checksum THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.checksum IS NOT NULL THEN
+        UPDATE attachment_blobs SET ref_count = ref_count - 1 WHERE checksum = OLD.checksum;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.checksum IS NOT NULL THEN
+        UPDATE attachment_blobs SET ref_count = ref_count + 1 WHERE checksum = NEW.checksum;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER adjust_blob_refs_todo_attachments
+    AFTER INSERT OR DELETE OR UPDATE OF checksum ON todo_attachments
+    FOR EACH ROW
+    EXECUTE FUNCTION adjust_attachment_blob_refs();
+
+CREATE TRIGGER adjust_blob_refs_todo_attachment_versions
+    AFTER INSERT OR DELETE OR UPDATE OF checksum ON todo_attachment_versions
+    FOR EACH ROW
+    EXECUTE FUNCTION adjust_attachment_blob_refs();
@@ -0,0 +1,29 @@
+-- ZIP archives of a todo's attachments that were too large to stream and are built by a job.
+-- todo_id is nulled rather than cascaded so the cleanup job still removes the archive object
+-- once it expires.
+CREATE TABLE attachment_archives (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    todo_id UUID REFERENCES todos(id) ON DELETE SET NULL,
+    requested_by TEXT NOT NULL,
+    name TEXT NOT NULL,
+    attachment_ids UUID[] NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    storage_key TEXT UNIQUE,
+    file_size BIGINT,
+    file_count INTEGER,
+    error TEXT,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_attachment_archives_todo_id ON attachment_archives(todo_id);
+CREATE INDEX idx_attachment_archives_requested_by ON attachment_archives(requested_by);
+CREATE INDEX idx_attachment_archives_expires_at ON attachment_archives(expires_at);
+
+CREATE TRIGGER set_updated_at_attachment_archives
+    BEFORE UPDATE ON attachment_archives
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
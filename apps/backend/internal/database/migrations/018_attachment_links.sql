@@ -0,0 +1,12 @@
+-- link attachments bookmark a URL instead of holding a file, they have no object in storage
+-- so their download_key stays empty and nothing about them waits for a scan
+ALTER TABLE todo_attachments
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'file' CHECK (kind IN ('file', 'link')),
+    ADD COLUMN url TEXT,
+    ADD COLUMN description TEXT;
+
+ALTER TABLE todo_attachments
+    ADD CONSTRAINT todo_attachments_link_check CHECK (
+        (kind = 'file' AND url IS NULL AND download_key <> '')
+        OR (kind = 'link' AND url IS NOT NULL AND download_key = '' AND checksum IS NULL)
+    );
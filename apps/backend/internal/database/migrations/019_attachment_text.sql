@@ -0,0 +1,9 @@
+-- text extracted from the current version of a document attachment, searched with ILIKE like
+-- todo titles, which the trigram index serves for patterns of any shape
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE todo_attachments
+    ADD COLUMN content_text TEXT,
+    ADD COLUMN extracted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_todo_attachments_content_text ON todo_attachments USING GIN (content_text gin_trgm_ops);
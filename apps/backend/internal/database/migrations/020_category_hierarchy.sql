@@ -0,0 +1,50 @@
+-- categories nest under a parent category of the same workspace, top level ones have none.
+-- Deleting a category takes its subcategories with it.
+ALTER TABLE todo_categories
+    ADD COLUMN parent_category_id UUID REFERENCES todo_categories(id) ON DELETE CASCADE,
+    ADD CONSTRAINT todo_categories_parent_check CHECK (parent_category_id <> id);
+
+CREATE INDEX idx_todo_categories_parent_category_id ON todo_categories(parent_category_id) WHERE parent_category_id IS NOT NULL;
+
+-- category names are unique among the siblings under one parent instead of per workspace
+DROP INDEX todo_categories_unique_name;
+DROP INDEX todo_categories_unique_org_name;
+CREATE UNIQUE INDEX todo_categories_unique_name
+    ON todo_categories(user_id, COALESCE(parent_category_id, '00000000-0000-0000-0000-000000000000'), name)
+    WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX todo_categories_unique_org_name
+    ON todo_categories(organization_id, COALESCE(parent_category_id, '00000000-0000-0000-0000-000000000000'), name)
+    WHERE organization_id IS NOT NULL;
+
+-- moves check for cycles before updating, this catches two moves racing each other into one.
+-- They are serialized by the lock, so the walk up from the new parent sees the committed tree.
+CREATE OR REPLACE FUNCTION prevent_category_cycle()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.parent_category_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('todo_categories_tree'));
+
+    IF EXISTS (
+        WITH RECURSIVE ancestors AS (
+            SELECT id, parent_category_id FROM todo_categories WHERE id = NEW.parent_category_id
+            UNION
+            SELECT p.id, p.parent_category_id FROM todo_categories p JOIN ancestors a ON p.id = a.parent_category_id
+        )
+        SELECT 1 FROM ancestors WHERE id = NEW.id
+    ) THEN
+        RAISE EXCEPTION 'category % cannot be moved under its own subcategory %', NEW.id, NEW.parent_category_id
+            USING ERRCODE = 'check_violation', TABLE = 'todo_categories', COLUMN = 'parent_category_id',
+            CONSTRAINT = 'todo_categories_parent_check';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_cycle_todo_categories
+    BEFORE UPDATE OF parent_category_id ON todo_categories
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_category_cycle();
@@ -0,0 +1,21 @@
+-- position orders a category among its siblings, the categories under the same parent of one
+-- workspace. Existing categories keep their alphabetical order.
+ALTER TABLE todo_categories
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0 CHECK (position >= 0),
+    ADD COLUMN archived_at TIMESTAMPTZ;
+
+UPDATE todo_categories c
+SET position = ordered.position
+FROM (
+    SELECT
+        id,
+        ROW_NUMBER() OVER (
+            PARTITION BY organization_id, CASE WHEN organization_id IS NULL THEN user_id END, parent_category_id
+            ORDER BY name
+        ) - 1 AS position
+    FROM todo_categories
+) ordered
+WHERE c.id = ordered.id;
+
+-- archived categories and their todos are left out of default listings
+CREATE INDEX idx_todo_categories_archived_at ON todo_categories(archived_at) WHERE archived_at IS NOT NULL;
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/newrelic/go-agent/v3/integrations/nrpkgerrors"
 	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/C0deNe0/go-tasker/internal/lib/filename"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/C0deNe0/go-tasker/internal/validation"
@@ -68,6 +72,25 @@ func (h NoContentResponseHandler) AddAttributes(txn *newrelic.Transaction, resul
 	// http.status_code is already set by tracing middleware
 }
 
+// FileStream is a file served straight from its source, Content is closed once the response is written
+type FileStream struct {
+	Name        string
+	ContentType string
+	Disposition string
+	ModifiedAt  time.Time
+	Content     io.ReadSeekCloser
+}
+
+// FileWriter is a file written to the response while it is produced, for content like archives whose
+// size is not known up front. Once Write starts the status is sent, so a failure can only cut the
+// response short. Accepted is sent as JSON with 202 Accepted instead when the file is produced elsewhere.
+type FileWriter struct {
+	Name        string
+	ContentType string
+	Write       func(w io.Writer) error
+	Accepted    any
+}
+
 // FileResponseHandler handles file responses
 type FileResponseHandler struct {
 	status      int
@@ -76,11 +99,67 @@ type FileResponseHandler struct {
 }
 
 func (h FileResponseHandler) Handle(c echo.Context, result interface{}) error {
+	if stream, ok := result.(*FileStream); ok {
+		return h.handleStream(c, stream)
+	}
+	if writer, ok := result.(*FileWriter); ok {
+		return h.handleWriter(c, writer)
+	}
+
 	data := result.([]byte)
 	c.Response().Header().Set("Content-Disposition", "attachment; filename="+h.filename)
 	return c.Blob(h.status, h.contentType, data)
 }
 
+// handleStream serves a FileStream with http.ServeContent, which answers Range and conditional
+// requests itself and so picks the status code
+func (h FileResponseHandler) handleStream(c echo.Context, stream *FileStream) error {
+	defer stream.Content.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, stream.ContentType)
+	header.Set(echo.HeaderContentDisposition, filename.ContentDisposition(stream.Disposition, stream.Name))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "sandbox")
+	header.Set("Cache-Control", "private, max-age=0")
+
+	clearWriteDeadline(c)
+	http.ServeContent(c.Response(), c.Request(), "", stream.ModifiedAt, stream.Content)
+	return nil
+}
+
+// clearWriteDeadline lifts the server write timeout for a response whose body can take longer to send
+// than the timeout allows, a large file or a slow client would otherwise be cut off mid-body
+func clearWriteDeadline(c echo.Context) {
+	if err := http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{}); err != nil &&
+		!errors.Is(err, http.ErrNotSupported) {
+		middleware.GetLogger(c).Warn().Err(err).Msg("failed to clear write deadline for file response")
+	}
+}
+
+// handleWriter sends a FileWriter's headers and lets it write the body, flushing as it goes
+func (h FileResponseHandler) handleWriter(c echo.Context, writer *FileWriter) error {
+	if writer.Accepted != nil {
+		return c.JSON(http.StatusAccepted, writer.Accepted)
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, writer.ContentType)
+	header.Set(echo.HeaderContentDisposition, filename.ContentDisposition("attachment", writer.Name))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set("Cache-Control", "private, no-store")
+
+	clearWriteDeadline(c)
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := writer.Write(c.Response()); err != nil {
+		middleware.GetLogger(c).Error().Err(err).Str("filename", writer.Name).Msg("failed to write file response")
+		return err
+	}
+
+	return nil
+}
+
 func (h FileResponseHandler) GetOperation() string {
 	return "handler_file"
 }
@@ -93,6 +172,14 @@ func (h FileResponseHandler) AddAttributes(txn *newrelic.Transaction, result int
 		if data, ok := result.([]byte); ok {
 			txn.AddAttribute("file.size_bytes", len(data))
 		}
+		if stream, ok := result.(*FileStream); ok {
+			txn.AddAttribute("file.name", stream.Name)
+			txn.AddAttribute("file.content_type", stream.ContentType)
+		}
+		if writer, ok := result.(*FileWriter); ok && writer.Accepted == nil {
+			txn.AddAttribute("file.name", writer.Name)
+			txn.AddAttribute("file.content_type", writer.ContentType)
+		}
 	}
 }
 
@@ -239,6 +326,34 @@ func HandleFile[Req validation.Validatable](
 	}
 }
 
+// HandleFileStream wraps a handler that streams a file, the name and content type come from the
+// returned FileStream since they are only known once the file is opened
+func HandleFileStream[Req validation.Validatable](
+	h Handler,
+	handler HandlerFunc[Req, *FileStream],
+	req Req,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return handleRequest(c, req, func(c echo.Context, req Req) (interface{}, error) {
+			return handler(c, req)
+		}, FileResponseHandler{status: http.StatusOK})
+	}
+}
+
+// HandleFileWriter wraps a handler that writes a file while producing it, or hands back a
+// FileWriter with Accepted set when the file is produced in the background
+func HandleFileWriter[Req validation.Validatable](
+	h Handler,
+	handler HandlerFunc[Req, *FileWriter],
+	req Req,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return handleRequest(c, req, func(c echo.Context, req Req) (interface{}, error) {
+			return handler(c, req)
+		}, FileResponseHandler{status: http.StatusOK})
+	}
+}
+
 // HandleNoContent wraps a handler with validation, error handling, logging, metrics, and tracing for endpoints that don't return content
 func HandleNoContent[Req validation.Validatable](
 	h Handler,
@@ -39,7 +39,7 @@ func (h *CategoryHandler) GetCategories(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, query *category.GetCategoriesQuery) (
-			*model.PaginatedResponse[category.Category], error,
+			*model.PaginatedResponse[category.CategoryWithStats], error,
 		) {
 			userID := middleware.GetUserID(c)
 			return h.categoryService.GetCategories(c, userID, query)
@@ -49,6 +49,18 @@ func (h *CategoryHandler) GetCategories(c echo.Context) error {
 	)(c)
 }
 
+func (h *CategoryHandler) GetCategoryTree(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *category.GetCategoryTreePayload) ([]category.CategoryNode, error) {
+			userID := middleware.GetUserID(c)
+			return h.categoryService.GetCategoryTree(c, userID, payload)
+		},
+		http.StatusOK,
+		&category.GetCategoryTreePayload{},
+	)(c)
+}
+
 func (h *CategoryHandler) UpdateCategory(c echo.Context) error {
 	return Handle(
 		h.Handler,
@@ -61,14 +73,62 @@ func (h *CategoryHandler) UpdateCategory(c echo.Context) error {
 	)(c)
 }
 
+func (h *CategoryHandler) MoveCategory(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *category.MoveCategoryPayload) (*category.Category, error) {
+			userID := middleware.GetUserID(c)
+			return h.categoryService.MoveCategory(c, userID, payload)
+		},
+		http.StatusOK,
+		&category.MoveCategoryPayload{},
+	)(c)
+}
+
+func (h *CategoryHandler) ReorderCategories(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *category.ReorderCategoriesPayload) ([]category.Category, error) {
+			userID := middleware.GetUserID(c)
+			return h.categoryService.ReorderCategories(c, userID, payload)
+		},
+		http.StatusOK,
+		&category.ReorderCategoriesPayload{},
+	)(c)
+}
+
+func (h *CategoryHandler) ArchiveCategory(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *category.ArchiveCategoryPayload) (*category.Category, error) {
+			userID := middleware.GetUserID(c)
+			return h.categoryService.SetCategoryArchived(c, userID, payload.ID, true)
+		},
+		http.StatusOK,
+		&category.ArchiveCategoryPayload{},
+	)(c)
+}
+
+func (h *CategoryHandler) UnarchiveCategory(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *category.ArchiveCategoryPayload) (*category.Category, error) {
+			userID := middleware.GetUserID(c)
+			return h.categoryService.SetCategoryArchived(c, userID, payload.ID, false)
+		},
+		http.StatusOK,
+		&category.ArchiveCategoryPayload{},
+	)(c)
+}
+
 func (h *CategoryHandler) DeleteCategory(c echo.Context) error {
-	return HandleNoContent(
+	return Handle(
 		h.Handler,
-		func(c echo.Context, payload *category.DeleteCategoryPayload) error {
+		func(c echo.Context, payload *category.DeleteCategoryPayload) (*category.DeleteCategoryResult, error) {
 			userID := middleware.GetUserID(c)
-			return h.categoryService.DeleteCategory(c, userID, payload.ID)
+			return h.categoryService.DeleteCategory(c, userID, payload)
 		},
-		http.StatusNoContent,
+		http.StatusOK,
 		&category.DeleteCategoryPayload{},
 	)(c)
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model"
 	"github.com/C0deNe0/go-tasker/internal/model/comment"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/C0deNe0/go-tasker/internal/service"
@@ -37,15 +38,27 @@ func (h *CommentHandler) AddComment(c echo.Context) error {
 func (h *CommentHandler) GetCommentsByTodoID(c echo.Context) error {
 	return Handle(
 		h.Handler,
-		func(c echo.Context, payload *comment.GetCommentByTodoIDPayload) ([]comment.Comment, error) {
+		func(c echo.Context, payload *comment.GetCommentByTodoIDPayload) (*model.CursorPage[comment.PopulatedComment], error) {
 			userID := middleware.GetUserID(c)
-			return h.commentService.GetCommentsByTodoID(c, userID, payload.TodoID)
+			return h.commentService.GetCommentsByTodoID(c, userID, payload)
 		},
 		http.StatusOK,
 		&comment.GetCommentByTodoIDPayload{},
 	)(c)
 }
 
+func (h *CommentHandler) GetCommentReplies(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.GetCommentRepliesPayload) (*model.CursorPage[comment.PopulatedComment], error) {
+			userID := middleware.GetUserID(c)
+			return h.commentService.GetCommentReplies(c, userID, payload)
+		},
+		http.StatusOK,
+		&comment.GetCommentRepliesPayload{},
+	)(c)
+}
+
 func (h *CommentHandler) UpdateComment(c echo.Context) error {
 	return Handle(
 		h.Handler,
@@ -69,3 +82,75 @@ func (h *CommentHandler) DeleteComment(c echo.Context) error {
 		&comment.DeleteCommentPayload{},
 	)(c)
 }
+
+func (h *CommentHandler) HideComment(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.ModerateCommentPayload) (*comment.Comment, error) {
+			userID := middleware.GetUserID(c)
+			return h.commentService.SetCommentHidden(c, userID, payload.ID, true)
+		},
+		http.StatusOK,
+		&comment.ModerateCommentPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) UnhideComment(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.ModerateCommentPayload) (*comment.Comment, error) {
+			userID := middleware.GetUserID(c)
+			return h.commentService.SetCommentHidden(c, userID, payload.ID, false)
+		},
+		http.StatusOK,
+		&comment.ModerateCommentPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) GetCommentRevisions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.GetCommentRevisionsPayload) ([]comment.Revision, error) {
+			userID := middleware.GetUserID(c)
+			return h.commentService.GetCommentRevisions(c, userID, payload.ID)
+		},
+		http.StatusOK,
+		&comment.GetCommentRevisionsPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) AddReaction(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.AddReactionPayload) (*comment.PopulatedComment, error) {
+			userID := middleware.GetUserID(c)
+			return h.commentService.AddReaction(c, userID, payload)
+		},
+		http.StatusOK,
+		&comment.AddReactionPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) RemoveReaction(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *comment.RemoveReactionPayload) error {
+			userID := middleware.GetUserID(c)
+			return h.commentService.RemoveReaction(c, userID, payload)
+		},
+		http.StatusNoContent,
+		&comment.RemoveReactionPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) GetMentions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *comment.GetMentionsQuery) (*model.PaginatedResponse[comment.PopulatedMention], error) {
+			userID := middleware.GetUserID(c)
+			return h.commentService.GetMentions(c, userID, query)
+		},
+		http.StatusOK,
+		&comment.GetMentionsQuery{},
+	)(c)
+}
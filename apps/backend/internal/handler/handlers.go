@@ -11,6 +11,10 @@ type Handlers struct {
 	Todo     *TodoHandler
 	Comment  *CommentHandler
 	Category *CategoryHandler
+	Realtime *RealtimeHandler
+	Share    *ShareHandler
+	Markdown *MarkdownHandler
+	Storage  *StorageHandler
 }
 
 func NewHandlers(s *server.Server, services *service.Services) *Handlers {
@@ -20,5 +24,9 @@ func NewHandlers(s *server.Server, services *service.Services) *Handlers {
 		Todo:     NewTodoHandler(s, services.Todo),
 		Comment:  NewCommentHandler(s, services.Comment),
 		Category: NewCategoryHandler(s, services.Category),
+		Realtime: NewRealtimeHandler(s),
+		Share:    NewShareHandler(s, services.Share),
+		Markdown: NewMarkdownHandler(s),
+		Storage:  NewStorageHandler(s, services.Storage),
 	}
 }
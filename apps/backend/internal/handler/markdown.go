@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/C0deNe0/go-tasker/internal/lib/markdown"
+	markdownModel "github.com/C0deNe0/go-tasker/internal/model/markdown"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type MarkdownHandler struct {
+	Handler
+}
+
+func NewMarkdownHandler(s *server.Server) *MarkdownHandler {
+	return &MarkdownHandler{
+		Handler: NewHandler(s),
+	}
+}
+
+// Preview renders comment or description source exactly as it would be stored, without saving it
+func (h *MarkdownHandler) Preview(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *markdownModel.PreviewPayload) (*markdownModel.Preview, error) {
+			html, err := markdown.Render(payload.Content)
+			if err != nil {
+				return nil, err
+			}
+
+			return &markdownModel.Preview{HTML: html}, nil
+		},
+		http.StatusOK,
+		&markdownModel.PreviewPayload{},
+	)(c)
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+)
+
+const sseRetryMillis = 3000
+
+type RealtimeHandler struct {
+	Handler
+}
+
+func NewRealtimeHandler(s *server.Server) *RealtimeHandler {
+	return &RealtimeHandler{
+		Handler: NewHandler(s),
+	}
+}
+
+// StreamEvents pushes the caller's change events as Server-Sent Events.
+// Clients resume after a reconnect through the standard Last-Event-ID header.
+func (h *RealtimeHandler) StreamEvents(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	logger := middleware.GetLogger(c).With().Str("operation", "realtime_sse").Logger()
+	ctx := c.Request().Context()
+	broker := h.server.Realtime
+
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("lastEventId")
+	}
+
+	// subscribe before replaying so nothing published in between is missed
+	sub := broker.Subscribe(userID)
+	defer broker.Unsubscribe(sub)
+
+	replay, err := broker.Replay(ctx, userID, lastEventID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to replay realtime events")
+		return errs.NewInternalServerError()
+	}
+
+	// the stream outlives the server write timeout
+	if err := http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{}); err != nil &&
+		!errors.Is(err, http.ErrNotSupported) {
+		logger.Warn().Err(err).Msg("failed to clear write deadline for event stream")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", sseRetryMillis); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	logger.Info().Str("last_event_id", lastEventID).Int("replayed", len(replay)).Msg("realtime stream opened")
+
+	lastSent := lastEventID
+	for i := range replay {
+		if err := writeSSEEvent(res, &replay[i]); err != nil {
+			return nil
+		}
+		if replay[i].Type != realtime.EventResync {
+			lastSent = replay[i].ID
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(h.server.Config.Realtime.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Msg("realtime stream closed by client")
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				logger.Info().Msg("realtime subscription dropped")
+				return nil
+			}
+			if lastSent != "" && !event.After(lastSent) {
+				continue
+			}
+			if err := writeSSEEvent(res, &event); err != nil {
+				return nil
+			}
+			lastSent = event.ID
+			res.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(res *echo.Response, event *realtime.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// a resync carries no ID so the client keeps its last position
+	if event.Type != realtime.EventResync {
+		if _, err := fmt.Fprintf(res, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Type, body)
+	return err
+}
+
+// heartbeatMessage keeps idle websocket connections alive through proxies
+type heartbeatMessage struct {
+	Type string `json:"type"`
+}
+
+// StreamEventsWebSocket delivers the same events as StreamEvents over a websocket.
+// Clients resume with the lastEventId query parameter.
+func (h *RealtimeHandler) StreamEventsWebSocket(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	logger := middleware.GetLogger(c).With().Str("operation", "realtime_ws").Logger()
+	broker := h.server.Realtime
+	lastEventID := c.QueryParam("lastEventId")
+
+	wsServer := websocket.Server{
+		Handshake: func(cfg *websocket.Config, r *http.Request) error {
+			return h.checkOrigin(cfg, r)
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// we never expect client messages; reading only detects disconnects
+			go func() {
+				defer cancel()
+				var discard string
+				for {
+					if err := websocket.Message.Receive(ws, &discard); err != nil {
+						return
+					}
+				}
+			}()
+
+			sub := broker.Subscribe(userID)
+			defer broker.Unsubscribe(sub)
+
+			replay, err := broker.Replay(ctx, userID, lastEventID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to replay realtime events")
+				return
+			}
+
+			logger.Info().Str("last_event_id", lastEventID).Int("replayed", len(replay)).Msg("realtime websocket opened")
+
+			lastSent := lastEventID
+			for i := range replay {
+				if err := websocket.JSON.Send(ws, replay[i]); err != nil {
+					return
+				}
+				if replay[i].Type != realtime.EventResync {
+					lastSent = replay[i].ID
+				}
+			}
+
+			heartbeat := time.NewTicker(h.server.Config.Realtime.HeartbeatInterval)
+			defer heartbeat.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					logger.Info().Msg("realtime websocket closed by client")
+					return
+				case <-heartbeat.C:
+					if err := websocket.JSON.Send(ws, heartbeatMessage{Type: "heartbeat"}); err != nil {
+						return
+					}
+				case event, ok := <-sub.Events():
+					if !ok {
+						logger.Info().Msg("realtime subscription dropped")
+						return
+					}
+					if lastSent != "" && !event.After(lastSent) {
+						continue
+					}
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						return
+					}
+					lastSent = event.ID
+				}
+			}
+		},
+	}
+
+	wsServer.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// checkOrigin only accepts browser connections from the configured CORS origins
+func (h *RealtimeHandler) checkOrigin(cfg *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(cfg, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return nil
+	}
+
+	allowed := h.server.Config.Server.CORSAllowedOrigins
+	if slices.Contains(allowed, "*") || slices.Contains(allowed, origin.Scheme+"://"+origin.Host) {
+		return nil
+	}
+
+	return fmt.Errorf("origin %s://%s is not allowed", origin.Scheme, origin.Host)
+}
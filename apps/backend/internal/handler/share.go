@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/C0deNe0/go-tasker/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type ShareHandler struct {
+	Handler
+	shareService *service.ShareService
+}
+
+func NewShareHandler(s *server.Server, shareService *service.ShareService) *ShareHandler {
+	return &ShareHandler{
+		Handler:      NewHandler(s),
+		shareService: shareService,
+	}
+}
+
+func (h *ShareHandler) ShareCategory(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.ShareCategoryPayload) (*share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.ShareCategory(c, userID, payload)
+		},
+		http.StatusCreated,
+		&share.ShareCategoryPayload{},
+	)(c)
+}
+
+func (h *ShareHandler) GetCategoryShares(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.GetCategorySharesPayload) ([]share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.GetCategoryShares(c, userID, payload.CategoryID)
+		},
+		http.StatusOK,
+		&share.GetCategorySharesPayload{},
+	)(c)
+}
+
+func (h *ShareHandler) ShareTodo(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.ShareTodoPayload) (*share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.ShareTodo(c, userID, payload)
+		},
+		http.StatusCreated,
+		&share.ShareTodoPayload{},
+	)(c)
+}
+
+func (h *ShareHandler) GetTodoShares(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.GetTodoSharesPayload) ([]share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.GetTodoShares(c, userID, payload.TodoID)
+		},
+		http.StatusOK,
+		&share.GetTodoSharesPayload{},
+	)(c)
+}
+
+func (h *ShareHandler) GetSharedWithMe(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *share.GetSharedWithMeQuery) ([]share.PopulatedShare, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.GetSharedWithMe(c, userID, query)
+		},
+		http.StatusOK,
+		&share.GetSharedWithMeQuery{},
+	)(c)
+}
+
+func (h *ShareHandler) UpdateShare(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.UpdateSharePayload) (*share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.UpdateShare(c, userID, payload)
+		},
+		http.StatusOK,
+		&share.UpdateSharePayload{},
+	)(c)
+}
+
+func (h *ShareHandler) AcceptShare(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.RespondToSharePayload) (*share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.AcceptShare(c, userID, payload.ID)
+		},
+		http.StatusOK,
+		&share.RespondToSharePayload{},
+	)(c)
+}
+
+func (h *ShareHandler) DeclineShare(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *share.RespondToSharePayload) (*share.Share, error) {
+			userID := middleware.GetUserID(c)
+			return h.shareService.DeclineShare(c, userID, payload.ID)
+		},
+		http.StatusOK,
+		&share.RespondToSharePayload{},
+	)(c)
+}
+
+func (h *ShareHandler) DeleteShare(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *share.DeleteSharePayload) error {
+			userID := middleware.GetUserID(c)
+			return h.shareService.DeleteShare(c, userID, payload.ID)
+		},
+		http.StatusNoContent,
+		&share.DeleteSharePayload{},
+	)(c)
+}
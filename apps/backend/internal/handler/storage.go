@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/lib/storage"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+// StorageHandler serves the signed URLs of storage drivers that have no URLs of their own.
+// The signature is the only authorization, so the routes sit outside the auth middleware.
+type StorageHandler struct {
+	Handler
+	storage storage.Storage
+}
+
+func NewStorageHandler(s *server.Server, blobStorage storage.Storage) *StorageHandler {
+	return &StorageHandler{
+		Handler: NewHandler(s),
+		storage: blobStorage,
+	}
+}
+
+// Download streams the object a signed GET URL points at, with Range support
+func (h *StorageHandler) Download(c echo.Context) error {
+	served, request, err := h.verify(c, http.MethodGet, c.QueryParams())
+	if err != nil {
+		return err
+	}
+
+	body, info, err := served.Get(c.Request().Context(), request.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return errs.NewNotFoundError("object not found", false, nil)
+		}
+		middleware.GetLogger(c).Error().Err(err).Str("key", request.Key).Msg("failed to open stored object")
+		return errs.NewInternalServerError()
+	}
+	defer body.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, info.ContentType)
+	if request.ContentDisposition != "" {
+		header.Set(echo.HeaderContentDisposition, request.ContentDisposition)
+	}
+	// objects may be served inline from the API origin, keep them from running scripts there
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "sandbox")
+	header.Set("Cache-Control", "private, max-age="+strconv.Itoa(int(time.Until(request.ExpiresAt).Seconds())))
+
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(c.Response(), c.Request(), "", time.Time{}, seeker)
+		return nil
+	}
+
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(info.Size, 10))
+	c.Response().WriteHeader(http.StatusOK)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	_, err = io.Copy(c.Response(), body)
+	return err
+}
+
+// Upload stores the body of a signed PUT, which must match the signed size and content type
+func (h *StorageHandler) Upload(c echo.Context) error {
+	served, request, err := h.verify(c, http.MethodPut, c.QueryParams())
+	if err != nil {
+		return err
+	}
+
+	if !sameContentType(c.Request().Header.Get(echo.HeaderContentType), request.ContentType) {
+		code := "UPLOAD_MISMATCH"
+		return errs.NewBadRequestError("content type does not match the signed upload", false, &code, nil, nil)
+	}
+	if length := c.Request().ContentLength; length >= 0 && length != request.Size {
+		code := "UPLOAD_MISMATCH"
+		return errs.NewBadRequestError("content length does not match the signed upload", false, &code, nil, nil)
+	}
+
+	return h.store(c, served, request, c.Request().Body, true)
+}
+
+// UploadForm stores the file of a signed multipart POST. The signed fields must come before the file.
+func (h *StorageHandler) UploadForm(c echo.Context) error {
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return errs.NewBadRequestError("multipart form not found", false, nil, nil, nil)
+	}
+
+	fields := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return errs.NewBadRequestError("no file found", false, nil, nil, nil)
+		}
+		if err != nil {
+			return errs.NewBadRequestError("failed to read multipart form", false, nil, nil, nil)
+		}
+
+		if part.FormName() == "file" {
+			served, request, err := h.verify(c, http.MethodPost, fields)
+			if err != nil {
+				return err
+			}
+			return h.store(c, served, request, part, false)
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, 4096))
+		if err != nil {
+			return errs.NewBadRequestError("failed to read multipart form", false, nil, nil, nil)
+		}
+		fields.Set(part.FormName(), string(value))
+	}
+}
+
+// store writes the upload capped at the signed size, an exact upload of the wrong size is removed again
+func (h *StorageHandler) store(c echo.Context, served storage.Served, request *storage.SignedRequest, body io.Reader, exact bool) error {
+	logger := middleware.GetLogger(c)
+
+	info, err := served.Put(c.Request().Context(), request.Key, body, storage.PutOptions{
+		ContentType: request.ContentType,
+		MaxSize:     request.Size,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrTooLarge) {
+			code := "FILE_TOO_LARGE"
+			return errs.NewBadRequestError("the file is larger than the signed upload allows", false, &code, nil, nil)
+		}
+		logger.Error().Err(err).Str("key", request.Key).Msg("failed to store uploaded object")
+		return errs.NewInternalServerError()
+	}
+
+	if exact && info.Size != request.Size {
+		if err := served.Delete(c.Request().Context(), request.Key); err != nil {
+			logger.Error().Err(err).Str("key", request.Key).Msg("failed to delete mismatched upload")
+		}
+		code := "UPLOAD_MISMATCH"
+		return errs.NewBadRequestError("the file size does not match the signed upload", false, &code, nil, nil)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *StorageHandler) verify(c echo.Context, method string, values url.Values) (storage.Served, *storage.SignedRequest, error) {
+	served, ok := h.storage.(storage.Served)
+	if !ok {
+		return nil, nil, errs.NewNotFoundError("storage is not served by this API", false, nil)
+	}
+
+	request, err := served.Signer().Verify(method, values)
+	if err != nil {
+		middleware.GetLogger(c).Warn().Err(err).Str("method", method).Msg("rejected storage url")
+		if errors.Is(err, storage.ErrURLExpired) {
+			return nil, nil, errs.NewForbiddenError("this link has expired", true)
+		}
+		return nil, nil, errs.NewForbiddenError("invalid link signature", true)
+	}
+
+	return served, request, nil
+}
+
+// sameContentType compares media types ignoring case and parameters
+func sameContentType(a, b string) bool {
+	parsedA, _, errA := mime.ParseMediaType(a)
+	parsedB, _, errB := mime.ParseMediaType(b)
+
+	return errA == nil && errB == nil && parsedA == parsedB
+}
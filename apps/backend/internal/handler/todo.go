@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/C0deNe0/go-tasker/internal/errs"
@@ -96,30 +99,226 @@ func (h *TodoHandler) GetTodoStats(c echo.Context) error {
 	)(c)
 }
 
+func (h *TodoHandler) GetInbox(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *todo.GetInboxQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetInbox(c, userID, query)
+		},
+		http.StatusOK,
+		&todo.GetInboxQuery{},
+	)(c)
+}
+
+func (h *TodoHandler) AssignTodo(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.AssignTodoPayload) ([]todo.TodoAssignee, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.AssignTodo(c, userID, payload)
+		},
+		http.StatusOK,
+		&todo.AssignTodoPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) UnassignTodo(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *todo.UnassignTodoPayload) error {
+			userID := middleware.GetUserID(c)
+			return h.todoService.UnassignTodo(c, userID, payload)
+		},
+		http.StatusNoContent,
+		&todo.UnassignTodoPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) ToggleDescriptionTask(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.ToggleDescriptionTaskPayload) (*todo.Todo, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.ToggleDescriptionTask(c, userID, payload)
+		},
+		http.StatusOK,
+		&todo.ToggleDescriptionTaskPayload{},
+	)(c)
+}
+
+// multipartOverhead is room in the request body for multipart boundaries and part headers
+const multipartOverhead = 1 << 20
+
+// UploadTodoAttachment streams the first "file" part of a multipart body straight to storage,
+// so large files are never held in memory or spooled to disk
 func (h *TodoHandler) UploadTodoAttachment(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *todo.UploadTodoAttachmentPayload) (*todo.TodoAttachment, error) {
 			userID := middleware.GetUserID(c)
 
-			form, err := c.MultipartForm()
+			part, err := h.multipartFile(c)
 			if err != nil {
-				return nil, errs.NewBadRequestError("multipart form not found", false, nil, nil, nil)
+				return nil, err
 			}
 
-			files := form.File["file"]
-			if len(files) == 0 {
-				return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
-			}
+			return h.todoService.UploadTodoAttachment(c, userID, payload.TodoID, part.FileName(), part)
+		},
+		http.StatusCreated,
+		&todo.UploadTodoAttachmentPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) UploadAttachmentVersion(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.UploadAttachmentVersionPayload) (*todo.TodoAttachment, error) {
+			userID := middleware.GetUserID(c)
 
-			if len(files) > 1 {
-				return nil, errs.NewBadRequestError("only one file allowed per upload", false, nil, nil, nil)
+			part, err := h.multipartFile(c)
+			if err != nil {
+				return nil, err
 			}
 
-			return h.todoService.UploadTodoAttachment(c, userID, payload.TodoID.String(), files[0])
+			return h.todoService.UploadAttachmentVersion(c, userID, payload.TodoID, payload.AttachmentID, part.FileName(), part)
 		},
 		http.StatusCreated,
-		&todo.UploadTodoAttachmentPayload{},
+		&todo.UploadAttachmentVersionPayload{},
+	)(c)
+}
+
+// multipartFile finds the "file" part of a multipart upload without buffering the body,
+// which is capped at the maximum attachment size
+func (h *TodoHandler) multipartFile(c echo.Context) (*multipart.Part, error) {
+	maxBodySize := h.server.Config.Attachment.MaxFileSize + multipartOverhead
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxBodySize)
+
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return nil, errs.NewBadRequestError("multipart form not found", false, nil, nil, nil)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
+		}
+		if err != nil {
+			return nil, errs.NewBadRequestError("failed to read multipart form", false, nil, nil, nil)
+		}
+
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
+
+func (h *TodoHandler) GetAttachmentVersions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetAttachmentVersionsPayload) ([]todo.AttachmentVersion, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetAttachmentVersions(c, userID, payload.TodoID, payload.AttachmentID)
+		},
+		http.StatusOK,
+		&todo.GetAttachmentVersionsPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetAttachmentVersionPresignedURL(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetAttachmentVersionPresignedURLPayload) (*todo.AttachmentDownloadURL, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetAttachmentVersionPresignedURL(c, userID, payload)
+		},
+		http.StatusOK,
+		&todo.GetAttachmentVersionPresignedURLPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) RestoreAttachmentVersion(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.RestoreAttachmentVersionPayload) (*todo.TodoAttachment, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.RestoreAttachmentVersion(c, userID, payload)
+		},
+		http.StatusOK,
+		&todo.RestoreAttachmentVersionPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) CreateAttachmentLink(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.CreateAttachmentLinkPayload) (*todo.TodoAttachment, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.CreateAttachmentLink(c, userID, payload)
+		},
+		http.StatusCreated,
+		&todo.CreateAttachmentLinkPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) RequestAttachmentUpload(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.CreateAttachmentUploadPayload) (*todo.AttachmentUpload, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.RequestAttachmentUpload(c, userID, payload)
+		},
+		http.StatusCreated,
+		&todo.CreateAttachmentUploadPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) ConfirmAttachmentUpload(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.ConfirmAttachmentUploadPayload) (*todo.TodoAttachment, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.ConfirmAttachmentUpload(c, userID, payload)
+		},
+		http.StatusCreated,
+		&todo.ConfirmAttachmentUploadPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetTodoAttachments(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetTodoAttachmentsPayload) ([]todo.TodoAttachment, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetTodoAttachments(c, userID, payload.TodoID)
+		},
+		http.StatusOK,
+		&todo.GetTodoAttachmentsPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetTodoAttachment(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetTodoAttachmentPayload) (*todo.TodoAttachment, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetTodoAttachment(c, userID, payload.TodoID, payload.AttachmentID)
+		},
+		http.StatusOK,
+		&todo.GetTodoAttachmentPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetStorageUsage(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetStorageUsagePayload) (*todo.StorageUsage, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetStorageUsage(c, userID)
+		},
+		http.StatusOK,
+		&todo.GetStorageUsagePayload{},
 	)(c)
 }
 
@@ -138,20 +337,66 @@ func (h *TodoHandler) DeleteTodoAttachment(c echo.Context) error {
 func (h *TodoHandler) GetAttachmentPresignedURL(c echo.Context) error {
 	return Handle(
 		h.Handler,
-		func(c echo.Context, payload *todo.GetAttachmentPresignedURLPayload) (*struct {
-			URL string `json:"url"`
-		}, error,
-		) {
+		func(c echo.Context, payload *todo.GetAttachmentPresignedURLPayload) (*todo.AttachmentDownloadURL, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetAttachmentPresignedURL(c, userID, payload)
+		},
+		http.StatusOK,
+		&todo.GetAttachmentPresignedURLPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetAttachmentContent(c echo.Context) error {
+	return HandleFileStream(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetAttachmentContentPayload) (*FileStream, error) {
 			userID := middleware.GetUserID(c)
-			url, err := h.todoService.GetAttachmentPresignedURL(c, userID, payload.TodoID, payload.AttachmentID)
+			content, err := h.todoService.OpenAttachmentContent(c, userID, payload)
 			if err != nil {
 				return nil, err
 			}
-			return &struct {
-				URL string `json:"url"`
-			}{URL: url}, nil
+			return &FileStream{
+				Name:        content.Attachment.Name,
+				ContentType: content.Attachment.ContentType(),
+				Disposition: content.Disposition,
+				ModifiedAt:  content.ModifiedAt,
+				Content:     content.Body,
+			}, nil
+		},
+		&todo.GetAttachmentContentPayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetAttachmentArchive(c echo.Context) error {
+	return HandleFileWriter(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetAttachmentArchivePayload) (*FileWriter, error) {
+			userID := middleware.GetUserID(c)
+			download, err := h.todoService.GetAttachmentArchive(c, userID, payload)
+			if err != nil {
+				return nil, err
+			}
+			if download.Archive != nil {
+				return &FileWriter{Accepted: download.Archive}, nil
+			}
+			return &FileWriter{
+				Name:        download.Name,
+				ContentType: "application/zip",
+				Write:       download.Write,
+			}, nil
+		},
+		&todo.GetAttachmentArchivePayload{},
+	)(c)
+}
+
+func (h *TodoHandler) GetAttachmentArchiveStatus(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetAttachmentArchiveStatusPayload) (*todo.AttachmentArchive, error) {
+			userID := middleware.GetUserID(c)
+			return h.todoService.GetAttachmentArchiveStatus(c, userID, payload)
 		},
 		http.StatusOK,
-		&todo.GetAttachmentPresignedURLPayload{},
+		&todo.GetAttachmentArchiveStatusPayload{},
 	)(c)
 }
@@ -3,14 +3,18 @@ package aws
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type S3Client struct {
@@ -22,38 +26,173 @@ func NewS3Client(server *server.Server, cfg aws.Config) *S3Client {
 	return &S3Client{server: server, client: s3.NewFromConfig(cfg)}
 }
 
-func (s *S3Client) UploadFile(ctx context.Context, bucket, fileName string, file io.Reader) (string, error) {
-	fileKey := fmt.Sprintf("%s_%d", fileName, time.Now().Unix())
+// ErrFileTooLarge is returned by UploadFile when the stream is longer than the allowed size
+var ErrFileTooLarge = errors.New("file exceeds the maximum upload size")
 
-	var buffer bytes.Buffer
-	_, err := io.Copy(&buffer, file)
+// multipartPartSize is how much of a stream is held in memory at a time.
+// S3 requires every part but the last to be at least 5 MiB.
+const multipartPartSize = 8 << 20
+
+// UploadedObject describes an object written by UploadFile
+type UploadedObject struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// UploadFile streams the file to S3 without buffering it whole. Files larger than one part go
+// through a multipart upload which is aborted if reading fails, the context is cancelled or
+// the stream exceeds maxSize. An empty content type is sniffed from the first bytes.
+func (s *S3Client) UploadFile(ctx context.Context, bucket, fileKey, contentType string, file io.Reader, maxSize int64) (*UploadedObject, error) {
+	// read one byte past the limit so an oversized file is rejected rather than truncated
+	body := io.LimitReader(file, maxSize+1)
+	buffer := make([]byte, multipartPartSize)
+
+	n, err := readPart(body, buffer)
+	if err != nil {
+		return nil, err
+	}
+	if int64(n) > maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	if contentType == "" {
+		contentType = http.DetectContentType(buffer[:n])
+	}
+
+	object := &UploadedObject{
+		Key:         fileKey,
+		ContentType: contentType,
+	}
+
+	// a file that fits in one part is cheaper to send as a plain put
+	if n < multipartPartSize {
+		_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(fileKey),
+			Body:          bytes.NewReader(buffer[:n]),
+			ContentLength: aws.Int64(int64(n)),
+			ContentType:   aws.String(object.ContentType),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload file to s3: %w", err)
+		}
+
+		object.Size = int64(n)
+		return object, nil
+	}
+
+	size, err := s.uploadMultipart(ctx, bucket, object, body, buffer, maxSize)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
 
-	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+	object.Size = size
+	return object, nil
+}
+
+// uploadMultipart sends the already read first part in buffer followed by the rest of body
+func (s *S3Client) uploadMultipart(ctx context.Context, bucket string, object *UploadedObject, body io.Reader, buffer []byte, maxSize int64) (int64, error) {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
 		Bucket:      aws.String(bucket),
-		Key:         aws.String(fileKey),
-		Body:        bytes.NewReader(buffer.Bytes()),
-		ContentType: aws.String(http.DetectContentType(buffer.Bytes())),
+		Key:         aws.String(object.Key),
+		ContentType: aws.String(object.ContentType),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	abort := func(cause error) (int64, error) {
+		// the request context is usually what failed, so the abort gets its own
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+
+		_, err := s.client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(object.Key),
+			UploadId: created.UploadId,
+		})
+		if err != nil {
+			s.server.Logger.Error().Err(err).Str("s3_key", object.Key).Msg("failed to abort multipart upload")
+		}
+
+		return 0, cause
+	}
+
+	parts := []types.CompletedPart{}
+	part := buffer
+	size := int64(0)
+	for partNumber := int32(1); len(part) > 0; partNumber++ {
+		if err := ctx.Err(); err != nil {
+			return abort(fmt.Errorf("upload cancelled: %w", err))
+		}
+
+		uploaded, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(object.Key),
+			UploadId:      created.UploadId,
+			PartNumber:    aws.Int32(partNumber),
+			Body:          bytes.NewReader(part),
+			ContentLength: aws.Int64(int64(len(part))),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("failed to upload part %d: %w", partNumber, err))
+		}
+
+		parts = append(parts, types.CompletedPart{
+			ETag:       uploaded.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+		size += int64(len(part))
+
+		n, err := readPart(body, buffer)
+		if err != nil {
+			return abort(err)
+		}
+		if size+int64(n) > maxSize {
+			return abort(ErrFileTooLarge)
+		}
+		part = buffer[:n]
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(object.Key),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file to s3: %w", err)
+		return abort(fmt.Errorf("failed to complete multipart upload: %w", err))
 	}
 
-	return fileKey, nil
+	return size, nil
+}
+
+// readPart fills buffer from the reader, a short read only happens at the end of the stream
+func readPart(body io.Reader, buffer []byte) (int, error) {
+	n, err := io.ReadFull(body, buffer)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return n, nil
 }
 
 // Pre signed URL workflow for downloading
-func (s *S3Client) CreatePresignedUrl(ctx context.Context, bucket string, objectKey string) (string, error) {
+// An empty contentDisposition leaves the header S3 returns unchanged.
+func (s *S3Client) CreatePresignedUrl(ctx context.Context, bucket string, objectKey string, expiration time.Duration, contentDisposition string) (string, error) {
 	presignClient := s3.NewPresignClient(s.client)
 
-	expiration := time.Minute * 60
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectKey),
+	}
+	if contentDisposition != "" {
+		input.ResponseContentDisposition = aws.String(contentDisposition)
+	}
+
 	presignedUrl, err := presignClient.PresignGetObject(ctx,
-		&s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(objectKey),
-		},
+		input,
 		s3.WithPresignExpires(expiration))
 	if err != nil {
 		return "", err
@@ -73,3 +212,203 @@ func (s *S3Client) DeleteObject(ctx context.Context, bucket string, key string)
 
 	return nil
 }
+
+// ErrObjectNotFound is returned by HeadObject and GetObject when the key does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// PresignedUpload describes how a client sends a file straight to the bucket.
+// PUT uploads replay Headers on the request, POST uploads submit Fields as form data before the file.
+type PresignedUpload struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Fields  map[string]string
+}
+
+// ObjectInfo is the metadata S3 reports for a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Pre signed PUT workflow for uploading, the signature pins the exact size and content type
+func (s *S3Client) CreatePresignedPutUpload(ctx context.Context, bucket, key, contentType string, size int64, expiration time.Duration) (*PresignedUpload, error) {
+	presignClient := s3.NewPresignClient(s.client)
+
+	request, err := presignClient.PresignPutObject(ctx,
+		&s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			ContentType:   aws.String(contentType),
+			ContentLength: aws.Int64(size),
+		},
+		s3.WithPresignExpires(expiration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload for %s: %w", key, err)
+	}
+
+	headers := map[string]string{}
+	for name, values := range request.SignedHeader {
+		// the browser sets these itself and refuses to have them overridden
+		if strings.EqualFold(name, "host") || strings.EqualFold(name, "content-length") || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+
+	return &PresignedUpload{
+		Method:  request.Method,
+		URL:     request.URL,
+		Headers: headers,
+	}, nil
+}
+
+// Pre signed POST workflow for uploading from an HTML form, the policy caps the size and pins the content type
+func (s *S3Client) CreatePresignedPostUpload(ctx context.Context, bucket, key, contentType string, maxSize int64, expiration time.Duration) (*PresignedUpload, error) {
+	presignClient := s3.NewPresignClient(s.client)
+
+	request, err := presignClient.PresignPostObject(ctx,
+		&s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		},
+		func(options *s3.PresignPostOptions) {
+			options.Expires = expiration
+			options.Conditions = []interface{}{
+				[]interface{}{"content-length-range", 1, maxSize},
+				map[string]string{"Content-Type": contentType},
+			}
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign post upload for %s: %w", key, err)
+	}
+
+	fields := request.Values
+	fields["Content-Type"] = contentType
+
+	return &PresignedUpload{
+		Method: http.MethodPost,
+		URL:    request.URL,
+		Fields: fields,
+	}, nil
+}
+
+func (s *S3Client) HeadObject(ctx context.Context, bucket string, key string) (*ObjectInfo, error) {
+	output, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		var responseErr interface{ HTTPStatusCode() int }
+		if errors.As(err, &notFound) || (errors.As(err, &responseErr) && responseErr.HTTPStatusCode() == http.StatusNotFound) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to head object %s: %w", key, err)
+	}
+
+	info := &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+	}
+
+	return info, nil
+}
+
+// CopyObject duplicates an object inside the bucket without downloading it
+func (s *S3Client) CopyObject(ctx context.Context, bucket, sourceKey, destinationKey string) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(destinationKey),
+		CopySource: aws.String(bucket + "/" + url.PathEscape(sourceKey)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return ErrObjectNotFound
+		}
+		return fmt.Errorf("failed to copy object %s to %s: %w", sourceKey, destinationKey, err)
+	}
+
+	return nil
+}
+
+// GetObject opens the object for reading, the caller must close it
+func (s *S3Client) GetObject(ctx context.Context, bucket string, key string) (io.ReadCloser, *ObjectInfo, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+
+	info := &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+	}
+
+	return output.Body, info, nil
+}
+
+// GetObjectRange opens length bytes of the object from offset, a negative length reads to the end
+func (s *S3Client) GetObjectRange(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(byteRange),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to get range %s of object %s: %w", byteRange, key, err)
+	}
+
+	return output.Body, nil
+}
+
+// ListObjects calls fn for every object under the prefix, a page at a time. Listing does not
+// report content types. An error from fn stops the listing and is returned.
+func (s *S3Client) ListObjects(ctx context.Context, bucket, prefix string, fn func(info ObjectInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects under %s: %w", prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			err := fn(ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
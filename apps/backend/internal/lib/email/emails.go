@@ -1,5 +1,7 @@
 package email
 
+import "fmt"
+
 func (c *Client) SendWelcomeEmail(to, firstName string) error {
 	data := map[string]string{
 		"UserFirstName": firstName,
@@ -12,3 +14,58 @@ func (c *Client) SendWelcomeEmail(to, firstName string) error {
 		data,
 	)
 }
+
+// SendTodoAssignedEmail tells a user a todo was handed to them; dueDate may be empty
+func (c *Client) SendTodoAssignedEmail(to, todoTitle, todoID, assignedByName, dueDate string) error {
+	if dueDate == "" {
+		dueDate = "No due date"
+	}
+
+	data := map[string]string{
+		"TodoTitle":      todoTitle,
+		"TodoID":         todoID,
+		"AssignedByName": assignedByName,
+		"DueDate":        dueDate,
+	}
+
+	return c.SendEmail(
+		to,
+		fmt.Sprintf("%s assigned you \"%s\"", assignedByName, todoTitle),
+		TemplateTodoAssigned,
+		data,
+	)
+}
+
+// SendCommentMentionEmail tells a user they were @mentioned in a comment
+func (c *Client) SendCommentMentionEmail(to, todoTitle, todoID, authorName, commentExcerpt string) error {
+	data := map[string]string{
+		"TodoTitle":      todoTitle,
+		"TodoID":         todoID,
+		"AuthorName":     authorName,
+		"CommentExcerpt": commentExcerpt,
+	}
+
+	return c.SendEmail(
+		to,
+		fmt.Sprintf("%s mentioned you on \"%s\"", authorName, todoTitle),
+		TemplateCommentMention,
+		data,
+	)
+}
+
+// SendAttachmentQuarantinedEmail tells an uploader the scanner flagged their file
+func (c *Client) SendAttachmentQuarantinedEmail(to, todoTitle, todoID, fileName, signature string) error {
+	data := map[string]string{
+		"TodoTitle": todoTitle,
+		"TodoID":    todoID,
+		"FileName":  fileName,
+		"Signature": signature,
+	}
+
+	return c.SendEmail(
+		to,
+		fmt.Sprintf("\"%s\" was quarantined", fileName),
+		TemplateAttachmentQuarantined,
+		data,
+	)
+}
@@ -4,4 +4,22 @@ var PreviewData = map[string]map[string]string{
 	"welcome": {
 		"UserFirstName": "John",
 	},
+	"todo-assigned": {
+		"TodoTitle":      "Complete quarterly report",
+		"TodoID":         "123e4567-e89b-12d3-a456-426614174000",
+		"AssignedByName": "Jane Cooper",
+		"DueDate":        "Monday, January 15, 2025 at 5:00 PM",
+	},
+	"comment-mention": {
+		"TodoTitle":      "Complete quarterly report",
+		"TodoID":         "123e4567-e89b-12d3-a456-426614174000",
+		"AuthorName":     "Jane Cooper",
+		"CommentExcerpt": "@john can you double check the revenue numbers before Friday?",
+	},
+	"attachment-quarantined": {
+		"TodoTitle": "Complete quarterly report",
+		"TodoID":    "123e4567-e89b-12d3-a456-426614174000",
+		"FileName":  "invoice.pdf.exe",
+		"Signature": "Win.Trojan.Agent-1234",
+	},
 }
@@ -3,5 +3,9 @@ package email
 type Template string
 
 const (
-	TemplateWelcome Template = "welcome"
+	TemplateWelcome        Template = "welcome"
+	TemplateTodoAssigned   Template = "todo-assigned"
+	TemplateCommentMention Template = "comment-mention"
+
+	TemplateAttachmentQuarantined Template = "attachment-quarantined"
 )
@@ -0,0 +1,78 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// textTypes are read as they are
+var textTypes = map[string]bool{
+	"text/plain":                true,
+	"text/markdown":             true,
+	"text/x-markdown":           true,
+	"text/csv":                  true,
+	"text/tab-separated-values": true,
+}
+
+// officeTypes are zip packages of XML parts. Uploads are sniffed, so office documents may also
+// arrive as plain zip files and are recognised by their parts.
+var officeTypes = map[string]bool{
+	"application/zip": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+}
+
+// BuiltinExtractor reads plain text, markdown, PDF and office documents in process. It keeps the
+// document in memory, so the caller bounds its size.
+type BuiltinExtractor struct {
+	maxText int
+}
+
+func NewBuiltinExtractor(maxText int) *BuiltinExtractor {
+	return &BuiltinExtractor{maxText: maxText}
+}
+
+func (e *BuiltinExtractor) Name() string {
+	return config.ExtractorDriverBuiltin
+}
+
+func (e *BuiltinExtractor) Supports(mimeType string) bool {
+	mediaType := mediaType(mimeType)
+	return textTypes[mediaType] || officeTypes[mediaType] || mediaType == "application/pdf"
+}
+
+func (e *BuiltinExtractor) Extract(ctx context.Context, file io.Reader, mimeType string) (string, error) {
+	mediaType := mediaType(mimeType)
+	if !e.Supports(mediaType) {
+		return "", ErrUnsupported
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+
+	out := &textWriter{limit: e.maxText}
+	switch {
+	case textTypes[mediaType]:
+		err = out.write(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	case mediaType == "application/pdf":
+		err = pdfText(ctx, data, out)
+	default:
+		err = officeText(ctx, data, out)
+	}
+	if err != nil && !errors.Is(err, errTextFull) {
+		return "", err
+	}
+
+	return out.String(), nil
+}
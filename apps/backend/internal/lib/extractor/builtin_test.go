@@ -0,0 +1,84 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinExtractorText(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxText  int
+		mimeType string
+		content  string
+		want     string
+		wantErr  error
+	}{
+		{name: "plain text", maxText: 100, mimeType: "text/plain", content: "hello", want: "hello"},
+		{name: "parameters are ignored", maxText: 100, mimeType: "Text/Plain; charset=utf-8", content: "hello", want: "hello"},
+		{name: "byte order mark is dropped", maxText: 100, mimeType: "text/plain", content: "\xef\xbb\xbfhello", want: "hello"},
+		{name: "cut to the limit", maxText: 5, mimeType: "text/plain", content: "hello world", want: "hello"},
+		{name: "unsupported type", maxText: 100, mimeType: "image/png", content: "\x89PNG", wantErr: ErrUnsupported},
+		{
+			name:     "word document",
+			maxText:  100,
+			mimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			content:  officePackage(t, "word/document.xml", wordDocument),
+			want:     "First paragraph\nSecond paragraph\n\n",
+		},
+		{
+			name:     "office text cut to the limit",
+			maxText:  10,
+			mimeType: "application/zip",
+			content:  officePackage(t, "word/document.xml", wordDocument),
+			want:     "First para",
+		},
+		{
+			name:     "zip without office parts",
+			maxText:  100,
+			mimeType: "application/zip",
+			content:  officePackage(t, "notes.txt", "hello"),
+			wantErr:  ErrUnsupported,
+		},
+		{name: "corrupt package", maxText: 100, mimeType: "application/zip", content: "PK\x03\x04", wantErr: ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewBuiltinExtractor(tt.maxText)
+			got, err := e.Extract(context.Background(), strings.NewReader(tt.content), tt.mimeType)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+const wordDocument = `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+	`<w:p><w:r><w:t>First paragraph</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Second paragraph</w:t></w:r></w:p>` +
+	`</w:body></w:document>`
+
+// officePackage zips a single part, as found in office documents
+func officePackage(t *testing.T, name, content string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	part, err := archive.Create(name)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+
+	return buf.String()
+}
@@ -0,0 +1,123 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// ErrUnsupported is returned for documents an extractor cannot read
+var ErrUnsupported = errors.New("unsupported document type")
+
+// Extractor pulls the plain text out of uploaded documents so their contents can be searched
+type Extractor interface {
+	// Supports reports whether documents of the media type can be read at all
+	Supports(mimeType string) bool
+	// Extract reads the document and returns its text. It gives up once ctx is done, an
+	// error other than ErrUnsupported may be temporary and worth retrying.
+	Extract(ctx context.Context, file io.Reader, mimeType string) (string, error)
+	Name() string
+}
+
+// New returns the extractor selected by the config
+func New(cfg *config.ExtractorConfig) (Extractor, error) {
+	switch cfg.Driver {
+	case config.ExtractorDriverNoop:
+		return NoopExtractor{}, nil
+	case config.ExtractorDriverBuiltin:
+		return NewBuiltinExtractor(cfg.MaxTextSize), nil
+	case config.ExtractorDriverTika:
+		return NewTikaExtractor(cfg.TikaURL, cfg.MaxTextSize), nil
+	default:
+		return nil, fmt.Errorf("unknown extractor driver %q", cfg.Driver)
+	}
+}
+
+// NoopExtractor reads nothing, for environments that do not search attachment contents
+type NoopExtractor struct{}
+
+func (NoopExtractor) Supports(mimeType string) bool {
+	return false
+}
+
+func (NoopExtractor) Extract(ctx context.Context, file io.Reader, mimeType string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (NoopExtractor) Name() string {
+	return config.ExtractorDriverNoop
+}
+
+// mediaType strips parameters such as charset and lowercases the type
+func mediaType(mimeType string) string {
+	if parsed, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return parsed
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// Clean makes extracted text fit for storage and search: invalid UTF-8 and control characters
+// are dropped, runs of blank space are collapsed and the result is cut to at most limit bytes.
+func Clean(text string, limit int) string {
+	var b strings.Builder
+	space, newline := false, false
+	for _, r := range strings.ToValidUTF8(text, "") {
+		switch {
+		case r == '\n' || r == '\r' || r == '\f' || r == '\v':
+			newline = b.Len() > 0
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+		case unicode.IsControl(r) || r == utf8.RuneError:
+			continue
+		default:
+			separator := ""
+			if newline {
+				separator = "\n"
+			} else if space {
+				separator = " "
+			}
+			if b.Len()+len(separator)+utf8.RuneLen(r) > limit {
+				return b.String()
+			}
+			b.WriteString(separator)
+			b.WriteRune(r)
+			space, newline = false, false
+		}
+	}
+
+	return b.String()
+}
+
+// errTextFull stops an extraction once it has collected as much text as is kept
+var errTextFull = errors.New("text limit reached")
+
+// textWriter collects extracted text until the limit is reached, cutting the last
+// piece on a rune boundary
+type textWriter struct {
+	b     strings.Builder
+	limit int
+}
+
+func (w *textWriter) write(s string) error {
+	if w.b.Len()+len(s) > w.limit {
+		cut := w.limit - w.b.Len()
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.b.WriteString(s[:cut])
+		return errTextFull
+	}
+	w.b.WriteString(s)
+	return nil
+}
+
+func (w *textWriter) String() string {
+	return w.b.String()
+}
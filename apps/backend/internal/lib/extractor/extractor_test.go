@@ -0,0 +1,70 @@
+package extractor
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{name: "plain", text: "hello world", limit: 100, want: "hello world"},
+		{name: "leading space", text: " \t\n lead", limit: 100, want: "lead"},
+		{name: "trailing space", text: "trail \n\t ", limit: 100, want: "trail"},
+		{name: "collapsed spaces", text: "a  \t b", limit: 100, want: "a b"},
+		{name: "collapsed lines", text: "a\r\n\r\n\fb", limit: 100, want: "a\nb"},
+		{name: "line wins over space", text: "a \n b", limit: 100, want: "a\nb"},
+		{name: "control characters", text: "a\x00b\x07c", limit: 100, want: "abc"},
+		{name: "invalid utf-8", text: "bad\xff\xfebyte", limit: 100, want: "badbyte"},
+		{name: "cut at the limit", text: "hello world", limit: 8, want: "hello wo"},
+		{name: "separator counts toward the limit", text: "hello world", limit: 6, want: "hello"},
+		{name: "cut before a multibyte rune", text: "héllo", limit: 2, want: "h"},
+		{name: "zero limit", text: "hello", limit: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Clean(tt.text, tt.limit)
+			assert.Equal(t, tt.want, got)
+			assert.LessOrEqual(t, len(got), tt.limit)
+		})
+	}
+}
+
+func TestTextWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		writes  []string
+		want    string
+		wantErr error
+	}{
+		{name: "under the limit", limit: 10, writes: []string{"abc", "def"}, want: "abcdef"},
+		{name: "exactly the limit", limit: 6, writes: []string{"abc", "def"}, want: "abcdef"},
+		{name: "over the limit", limit: 4, writes: []string{"abc", "def"}, want: "abcd", wantErr: errTextFull},
+		{name: "already full", limit: 2, writes: []string{"ab", "c"}, want: "ab", wantErr: errTextFull},
+		{name: "whole multibyte rune fits", limit: 4, writes: []string{"ab", "éé"}, want: "abé", wantErr: errTextFull},
+		{name: "partial multibyte rune is dropped", limit: 3, writes: []string{"ab", "é"}, want: "ab", wantErr: errTextFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &textWriter{limit: tt.limit}
+			var err error
+			for _, s := range tt.writes {
+				if err = w.write(s); err != nil {
+					break
+				}
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, w.String())
+			assert.True(t, utf8.ValidString(w.String()))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// maxPartBytes bounds how much of one XML part is inflated, packages may be zip bombs
+const maxPartBytes = 64 << 20
+
+// officePart is an XML part of an office package that holds text
+type officePart struct {
+	match func(name string) bool
+	// text lists the elements whose content is text, the content of every element counts when it is nil
+	text map[string]bool
+	// breaks separates the text of elements, by what is written when one of them closes
+	breaks map[string]string
+}
+
+var officeParts = []officePart{
+	// Word documents
+	{
+		match:  func(name string) bool { return name == "word/document.xml" },
+		text:   map[string]bool{"t": true},
+		breaks: map[string]string{"p": "\n", "br": "\n", "tab": " "},
+	},
+	// PowerPoint slides
+	{
+		match: func(name string) bool {
+			return strings.HasPrefix(name, "ppt/slides/slide") && strings.HasSuffix(name, ".xml")
+		},
+		text:   map[string]bool{"t": true},
+		breaks: map[string]string{"p": "\n", "br": "\n"},
+	},
+	// Excel shared strings, which hold the text of every cell
+	{
+		match:  func(name string) bool { return name == "xl/sharedStrings.xml" },
+		text:   map[string]bool{"t": true},
+		breaks: map[string]string{"si": "\n"},
+	},
+	// OpenDocument text, spreadsheets and presentations
+	{
+		match:  func(name string) bool { return name == "content.xml" },
+		breaks: map[string]string{"p": "\n", "h": "\n", "s": " ", "tab": " ", "line-break": "\n"},
+	},
+}
+
+// officeText collects the text of the parts of an office package in the order they are stored
+func officeText(ctx context.Context, data []byte, out *textWriter) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ErrUnsupported
+	}
+
+	found := false
+	for _, file := range archive.File {
+		for _, part := range officeParts {
+			if !part.match(file.Name) {
+				continue
+			}
+			found = true
+
+			if err := xmlPartText(ctx, file, part, out); err != nil {
+				return err
+			}
+			if err := out.write("\n"); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return ErrUnsupported
+	}
+
+	return nil
+}
+
+// xmlPartText writes the character data of one part, a part that stops parsing halfway keeps
+// whatever text came before
+func xmlPartText(ctx context.Context, file *zip.File, part officePart, out *textWriter) error {
+	body, err := file.Open()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	decoder := xml.NewDecoder(io.LimitReader(body, maxPartBytes))
+	depth := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// the part ends at io.EOF, malformed or truncated XML keeps what was read so far
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if part.text[token.Name.Local] {
+				depth++
+			}
+		case xml.EndElement:
+			if part.text[token.Name.Local] {
+				depth--
+			}
+			if separator, ok := part.breaks[token.Name.Local]; ok {
+				if err := out.write(separator); err != nil {
+					return err
+				}
+			}
+		case xml.CharData:
+			if part.text != nil && depth == 0 {
+				continue
+			}
+			if err := out.write(string(token)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package extractor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"io"
+	"strconv"
+	"unicode/utf16"
+)
+
+// maxInflatedBytes bounds how much of a PDF's compressed streams is inflated
+const maxInflatedBytes = 256 << 20
+
+// pdfStreamDictLookback is how far before a stream its dictionary is looked for
+const pdfStreamDictLookback = 1024
+
+var (
+	pdfStreamStart = []byte("stream")
+	pdfStreamEnd   = []byte("endstream")
+)
+
+// pdfText writes the text shown by the content streams of a PDF. Only unfiltered and Flate
+// compressed streams are read, and text drawn with embedded CID fonts cannot be mapped back.
+func pdfText(ctx context.Context, data []byte, out *textWriter) error {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return ErrUnsupported
+	}
+
+	inflated := 0
+	for offset := 0; offset < len(data); {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		start := bytes.Index(data[offset:], pdfStreamStart)
+		if start < 0 {
+			break
+		}
+		start += offset
+		offset = start + len(pdfStreamStart)
+
+		// "endstream" contains "stream" too, only a keyword that follows a dictionary starts one
+		dictStart := max(0, start-pdfStreamDictLookback)
+		dict := data[dictStart:start]
+		if !bytes.HasSuffix(bytes.TrimRight(dict, " \r\n\t"), []byte(">>")) {
+			continue
+		}
+		if obj := bytes.LastIndex(dict, []byte("obj")); obj >= 0 {
+			dict = dict[obj:]
+		}
+
+		bodyStart := offset
+		if bodyStart < len(data) && data[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart < len(data) && data[bodyStart] == '\n' {
+			bodyStart++
+		}
+		end := bytes.Index(data[bodyStart:], pdfStreamEnd)
+		if end < 0 {
+			break
+		}
+		body := data[bodyStart : bodyStart+end]
+		offset = bodyStart + end + len(pdfStreamEnd)
+
+		if !isContentStream(dict) {
+			continue
+		}
+
+		if bytes.Contains(dict, []byte("/Filter")) {
+			if inflated >= maxInflatedBytes || !isFlateOnly(dict) {
+				continue
+			}
+			reader, err := zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				continue
+			}
+			content, _ := io.ReadAll(io.LimitReader(reader, int64(maxInflatedBytes-inflated)))
+			reader.Close()
+			inflated += len(content)
+			body = content
+		}
+
+		if err := contentStreamText(ctx, body, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isContentStream tells page content apart from fonts, images, metadata and object streams,
+// which all carry a type or extra lengths in their dictionary
+func isContentStream(dict []byte) bool {
+	for _, key := range []string{"/Type", "/Subtype", "/Length1", "/Length2", "/Length3", "/DecodeParms"} {
+		if bytes.Contains(dict, []byte(key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFlateOnly reports whether FlateDecode is the only filter of a stream
+func isFlateOnly(dict []byte) bool {
+	return bytes.Count(dict, []byte("Decode")) == 1 && bytes.Contains(dict, []byte("/FlateDecode"))
+}
+
+// contentStreamText runs through the operators of a content stream and writes the strings of
+// the text showing ones, starting lines where the text moves down
+func contentStreamText(ctx context.Context, content []byte, out *textWriter) error {
+	var (
+		strs    []string
+		nums    []float64
+		inArray bool
+	)
+
+	for i, tokens := 0, 0; i < len(content); tokens++ {
+		if tokens%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := pdfLiteralString(content[i:])
+			strs = append(strs, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '<':
+			s, n := pdfHexString(content[i:])
+			strs = append(strs, s)
+			i += n
+		case c == '>':
+			i++
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			word := string(content[start:i])
+
+			if num, err := strconv.ParseFloat(word, 64); err == nil {
+				// a large negative adjustment inside a TJ array is the gap between two words
+				if inArray && num < -200 {
+					strs = append(strs, " ")
+				}
+				nums = append(nums, num)
+				continue
+			}
+
+			separator := ""
+			show := false
+			switch word {
+			case "Tj", "TJ":
+				show = true
+			case "'", "\"":
+				separator, show = "\n", true
+			case "T*", "ET":
+				separator = "\n"
+			case "Td", "TD":
+				separator = " "
+				if len(nums) >= 2 && nums[len(nums)-1] != 0 {
+					separator = "\n"
+				}
+			case "Tm":
+				separator = " "
+			case "BI":
+				// inline image data is binary, skip to its end
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+
+			if separator != "" {
+				if err := out.write(separator); err != nil {
+					return err
+				}
+			}
+			if show {
+				for _, s := range strs {
+					if err := out.write(s); err != nil {
+						return err
+					}
+				}
+			}
+			strs, nums = strs[:0], nums[:0]
+		}
+	}
+
+	return nil
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// pdfLiteralString decodes a (literal) string with its escapes and balanced parentheses and
+// returns it with the number of bytes it took
+func pdfLiteralString(content []byte) (string, int) {
+	var b []byte
+	depth := 0
+	i := 0
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(b), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(content) {
+				continue
+			}
+			switch e := content[i]; e {
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// a line continuation
+				if e == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					for n := 0; n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; n++ {
+						value = value*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					b = append(b, byte(value))
+				} else {
+					b = append(b, e)
+				}
+			}
+			continue
+		}
+		b = append(b, c)
+	}
+
+	return decodePDFString(b), i
+}
+
+// pdfHexString decodes a <hex> string and returns it with the number of bytes it took
+func pdfHexString(content []byte) (string, int) {
+	var b []byte
+	high, odd := byte(0), false
+	for i := 1; i < len(content); i++ {
+		c := content[i]
+		var value byte
+		switch {
+		case c == '>':
+			if odd {
+				b = append(b, high<<4)
+			}
+			return decodePDFString(b), i + 1
+		case c >= '0' && c <= '9':
+			value = c - '0'
+		case c >= 'a' && c <= 'f':
+			value = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			value = c - 'A' + 10
+		default:
+			continue
+		}
+		if odd {
+			b = append(b, high<<4|value)
+		} else {
+			high = value
+		}
+		odd = !odd
+	}
+
+	return decodePDFString(b), len(content)
+}
+
+// decodePDFString reads UTF-16 strings by their byte order mark and everything else as Latin-1,
+// which PDFDocEncoding matches for the printable range
+func decodePDFString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
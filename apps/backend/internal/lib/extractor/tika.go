@@ -0,0 +1,85 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// tikaTypes are the families of documents sent to Tika, which reads far more formats than the builtin extractor
+var tikaTypes = []string{
+	"text/",
+	"application/pdf",
+	"application/rtf",
+	"application/zip",
+	"application/msword",
+	"application/vnd.ms-",
+	"application/vnd.openxmlformats-officedocument.",
+	"application/vnd.oasis.opendocument.",
+}
+
+// TikaExtractor sends documents to an Apache Tika server and reads back their plain text
+type TikaExtractor struct {
+	url     string
+	maxText int
+	client  *http.Client
+}
+
+func NewTikaExtractor(url string, maxText int) *TikaExtractor {
+	return &TikaExtractor{
+		url:     strings.TrimSuffix(url, "/") + "/tika",
+		maxText: maxText,
+		client:  &http.Client{},
+	}
+}
+
+func (e *TikaExtractor) Name() string {
+	return config.ExtractorDriverTika
+}
+
+func (e *TikaExtractor) Supports(mimeType string) bool {
+	mediaType := mediaType(mimeType)
+	for _, prefix := range tikaTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TikaExtractor) Extract(ctx context.Context, file io.Reader, mimeType string) (string, error) {
+	if !e.Supports(mimeType) {
+		return "", ErrUnsupported
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, e.url, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tika request: %w", err)
+	}
+	req.Header.Set("Content-Type", mimeType)
+	req.Header.Set("Accept", "text/plain; charset=UTF-8")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach tika at %s: %w", e.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnsupportedMediaType || resp.StatusCode == http.StatusUnprocessableEntity:
+		return "", ErrUnsupported
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("tika responded with status %d", resp.StatusCode)
+	}
+
+	text, err := io.ReadAll(io.LimitReader(resp.Body, int64(e.maxText)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read tika response: %w", err)
+	}
+
+	return string(text), nil
+}
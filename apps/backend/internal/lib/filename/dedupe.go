@@ -0,0 +1,23 @@
+package filename
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Dedupe returns name, or name with a counter before its extension like "report (2).pdf" when
+// taken already holds it, and marks the result as taken. Names are compared case insensitively
+// since archives are often unpacked on file systems that do the same.
+func Dedupe(name string, taken map[string]bool) string {
+	extension := path.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+
+	candidate := name
+	for i := 2; taken[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, extension)
+	}
+	taken[strings.ToLower(candidate)] = true
+
+	return candidate
+}
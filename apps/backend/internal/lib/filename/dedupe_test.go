@@ -0,0 +1,52 @@
+package filename
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{
+			name:  "distinct names",
+			names: []string{"a.txt", "b.txt"},
+			want:  []string{"a.txt", "b.txt"},
+		},
+		{
+			name:  "counter before the extension",
+			names: []string{"report.pdf", "report.pdf", "report.pdf"},
+			want:  []string{"report.pdf", "report (2).pdf", "report (3).pdf"},
+		},
+		{
+			name:  "case insensitive",
+			names: []string{"Report.PDF", "report.pdf"},
+			want:  []string{"Report.PDF", "report (2).pdf"},
+		},
+		{
+			name:  "without extension",
+			names: []string{"notes", "notes"},
+			want:  []string{"notes", "notes (2)"},
+		},
+		{
+			name:  "generated name already taken",
+			names: []string{"a (2).txt", "a.txt", "a.txt"},
+			want:  []string{"a (2).txt", "a.txt", "a (3).txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := map[string]bool{}
+			got := []string{}
+			for _, name := range tt.names {
+				got = append(got, Dedupe(name, taken))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
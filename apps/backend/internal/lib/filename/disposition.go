@@ -0,0 +1,93 @@
+package filename
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DispositionAttachment = "attachment"
+	DispositionInline     = "inline"
+)
+
+// ContentDisposition builds a Content-Disposition header for the name following RFC 6266.
+// Non-ASCII names get an ASCII filename fallback and the exact name as an RFC 5987 filename*.
+func ContentDisposition(dispositionType, name string) string {
+	name = Sanitize(name)
+
+	fallback := asciiFallback(name)
+	if fallback == name {
+		return fmt.Sprintf(`%s; filename="%s"`, dispositionType, quote(name))
+	}
+
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, dispositionType, quote(fallback), encodeExtValue(name))
+}
+
+// inlineTypes render in a browser without running anything the file carries.
+// HTML, SVG and XML are left out since they can script the API origin.
+var inlineTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/avif":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// Disposition returns the requested disposition type when it is safe for the media type,
+// anything that cannot be shown inline safely falls back to an attachment
+func Disposition(requested, mimeType string) string {
+	if requested != DispositionInline {
+		return DispositionAttachment
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	if inlineTypes[mediaType] || strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/") {
+		return DispositionInline
+	}
+
+	return DispositionAttachment
+}
+
+// asciiFallback replaces everything outside printable ASCII for clients without filename* support
+func asciiFallback(name string) string {
+	var builder strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e {
+			builder.WriteByte('_')
+			continue
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
+
+func quote(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// encodeExtValue percent-encodes every byte that is not an RFC 5987 attr-char
+func encodeExtValue(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isAttrChar(c) {
+			builder.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&builder, "%%%02X", c)
+	}
+
+	return builder.String()
+}
+
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,78 @@
+package filename
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name            string
+		dispositionType string
+		input           string
+		want            string
+	}{
+		{
+			name:            "ascii name",
+			dispositionType: DispositionAttachment,
+			input:           "report.pdf",
+			want:            `attachment; filename="report.pdf"`,
+		},
+		{
+			name:            "name with spaces",
+			dispositionType: DispositionInline,
+			input:           "my file.txt",
+			want:            `inline; filename="my file.txt"`,
+		},
+		{
+			name:            "name is sanitized",
+			dispositionType: DispositionAttachment,
+			input:           "../secret\".txt",
+			want:            `attachment; filename="secret.txt"`,
+		},
+		{
+			name:            "non-ascii name",
+			dispositionType: DispositionAttachment,
+			input:           "r\u00e9sum\u00e9.pdf",
+			want:            `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`,
+		},
+		{
+			name:            "non-attr-chars are encoded",
+			dispositionType: DispositionInline,
+			input:           "\u00fcber (1);x.txt",
+			want:            `inline; filename="_ber (1);x.txt"; filename*=UTF-8''%C3%BCber%20%281%29%3Bx.txt`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ContentDisposition(tt.dispositionType, tt.input))
+		})
+	}
+}
+
+func TestDisposition(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		mimeType  string
+		want      string
+	}{
+		{name: "inline image", requested: DispositionInline, mimeType: "image/png", want: DispositionInline},
+		{name: "inline with parameters", requested: DispositionInline, mimeType: "Text/Plain; charset=utf-8", want: DispositionInline},
+		{name: "inline video", requested: DispositionInline, mimeType: "video/mp4", want: DispositionInline},
+		{name: "inline audio", requested: DispositionInline, mimeType: "audio/mpeg", want: DispositionInline},
+		{name: "html is never inline", requested: DispositionInline, mimeType: "text/html", want: DispositionAttachment},
+		{name: "svg is never inline", requested: DispositionInline, mimeType: "image/svg+xml", want: DispositionAttachment},
+		{name: "unknown type", requested: DispositionInline, mimeType: "application/octet-stream", want: DispositionAttachment},
+		{name: "attachment requested", requested: DispositionAttachment, mimeType: "image/png", want: DispositionAttachment},
+		{name: "nothing requested", requested: "", mimeType: "image/png", want: DispositionAttachment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Disposition(tt.requested, tt.mimeType))
+		})
+	}
+}
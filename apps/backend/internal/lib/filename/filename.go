@@ -0,0 +1,72 @@
+package filename
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fallback replaces names that sanitize to nothing
+const Fallback = "file"
+
+// maxBytes keeps names within what common filesystems accept
+const maxBytes = 255
+
+// Sanitize turns a client supplied file name into a safe display name. It drops any directory
+// part, normalizes to NFC, removes control and bidirectional formatting characters that can
+// disguise an extension, collapses whitespace and caps the length while keeping the extension.
+func Sanitize(name string) string {
+	name = strings.ToValidUTF8(name, "")
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(name)
+	name = norm.NFC.String(name)
+
+	var builder strings.Builder
+	lastSpace := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			if !lastSpace {
+				builder.WriteRune(' ')
+			}
+			lastSpace = true
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Bidi_Control, r), unicode.Is(unicode.Join_Control, r),
+			r == '\u200b', r == '\ufeff', strings.ContainsRune(`/:*?"<>|`, r):
+			continue
+		}
+		builder.WriteRune(r)
+		lastSpace = false
+	}
+
+	// leading dots hide files and trailing dots and spaces are dropped by Windows
+	name = strings.Trim(builder.String(), " .")
+	if name == "" {
+		return Fallback
+	}
+
+	return truncate(name, maxBytes)
+}
+
+// truncate shortens the name to at most limit bytes on a rune boundary, keeping a short extension
+func truncate(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+
+	extension := path.Ext(name)
+	if len(extension) > 16 {
+		extension = ""
+	}
+
+	base := strings.TrimSuffix(name, extension)
+	cut := limit - len(extension)
+	for cut > 0 && !utf8.RuneStart(base[cut]) {
+		cut--
+	}
+
+	return strings.TrimRight(base[:cut], " .") + extension
+}
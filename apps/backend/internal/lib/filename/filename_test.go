@@ -0,0 +1,54 @@
+package filename
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain", input: "report.pdf", want: "report.pdf"},
+		{name: "unix traversal", input: "../../etc/passwd", want: "passwd"},
+		{name: "windows path", input: `C:\Users\me\doc.txt`, want: "doc.txt"},
+		{name: "right-to-left override", input: "invoice\u202efdp.exe", want: "invoicefdp.exe"},
+		{name: "zero width characters", input: "a\u200bb\ufeff.txt", want: "ab.txt"},
+		{name: "control characters", input: "a\x00b\x1fc.txt", want: "abc.txt"},
+		{name: "reserved characters", input: `a:b*c?"<d>|.txt`, want: "abcd.txt"},
+		{name: "invalid utf-8", input: "bad\xffname.txt", want: "badname.txt"},
+		{name: "decomposed accents", input: "e\u0301.txt", want: "\u00e9.txt"},
+		{name: "collapsed whitespace", input: "  my   file\t.txt ", want: "my file .txt"},
+		{name: "leading and trailing dots", input: ".hidden.", want: "hidden"},
+		{name: "only dots", input: "...", want: Fallback},
+		{name: "empty", input: "", want: Fallback},
+		{name: "only separators", input: "/", want: Fallback},
+		{
+			name:  "long name keeps extension",
+			input: strings.Repeat("a", 300) + ".pdf",
+			want:  strings.Repeat("a", 251) + ".pdf",
+		},
+		{
+			name:  "long name cut on a rune boundary",
+			input: strings.Repeat("\u00e9", 200) + ".txt",
+			want:  strings.Repeat("\u00e9", 125) + ".txt",
+		},
+		{
+			name:  "long extension is not kept",
+			input: strings.Repeat("a", 250) + "." + strings.Repeat("b", 20),
+			want:  strings.Repeat("a", 250) + "." + strings.Repeat("b", 4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sanitize(tt.input)
+			assert.Equal(t, tt.want, got)
+			assert.LessOrEqual(t, len(got), maxBytes)
+		})
+	}
+}
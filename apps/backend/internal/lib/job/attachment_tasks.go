@@ -0,0 +1,174 @@
+package job
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskCleanupPendingUploads   = "attachment:cleanup_pending_uploads"
+	TaskScanAttachment          = "attachment:scan"
+	TaskProcessAttachment       = "attachment:process"
+	TaskDeleteStorageObjects    = "attachment:delete_objects"
+	TaskReconcileStorage        = "attachment:reconcile_storage"
+	TaskBackfillAttachmentBlobs = "attachment:backfill_blobs"
+	TaskBuildAttachmentArchive  = "attachment:build_archive"
+	TaskCleanupArchives         = "attachment:cleanup_archives"
+	TaskExtractAttachmentText   = "attachment:extract_text"
+	TaskBackfillAttachmentText  = "attachment:backfill_text"
+)
+
+func NewCleanupPendingUploadsTask(interval time.Duration) *asynq.Task {
+	// every instance schedules the sweep, uniqueness keeps it to one run per interval
+	return asynq.NewTask(TaskCleanupPendingUploads, nil,
+		asynq.MaxRetry(1),
+		asynq.Queue("low"),
+		asynq.Unique(interval),
+		asynq.Timeout(5*time.Minute))
+}
+
+type ScanAttachmentPayload struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+func NewScanAttachmentTask(attachmentID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(ScanAttachmentPayload{
+		AttachmentID: attachmentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a scanner outage is retried with backoff, the attachment stays pending meanwhile
+	return asynq.NewTask(TaskScanAttachment, payload,
+		asynq.MaxRetry(10),
+		asynq.Queue("default"),
+		asynq.Timeout(10*time.Minute)), nil
+}
+
+type ProcessAttachmentPayload struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+func NewProcessAttachmentTask(attachmentID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(ProcessAttachmentPayload{
+		AttachmentID: attachmentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// thumbnails are a nicety, a few retries cover storage hiccups
+	return asynq.NewTask(TaskProcessAttachment, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(5*time.Minute)), nil
+}
+
+type ExtractAttachmentTextPayload struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+func NewExtractAttachmentTextTask(attachmentID string, timeout time.Duration) (*asynq.Task, error) {
+	payload, err := json.Marshal(ExtractAttachmentTextPayload{
+		AttachmentID: attachmentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the scan and the backfill may both queue an attachment, uniqueness keeps it to one extraction.
+	// The extraction bounds itself, the task timeout only leaves room to read and record it.
+	return asynq.NewTask(TaskExtractAttachmentText, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Unique(time.Hour),
+		asynq.Timeout(timeout+time.Minute)), nil
+}
+
+func NewBackfillAttachmentTextTask() *asynq.Task {
+	// enqueued on every start and on a schedule, uniqueness keeps one backfill running at a time
+	return asynq.NewTask(TaskBackfillAttachmentText, nil,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Unique(time.Hour),
+		asynq.Timeout(time.Hour))
+}
+
+type DeleteStorageObjectsPayload struct {
+	Keys []string `json:"keys"`
+}
+
+func NewDeleteStorageObjectsTask(keys []string) (*asynq.Task, error) {
+	payload, err := json.Marshal(DeleteStorageObjectsPayload{
+		Keys: keys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// deleting is idempotent, so a storage outage is simply retried
+	return asynq.NewTask(TaskDeleteStorageObjects, payload,
+		asynq.MaxRetry(10),
+		asynq.Queue("default"),
+		asynq.Timeout(5*time.Minute)), nil
+}
+
+type ReconcileStoragePayload struct {
+	DryRun bool `json:"dry_run"`
+}
+
+func NewReconcileStorageTask(interval time.Duration, dryRun bool) (*asynq.Task, error) {
+	payload, err := json.Marshal(ReconcileStoragePayload{
+		DryRun: dryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a full listing is expensive, uniqueness keeps it to one run per interval across instances
+	return asynq.NewTask(TaskReconcileStorage, payload,
+		asynq.MaxRetry(1),
+		asynq.Queue("low"),
+		asynq.Unique(interval),
+		asynq.Timeout(time.Hour)), nil
+}
+
+func NewBackfillAttachmentBlobsTask() *asynq.Task {
+	// enqueued on every start and on a schedule, uniqueness keeps one backfill running at a time
+	return asynq.NewTask(TaskBackfillAttachmentBlobs, nil,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Unique(time.Hour),
+		asynq.Timeout(time.Hour))
+}
+
+type BuildAttachmentArchivePayload struct {
+	ArchiveID string `json:"archive_id"`
+}
+
+func NewBuildAttachmentArchiveTask(archiveID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(BuildAttachmentArchivePayload{
+		ArchiveID: archiveID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// large archives take a while, a few retries cover storage hiccups before it is marked failed
+	return asynq.NewTask(TaskBuildAttachmentArchive, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(time.Hour)), nil
+}
+
+func NewCleanupArchivesTask(interval time.Duration) *asynq.Task {
+	// every instance schedules the sweep, uniqueness keeps it to one run per interval
+	return asynq.NewTask(TaskCleanupArchives, nil,
+		asynq.MaxRetry(1),
+		asynq.Queue("low"),
+		asynq.Unique(interval),
+		asynq.Timeout(5*time.Minute))
+}
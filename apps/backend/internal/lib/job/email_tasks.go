@@ -8,7 +8,11 @@ import (
 )
 
 const (
-	TaskWelcome = "email:welcome"
+	TaskWelcome        = "email:welcome"
+	TaskTodoAssigned   = "email:todo_assigned"
+	TaskCommentMention = "email:comment_mention"
+
+	TaskAttachmentQuarantined = "email:attachment_quarantined"
 )
 
 type WelcomeEmailPayload struct {
@@ -30,3 +34,81 @@ func NewWelcomeEmailTask(to, firstName string) (*asynq.Task, error) {
 		asynq.Queue("default"),
 		asynq.Timeout(30*time.Second)), nil
 }
+
+type TodoAssignedEmailPayload struct {
+	AssigneeID   string     `json:"assignee_id"`
+	AssignedByID string     `json:"assigned_by_id"`
+	TodoID       string     `json:"todo_id"`
+	TodoTitle    string     `json:"todo_title"`
+	DueDate      *time.Time `json:"due_date,omitempty"`
+}
+
+func NewTodoAssignedEmailTask(assigneeID, assignedByID, todoID, todoTitle string, dueDate *time.Time) (*asynq.Task, error) {
+	payload, err := json.Marshal(TodoAssignedEmailPayload{
+		AssigneeID:   assigneeID,
+		AssignedByID: assignedByID,
+		TodoID:       todoID,
+		TodoTitle:    todoTitle,
+		DueDate:      dueDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskTodoAssigned, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+type CommentMentionEmailPayload struct {
+	MentionedUserID string `json:"mentioned_user_id"`
+	AuthorID        string `json:"author_id"`
+	TodoID          string `json:"todo_id"`
+	TodoTitle       string `json:"todo_title"`
+	CommentExcerpt  string `json:"comment_excerpt"`
+}
+
+func NewCommentMentionEmailTask(mentionedUserID, authorID, todoID, todoTitle, commentExcerpt string) (*asynq.Task, error) {
+	payload, err := json.Marshal(CommentMentionEmailPayload{
+		MentionedUserID: mentionedUserID,
+		AuthorID:        authorID,
+		TodoID:          todoID,
+		TodoTitle:       todoTitle,
+		CommentExcerpt:  commentExcerpt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskCommentMention, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+type AttachmentQuarantinedEmailPayload struct {
+	UploaderID string `json:"uploader_id"`
+	TodoID     string `json:"todo_id"`
+	TodoTitle  string `json:"todo_title"`
+	FileName   string `json:"file_name"`
+	Signature  string `json:"signature"`
+}
+
+func NewAttachmentQuarantinedEmailTask(uploaderID, todoID, todoTitle, fileName, signature string) (*asynq.Task, error) {
+	payload, err := json.Marshal(AttachmentQuarantinedEmailPayload{
+		UploaderID: uploaderID,
+		TodoID:     todoID,
+		TodoTitle:  todoTitle,
+		FileName:   fileName,
+		Signature:  signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskAttachmentQuarantined, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(30*time.Second)), nil
+}
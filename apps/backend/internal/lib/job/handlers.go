@@ -47,3 +47,138 @@ func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task)
 		Msg("Successfully sent welcome email")
 	return nil
 }
+
+func (j *JobService) handleTodoAssignedEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p TodoAssignedEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal todo assigned email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "todo_assigned").
+		Str("assignee_id", p.AssigneeID).
+		Str("todo_id", p.TodoID).
+		Msg("Processing todo assigned email task")
+
+	assignee, err := lookupRecipient(ctx, p.AssigneeID)
+	if err != nil {
+		return err
+	}
+
+	assignedBy, err := lookupRecipient(ctx, p.AssignedByID)
+	if err != nil {
+		return err
+	}
+
+	dueDate := ""
+	if p.DueDate != nil {
+		dueDate = p.DueDate.Format("Monday, January 2, 2006 at 3:04 PM")
+	}
+
+	err = emailClient.SendTodoAssignedEmail(
+		assignee.Email,
+		p.TodoTitle,
+		p.TodoID,
+		assignedBy.Name,
+		dueDate,
+	)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "todo_assigned").
+			Str("to", assignee.Email).
+			Err(err).
+			Msg("Failed to send todo assigned email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "todo_assigned").
+		Str("to", assignee.Email).
+		Msg("Successfully sent todo assigned email")
+	return nil
+}
+
+func (j *JobService) handleCommentMentionEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p CommentMentionEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal comment mention email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "comment_mention").
+		Str("mentioned_user_id", p.MentionedUserID).
+		Str("todo_id", p.TodoID).
+		Msg("Processing comment mention email task")
+
+	mentioned, err := lookupRecipient(ctx, p.MentionedUserID)
+	if err != nil {
+		return err
+	}
+
+	author, err := lookupRecipient(ctx, p.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	err = emailClient.SendCommentMentionEmail(
+		mentioned.Email,
+		p.TodoTitle,
+		p.TodoID,
+		author.Name,
+		p.CommentExcerpt,
+	)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "comment_mention").
+			Str("to", mentioned.Email).
+			Err(err).
+			Msg("Failed to send comment mention email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "comment_mention").
+		Str("to", mentioned.Email).
+		Msg("Successfully sent comment mention email")
+	return nil
+}
+
+func (j *JobService) handleAttachmentQuarantinedEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p AttachmentQuarantinedEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal attachment quarantined email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_quarantined").
+		Str("uploader_id", p.UploaderID).
+		Str("todo_id", p.TodoID).
+		Msg("Processing attachment quarantined email task")
+
+	uploader, err := lookupRecipient(ctx, p.UploaderID)
+	if err != nil {
+		return err
+	}
+
+	err = emailClient.SendAttachmentQuarantinedEmail(
+		uploader.Email,
+		p.TodoTitle,
+		p.TodoID,
+		p.FileName,
+		p.Signature,
+	)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "attachment_quarantined").
+			Str("to", uploader.Email).
+			Err(err).
+			Msg("Failed to send attachment quarantined email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_quarantined").
+		Str("to", uploader.Email).
+		Msg("Successfully sent attachment quarantined email")
+	return nil
+}
@@ -7,9 +7,11 @@ import (
 )
 
 type JobService struct {
-	Client *asynq.Client
-	server *asynq.Server
-	logger *zerolog.Logger
+	Client    *asynq.Client
+	server    *asynq.Server
+	scheduler *asynq.Scheduler
+	mux       *asynq.ServeMux
+	logger    *zerolog.Logger
 }
 
 func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
@@ -31,28 +33,60 @@ func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
 		},
 	)
 
+	scheduler := asynq.NewScheduler(asynq.RedisClientOpt{Addr: redisAddr}, nil)
+
 	return &JobService{
-		Client: client,
-		server: server,
-		logger: logger,
+		Client:    client,
+		server:    server,
+		scheduler: scheduler,
+		mux:       asynq.NewServeMux(),
+		logger:    logger,
 	}
 }
 
 func (j *JobService) Start() error {
 	// Register task handlers
-	mux := asynq.NewServeMux()
-	mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
+	j.mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
+	j.mux.HandleFunc(TaskTodoAssigned, j.handleTodoAssignedEmailTask)
+	j.mux.HandleFunc(TaskCommentMention, j.handleCommentMentionEmailTask)
+	j.mux.HandleFunc(TaskAttachmentQuarantined, j.handleAttachmentQuarantinedEmailTask)
 
 	j.logger.Info().Msg("Starting background job server")
-	if err := j.server.Start(mux); err != nil {
+	if err := j.server.Start(j.mux); err != nil {
+		return err
+	}
+
+	if err := j.scheduler.Start(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Handle registers a handler for tasks whose work needs the service layer,
+// which this package cannot import. It may be called after Start.
+func (j *JobService) Handle(pattern string, handler asynq.HandlerFunc) {
+	j.mux.Handle(pattern, handler)
+}
+
+// Schedule enqueues the task periodically on the given cron spec, e.g. "@every 15m"
+func (j *JobService) Schedule(cronspec string, task *asynq.Task) error {
+	entryID, err := j.scheduler.Register(cronspec, task)
+	if err != nil {
 		return err
 	}
 
+	j.logger.Info().
+		Str("task", task.Type()).
+		Str("cronspec", cronspec).
+		Str("entry_id", entryID).
+		Msg("Scheduled periodic task")
 	return nil
 }
 
 func (j *JobService) Stop() {
 	j.logger.Info().Msg("Stopping background job server")
+	j.scheduler.Shutdown()
 	j.server.Shutdown()
 	j.Client.Close()
 }
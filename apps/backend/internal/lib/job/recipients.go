@@ -0,0 +1,64 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/user"
+)
+
+// recipient is a Clerk user resolved for a notification email
+type recipient struct {
+	Email string
+	Name  string
+}
+
+// lookupRecipient resolves a user ID into their primary email address and a display name.
+// Lookups happen in the task rather than the request so that Clerk outages are retried.
+func lookupRecipient(ctx context.Context, userID string) (*recipient, error) {
+	clerkUser, err := user.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clerk user %s: %w", userID, err)
+	}
+
+	email := primaryEmail(clerkUser)
+	if email == "" {
+		return nil, fmt.Errorf("clerk user %s has no primary email address", userID)
+	}
+
+	return &recipient{
+		Email: email,
+		Name:  displayName(clerkUser, email),
+	}, nil
+}
+
+func primaryEmail(clerkUser *clerk.User) string {
+	for _, address := range clerkUser.EmailAddresses {
+		if clerkUser.PrimaryEmailAddressID != nil && address.ID == *clerkUser.PrimaryEmailAddressID {
+			return address.EmailAddress
+		}
+	}
+
+	return ""
+}
+
+func displayName(clerkUser *clerk.User, fallback string) string {
+	parts := []string{}
+	if clerkUser.FirstName != nil && *clerkUser.FirstName != "" {
+		parts = append(parts, *clerkUser.FirstName)
+	}
+	if clerkUser.LastName != nil && *clerkUser.LastName != "" {
+		parts = append(parts, *clerkUser.LastName)
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, " ")
+	}
+
+	if clerkUser.Username != nil && *clerkUser.Username != "" {
+		return *clerkUser.Username
+	}
+
+	return fallback
+}
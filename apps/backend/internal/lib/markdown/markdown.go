@@ -0,0 +1,120 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// ErrTaskNotFound is returned when a task list item index is out of range
+var ErrTaskNotFound = errors.New("task list item not found")
+
+// GitHub flavoured CommonMark: task lists, links, autolinks, tables and strikethrough.
+// Raw HTML in the source is not rendered.
+var converter = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+var policy = newPolicy()
+
+// newPolicy allows the user generated content allowlist plus the disabled
+// checkboxes that task lists render to
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowURLSchemes("http", "https", "mailto")
+	return p
+}
+
+// Render turns CommonMark source into HTML that is safe to embed in a page
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
+
+// RenderPtr renders optional source, keeping nil as nil
+func RenderPtr(source *string) (*string, error) {
+	if source == nil {
+		return nil, nil
+	}
+
+	html, err := Render(*source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &html, nil
+}
+
+// CountTasks returns how many task list items the source contains
+func CountTasks(source string) int {
+	return len(findTasks([]byte(source)))
+}
+
+// ToggleTask checks or unchecks the task list item at index (zero based, in
+// document order), rewriting only its "[ ]" marker and leaving the rest of the
+// source byte for byte as it was
+func ToggleTask(source string, index int, checked bool) (string, error) {
+	src := []byte(source)
+
+	tasks := findTasks(src)
+	if index < 0 || index >= len(tasks) {
+		return "", ErrTaskNotFound
+	}
+
+	marker := byte(' ')
+	if checked {
+		marker = 'x'
+	}
+
+	// tasks[index] points at the "[" of the marker
+	out := bytes.Clone(src)
+	out[tasks[index]+1] = marker
+	return string(out), nil
+}
+
+// findTasks returns the offset of the "[" of every task list marker in the source
+func findTasks(src []byte) []int {
+	doc := converter.Parser().Parse(text.NewReader(src))
+
+	offsets := []int{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		if _, ok := n.(*extast.TaskCheckBox); !ok {
+			return ast.WalkContinue, nil
+		}
+
+		// the checkbox opens the first line of its paragraph
+		block := n.Parent()
+		if block == nil || block.Lines().Len() == 0 {
+			return ast.WalkContinue, nil
+		}
+
+		start := block.Lines().At(0).Start
+		if start+2 < len(src) && src[start] == '[' && src[start+2] == ']' {
+			offsets = append(offsets, start)
+		}
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	return offsets
+}
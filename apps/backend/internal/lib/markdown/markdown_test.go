@@ -0,0 +1,125 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "emphasis", source: "**bold**", want: "<p><strong>bold</strong></p>\n"},
+		{name: "strikethrough", source: "~~gone~~", want: "<p><del>gone</del></p>\n"},
+		{name: "script tag", source: "<script>alert(1)</script>hi", want: "\n"},
+		{name: "event handler", source: "<img src=x onerror=alert(1)>", want: "\n"},
+		{name: "javascript link", source: "[x](javascript:alert(1))", want: "<p>x</p>\n"},
+		{
+			name:   "autolink opens in a new tab",
+			source: "https://example.com",
+			want:   "<p><a href=\"https://example.com\" rel=\"nofollow noreferrer noopener\" target=\"_blank\">https://example.com</a></p>\n",
+		},
+		{
+			name:   "mailto link",
+			source: "[m](mailto:a@b.c)",
+			want:   "<p><a href=\"mailto:a@b.c\" rel=\"nofollow noreferrer\">m</a></p>\n",
+		},
+		{
+			name:   "task list renders disabled checkboxes",
+			source: "- [x] done\n- [ ] todo",
+			want: "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n" +
+				"<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenderPtr(t *testing.T) {
+	got, err := RenderPtr(nil)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	source := "*hi*"
+	got, err = RenderPtr(&source)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "<p><em>hi</em></p>\n", *got)
+}
+
+func TestCountTasks(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   int
+	}{
+		{name: "no list", source: "[ ] not a task", want: 0},
+		{name: "bullet list", source: "- [ ] a\n- [x] b\n- c", want: 2},
+		{name: "ordered list", source: "1. [ ] a", want: 1},
+		{name: "nested list", source: "- [ ] a\n  - [ ] b\n", want: 2},
+		{name: "block quote", source: "> - [ ] quoted", want: 1},
+		{name: "code block is skipped", source: "```\n- [ ] no\n```\n- [ ] yes\n", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CountTasks(tt.source))
+		})
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		index   int
+		checked bool
+		want    string
+		wantErr error
+	}{
+		{name: "check", source: "- [ ] a\n- [ ] b\n", index: 1, checked: true, want: "- [ ] a\n- [x] b\n"},
+		{name: "uncheck", source: "- [x] a", index: 0, checked: false, want: "- [ ] a"},
+		{name: "uncheck upper case", source: "- [X] a", index: 0, checked: false, want: "- [ ] a"},
+		{name: "already checked", source: "- [x] a", index: 0, checked: true, want: "- [x] a"},
+		{name: "nested item", source: "- [ ] a\n  - [ ] b\n", index: 1, checked: true, want: "- [ ] a\n  - [x] b\n"},
+		{
+			name:    "code block is left alone",
+			source:  "```\n- [ ] no\n```\n- [ ] yes\n",
+			index:   0,
+			checked: true,
+			want:    "```\n- [ ] no\n```\n- [x] yes\n",
+		},
+		{
+			name:    "line endings are kept",
+			source:  "Steps:\r\n\r\n- [ ] a\r\n- [ ] b\r\n",
+			index:   0,
+			checked: true,
+			want:    "Steps:\r\n\r\n- [x] a\r\n- [ ] b\r\n",
+		},
+		{name: "index past the end", source: "- [ ] a", index: 1, checked: true, wantErr: ErrTaskNotFound},
+		{name: "negative index", source: "- [ ] a", index: -1, checked: true, wantErr: ErrTaskNotFound},
+		{name: "no tasks", source: "just text", index: 0, checked: true, wantErr: ErrTaskNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToggleTask(tt.source, tt.index, tt.checked)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	pngHeader  = []byte("\x89PNG\r\n\x1a\n")
+)
+
+var errMalformedExif = errors.New("malformed exif data")
+
+// tiffTypeSizes is the byte size of one value of each TIFF field type
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// StripLocation removes GPS coordinates from JPEG, PNG and WebP metadata. Everything else,
+// including the orientation, is kept. It reports whether the data had to change.
+func StripLocation(data []byte, mimeType string) ([]byte, bool, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEGLocation(data)
+	case "image/png":
+		return stripPNGLocation(data)
+	case "image/webp":
+		return stripWebPLocation(data)
+	default:
+		return data, false, nil
+	}
+}
+
+// Orientation returns the EXIF orientation of a JPEG, 1 when it has none
+func Orientation(data []byte) int {
+	orientation := 1
+	_ = forEachJPEGSegment(data, func(marker byte, start, end int) bool {
+		segment := data[start:end]
+		if marker != 0xE1 || !bytes.HasPrefix(segment, exifHeader) {
+			return true
+		}
+
+		tiff := segment[len(exifHeader):]
+		order, ifd0, err := readTIFFHeader(tiff)
+		if err != nil {
+			return false
+		}
+		if entry, ok := findIFDEntry(tiff, order, ifd0, tagOrientation); ok {
+			orientation = int(order.Uint16(tiff[entry+8:]))
+		}
+		return false
+	})
+
+	return orientation
+}
+
+// forEachJPEGSegment calls fn with the payload bounds of every segment before the image data
+// until fn returns false
+func forEachJPEGSegment(data []byte, fn func(marker byte, start, end int) bool) error {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return errors.New("not a jpeg")
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return errors.New("malformed jpeg segment")
+		}
+		marker := data[i+1]
+		// start of scan, the metadata segments are all before it
+		if marker == 0xDA {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return errors.New("malformed jpeg segment")
+		}
+		if !fn(marker, i+4, i+2+length) {
+			return nil
+		}
+		i += 2 + length
+	}
+
+	return nil
+}
+
+func stripJPEGLocation(data []byte) ([]byte, bool, error) {
+	out := bytes.Clone(data)
+	changed := false
+	drop := [][2]int{}
+
+	err := forEachJPEGSegment(out, func(marker byte, start, end int) bool {
+		if marker != 0xE1 {
+			return true
+		}
+
+		segment := out[start:end]
+		switch {
+		case bytes.HasPrefix(segment, exifHeader):
+			if scrubGPS(segment[len(exifHeader):]) {
+				changed = true
+			}
+		case bytes.HasPrefix(segment, xmpHeader) && bytes.Contains(segment, []byte("exif:GPS")):
+			// XMP repeats the coordinates as text, the whole packet goes
+			drop = append(drop, [2]int{start - 4, end})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	for i := len(drop) - 1; i >= 0; i-- {
+		out = append(out[:drop[i][0]], out[drop[i][1]:]...)
+		changed = true
+	}
+
+	return out, changed, nil
+}
+
+// scrubGPS empties the GPS IFD of a TIFF structure in place, keeping every offset valid
+func scrubGPS(tiff []byte) bool {
+	order, ifd0, err := readTIFFHeader(tiff)
+	if err != nil {
+		return false
+	}
+
+	entry, ok := findIFDEntry(tiff, order, ifd0, tagGPSInfo)
+	if !ok {
+		return false
+	}
+
+	gpsIFD := order.Uint32(tiff[entry+8:])
+	if uint64(gpsIFD)+2 > uint64(len(tiff)) {
+		return false
+	}
+	count := uint32(order.Uint16(tiff[gpsIFD:]))
+	entriesEnd := uint64(gpsIFD) + 2 + uint64(count)*12
+	if entriesEnd > uint64(len(tiff)) {
+		return false
+	}
+
+	for i := uint32(0); i < count; i++ {
+		field := gpsIFD + 2 + i*12
+		size := uint64(tiffTypeSizes[order.Uint16(tiff[field+2:])]) * uint64(order.Uint32(tiff[field+4:]))
+		// values over four bytes live elsewhere in the structure
+		if size > 4 {
+			offset := uint64(order.Uint32(tiff[field+8:]))
+			if offset+size <= uint64(len(tiff)) {
+				clear(tiff[offset : offset+size])
+			}
+		}
+	}
+
+	clear(tiff[gpsIFD+2 : entriesEnd])
+	order.PutUint16(tiff[gpsIFD:], 0)
+
+	return count > 0
+}
+
+func readTIFFHeader(tiff []byte) (binary.ByteOrder, uint32, error) {
+	if len(tiff) < 8 {
+		return nil, 0, errMalformedExif
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, errMalformedExif
+	}
+
+	return order, order.Uint32(tiff[4:]), nil
+}
+
+// findIFDEntry returns the offset of the entry with the tag in the IFD at offset
+func findIFDEntry(tiff []byte, order binary.ByteOrder, offset uint32, tag uint16) (uint32, bool) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return 0, false
+	}
+
+	count := uint32(order.Uint16(tiff[offset:]))
+	for i := uint32(0); i < count; i++ {
+		entry := offset + 2 + i*12
+		if uint64(entry)+12 > uint64(len(tiff)) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == tag {
+			return entry, true
+		}
+	}
+
+	return 0, false
+}
+
+// stripPNGLocation drops the eXIf chunk, PNG has no other standard place for coordinates
+func stripPNGLocation(data []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, pngHeader) {
+		return nil, false, errors.New("not a png")
+	}
+
+	out := bytes.Clone(data[:len(pngHeader)])
+	changed := false
+	for i := len(pngHeader); i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, false, errors.New("malformed png chunk")
+		}
+
+		if string(data[i+4:i+8]) == "eXIf" {
+			changed = true
+		} else {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	return out, changed, nil
+}
+
+// stripWebPLocation drops the EXIF and XMP chunks of an extended WebP and clears their flags
+func stripWebPLocation(data []byte) ([]byte, bool, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false, errors.New("not a webp")
+	}
+
+	out := bytes.Clone(data[:12])
+	changed := false
+	vp8x := -1
+	for i := 12; i+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[i+4:]))
+		// chunks are padded to an even size
+		end := i + 8 + length + length%2
+		if length < 0 || end > len(data) {
+			return nil, false, errors.New("malformed webp chunk")
+		}
+
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+			changed = true
+		case "VP8X":
+			vp8x = len(out)
+			out = append(out, data[i:end]...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	if !changed {
+		return data, false, nil
+	}
+
+	if vp8x >= 0 {
+		// flag bits 3 and 2 announce EXIF and XMP metadata
+		out[vp8x+8] &^= 0x08 | 0x04
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+
+	return out, true, nil
+}
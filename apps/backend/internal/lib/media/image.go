@@ -0,0 +1,159 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+
+	// register the decoders for the formats thumbnails are made from
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds the images that are decoded, so a small file cannot expand into gigabytes
+const MaxPixels = 50_000_000
+
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// ThumbnailSize is a named bounding box for the longest edge of a thumbnail
+type ThumbnailSize struct {
+	Name    string
+	MaxEdge int
+}
+
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", MaxEdge: 160},
+	{Name: "medium", MaxEdge: 480},
+	{Name: "large", MaxEdge: 1024},
+}
+
+var ErrImageTooLarge = errors.New("image has too many pixels to process")
+
+var imageMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// IsImage reports whether thumbnails can be made for the media type
+func IsImage(mimeType string) bool {
+	return imageMimeTypes[strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))]
+}
+
+// DecodeImage decodes an image upright, following its EXIF orientation
+func DecodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return applyOrientation(img, Orientation(data)), nil
+}
+
+// Resize scales the image down to fit maxEdge, smaller images are returned as they are
+func Resize(img image.Image, maxEdge int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxEdge && height <= maxEdge {
+		return img
+	}
+
+	if width >= height {
+		height = max(height*maxEdge/width, 1)
+		width = maxEdge
+	} else {
+		width = max(width*maxEdge/height, 1)
+		height = maxEdge
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Over, nil)
+
+	return resized
+}
+
+// Encode writes the image in one of the thumbnail formats
+func Encode(img image.Image, format string) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	switch format {
+	case FormatJPEG:
+		if err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 82}); err != nil {
+			return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+	case FormatWebP:
+		if err := nativewebp.Encode(&buffer, img, nil); err != nil {
+			return nil, fmt.Errorf("failed to encode webp: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown image format %q", format)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ContentType is the media type of a thumbnail format
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// applyOrientation turns an image stored sideways or mirrored the way the camera meant it
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// orientations 5 to 8 swap the axes
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		outWidth, outHeight = height, width
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, outWidth, outHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			out.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return out
+}
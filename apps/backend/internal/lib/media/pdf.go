@@ -0,0 +1,75 @@
+package media
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// maxObjectStreamBytes bounds how much compressed PDF structure is inflated while counting pages
+const maxObjectStreamBytes = 16 << 20
+
+var (
+	pagesCountPattern = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+	pagePattern       = regexp.MustCompile(`/Type\s*/Page\b`)
+	objectStmPattern  = regexp.MustCompile(`/Type\s*/ObjStm\b[^>]*>>\s*stream\r?\n`)
+)
+
+var ErrPageCountUnknown = errors.New("could not find the page count")
+
+// PDFPageCount reads the page count from the root of the page tree. Documents that keep their
+// page tree in compressed object streams are inflated first.
+func PDFPageCount(data []byte) (int, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return 0, errors.New("not a pdf")
+	}
+
+	corpus := [][]byte{data}
+	inflated := 0
+	for _, match := range objectStmPattern.FindAllIndex(data, -1) {
+		if inflated >= maxObjectStreamBytes {
+			break
+		}
+
+		reader, err := zlib.NewReader(bytes.NewReader(data[match[1]:]))
+		if err != nil {
+			continue
+		}
+		// the stream ends where zlib does, trailing bytes are never read
+		content, _ := io.ReadAll(io.LimitReader(reader, int64(maxObjectStreamBytes-inflated)))
+		reader.Close()
+
+		inflated += len(content)
+		corpus = append(corpus, content)
+	}
+
+	// the root of the page tree holds the largest count
+	pages := 0
+	for _, content := range corpus {
+		for _, match := range pagesCountPattern.FindAllSubmatch(content, -1) {
+			value := match[1]
+			if value == nil {
+				value = match[2]
+			}
+			if count, err := strconv.Atoi(string(value)); err == nil && count > pages {
+				pages = count
+			}
+		}
+	}
+	if pages > 0 {
+		return pages, nil
+	}
+
+	// fall back to counting the page objects themselves
+	for _, content := range corpus {
+		pages += len(pagePattern.FindAllIndex(content, -1))
+	}
+	if pages == 0 {
+		return 0, ErrPageCountUnknown
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,298 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+const (
+	streamKeyPrefix = "realtime:stream:"
+	channelPrefix   = "realtime:user:"
+)
+
+// Broker fans change events out to every connected client of a user.
+//
+// Each event is appended to a capped per-user redis stream, which provides the
+// event ID and the replay buffer for Last-Event-ID resume, and is then published
+// on a per-user pub/sub channel so that every API instance can deliver it to the
+// connections it holds locally.
+type Broker struct {
+	redis  *redis.Client
+	logger *zerolog.Logger
+	cfg    *config.RealtimeConfig
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// Subscription is a single client connection listening to a user's events
+type Subscription struct {
+	UserID string
+	events chan Event
+	once   sync.Once
+}
+
+// Events is closed when the subscription is dropped, either by Unsubscribe or
+// because the client could not keep up and should reconnect with Last-Event-ID
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+}
+
+func NewBroker(client *redis.Client, logger *zerolog.Logger, cfg *config.RealtimeConfig) *Broker {
+	return &Broker{
+		redis:       client,
+		logger:      logger,
+		cfg:         cfg,
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start listens for events published by any instance and dispatches them to local subscribers
+func (b *Broker) Start() {
+	b.pubsub = b.redis.PSubscribe(context.Background(), channelPrefix+"*")
+
+	go func() {
+		ch := b.pubsub.Channel()
+		for {
+			select {
+			case <-b.done:
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				b.dispatch(msg)
+			}
+		}
+	}()
+
+	b.logger.Info().Msg("Started realtime broker")
+}
+
+func (b *Broker) Stop() {
+	b.logger.Info().Msg("Stopping realtime broker")
+	close(b.done)
+
+	if b.pubsub != nil {
+		if err := b.pubsub.Close(); err != nil {
+			b.logger.Error().Err(err).Msg("failed to close realtime pubsub")
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, subs := range b.subscribers {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+	b.subscribers = make(map[string]map[*Subscription]struct{})
+}
+
+// Publish records an event in the user's replay buffer and notifies every instance
+func (b *Broker) Publish(ctx context.Context, userID string, eventType EventType, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal realtime event %s: %w", eventType, err)
+	}
+
+	occurredAt := time.Now().UTC()
+	streamKey := streamKeyPrefix + userID
+
+	id, err := b.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey,
+		MaxLen: b.cfg.ReplayBufferSize,
+		Approx: true,
+		Values: map[string]any{
+			"type":       string(eventType),
+			"data":       string(payload),
+			"occurredAt": occurredAt.Format(time.RFC3339Nano),
+		},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to append realtime event %s for user_id=%s: %w", eventType, userID, err)
+	}
+
+	if err := b.redis.Expire(ctx, streamKey, b.cfg.ReplayTTL).Err(); err != nil {
+		b.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to refresh realtime replay buffer ttl")
+	}
+
+	message, err := json.Marshal(Event{
+		ID:         id,
+		Type:       eventType,
+		Data:       payload,
+		OccurredAt: occurredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal realtime message %s: %w", eventType, err)
+	}
+
+	if err := b.redis.Publish(ctx, channelPrefix+userID, message).Err(); err != nil {
+		return fmt.Errorf("failed to publish realtime event %s for user_id=%s: %w", eventType, userID, err)
+	}
+
+	return nil
+}
+
+// Replay returns the buffered events that were published after lastEventID.
+//
+// If lastEventID is older than everything still retained, or the buffer has expired
+// altogether, events may have been lost in between, so a synthetic resync event is
+// returned ahead of whatever is still buffered.
+func (b *Broker) Replay(ctx context.Context, userID string, lastEventID string) ([]Event, error) {
+	if !IsValidID(lastEventID) {
+		return []Event{}, nil
+	}
+
+	streamKey := streamKeyPrefix + userID
+
+	oldest, err := b.redis.XRangeN(ctx, streamKey, "-", "+", 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read realtime replay buffer for user_id=%s: %w", userID, err)
+	}
+
+	events := []Event{}
+	if len(oldest) == 0 || compareIDs(oldest[0].ID, lastEventID) > 0 {
+		events = append(events, Event{
+			ID:         lastEventID,
+			Type:       EventResync,
+			UserID:     userID,
+			Data:       json.RawMessage("{}"),
+			OccurredAt: time.Now().UTC(),
+		})
+	}
+	if len(oldest) == 0 {
+		return events, nil
+	}
+
+	messages, err := b.redis.XRange(ctx, streamKey, "("+lastEventID, "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay realtime events for user_id=%s: %w", userID, err)
+	}
+
+	for _, msg := range messages {
+		event, err := eventFromStream(userID, msg)
+		if err != nil {
+			b.logger.Warn().Err(err).Str("event_id", msg.ID).Msg("skipping malformed realtime event")
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (b *Broker) Subscribe(userID string) *Subscription {
+	sub := &Subscription{
+		UserID: userID,
+		events: make(chan Event, b.cfg.SubscriberBufferSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(sub)
+}
+
+// remove must be called with the write lock held
+func (b *Broker) remove(sub *Subscription) {
+	if subs, ok := b.subscribers[sub.UserID]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(b.subscribers, sub.UserID)
+		}
+	}
+	sub.close()
+}
+
+func (b *Broker) dispatch(msg *redis.Message) {
+	userID := strings.TrimPrefix(msg.Channel, channelPrefix)
+
+	var event Event
+	if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+		b.logger.Warn().Err(err).Str("channel", msg.Channel).Msg("dropping malformed realtime message")
+		return
+	}
+	event.UserID = userID
+
+	var slow []*Subscription
+
+	b.mu.RLock()
+	for sub := range b.subscribers[userID] {
+		select {
+		case sub.events <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+
+	// Drop connections that fell behind; they resume from the replay buffer on reconnect
+	b.mu.Lock()
+	for _, sub := range slow {
+		b.remove(sub)
+	}
+	b.mu.Unlock()
+
+	b.logger.Warn().
+		Str("user_id", userID).
+		Int("dropped", len(slow)).
+		Msg("dropped slow realtime subscribers")
+}
+
+func eventFromStream(userID string, msg redis.XMessage) (Event, error) {
+	eventType, _ := msg.Values["type"].(string)
+	data, _ := msg.Values["data"].(string)
+	occurredAtRaw, _ := msg.Values["occurredAt"].(string)
+
+	if eventType == "" || data == "" {
+		return Event{}, fmt.Errorf("realtime event %s is missing type or data", msg.ID)
+	}
+
+	occurredAt, err := time.Parse(time.RFC3339Nano, occurredAtRaw)
+	if err != nil {
+		return Event{}, fmt.Errorf("realtime event %s has invalid timestamp: %w", msg.ID, err)
+	}
+
+	return Event{
+		ID:         msg.ID,
+		Type:       EventType(eventType),
+		UserID:     userID,
+		Data:       json.RawMessage(data),
+		OccurredAt: occurredAt,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package realtime
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventTodoCreated            EventType = "todo.created"
+	EventTodoUpdated            EventType = "todo.updated"
+	EventTodoDeleted            EventType = "todo.deleted"
+	EventTodoAssigned           EventType = "todo.assigned"
+	EventTodoUnassigned         EventType = "todo.unassigned"
+	EventCommentCreated         EventType = "comment.created"
+	EventCommentUpdated         EventType = "comment.updated"
+	EventCommentDeleted         EventType = "comment.deleted"
+	EventCommentMentioned       EventType = "comment.mentioned"
+	EventCommentReactionAdded   EventType = "comment.reaction_added"
+	EventCommentReactionRemoved EventType = "comment.reaction_removed"
+	EventCategoryCreated        EventType = "category.created"
+	EventCategoryUpdated        EventType = "category.updated"
+	EventCategoryDeleted        EventType = "category.deleted"
+	EventAttachmentCreated      EventType = "attachment.created"
+	EventAttachmentDeleted      EventType = "attachment.deleted"
+	EventAttachmentScanned      EventType = "attachment.scanned"
+	EventAttachmentQuarantined  EventType = "attachment.quarantined"
+	EventAttachmentUpdated      EventType = "attachment.updated"
+	EventShareInvited           EventType = "share.invited"
+	EventShareUpdated           EventType = "share.updated"
+	EventShareDeleted           EventType = "share.deleted"
+
+	// archives built in the background are only announced to the user who requested them
+	EventAttachmentArchiveReady  EventType = "attachment.archive_ready"
+	EventAttachmentArchiveFailed EventType = "attachment.archive_failed"
+
+	// EventResync tells a client that its Last-Event-ID fell out of the replay
+	// buffer and it should refetch its state instead of relying on the stream
+	EventResync EventType = "resync"
+)
+
+// Event is a single change notification delivered to a user's stream
+type Event struct {
+	ID         string          `json:"id"`
+	Type       EventType       `json:"type"`
+	UserID     string          `json:"-"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurredAt"`
+}
+
+// After reports whether the event was appended to the stream after the given event ID
+func (e *Event) After(id string) bool {
+	return compareIDs(e.ID, id) > 0
+}
+
+// compareIDs orders two redis stream IDs ("<ms>-<seq>"); unparsable IDs sort first
+func compareIDs(a, b string) int {
+	aMs, aSeq, aOk := parseID(a)
+	bMs, bSeq, bOk := parseID(b)
+
+	switch {
+	case !aOk && !bOk:
+		return 0
+	case !aOk:
+		return -1
+	case !bOk:
+		return 1
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseID(id string) (uint64, uint64, bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return ms, seq, true
+}
+
+// IsValidID reports whether the value looks like an event ID issued by the broker
+func IsValidID(id string) bool {
+	_, _, ok := parseID(id)
+	return ok
+}
+
+// Deleted is the payload of *.deleted events, which no longer have an entity to send
+type Deleted struct {
+	ID     uuid.UUID  `json:"id"`
+	TodoID *uuid.UUID `json:"todoId,omitempty"`
+}
@@ -0,0 +1,84 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantMs  uint64
+		wantSeq uint64
+		wantOk  bool
+	}{
+		{name: "stream id", id: "1700000000000-3", wantMs: 1700000000000, wantSeq: 3, wantOk: true},
+		{name: "zero", id: "0-0", wantOk: true},
+		{name: "empty", id: ""},
+		{name: "missing sequence", id: "1700000000000"},
+		{name: "empty sequence", id: "1700000000000-"},
+		{name: "empty milliseconds", id: "-1"},
+		{name: "negative", id: "-1-1"},
+		{name: "not a number", id: "abc-1"},
+		{name: "trailing garbage", id: "1-1-1"},
+		{name: "overflow", id: "18446744073709551616-0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, seq, ok := parseID(tt.id)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantMs, ms)
+			assert.Equal(t, tt.wantSeq, seq)
+			assert.Equal(t, tt.wantOk, IsValidID(tt.id))
+		})
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want int
+	}{
+		{name: "equal", a: "5-1", b: "5-1", want: 0},
+		{name: "earlier millisecond", a: "4-9", b: "5-0", want: -1},
+		{name: "later millisecond", a: "6-0", b: "5-9", want: 1},
+		{name: "earlier sequence", a: "5-1", b: "5-2", want: -1},
+		{name: "later sequence", a: "5-10", b: "5-9", want: 1},
+		{name: "numeric not lexical", a: "10-0", b: "9-0", want: 1},
+		{name: "invalid sorts first", a: "bogus", b: "0-0", want: -1},
+		{name: "valid sorts after invalid", a: "0-0", b: "", want: 1},
+		{name: "both invalid", a: "x", b: "y", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, compareIDs(tt.a, tt.b))
+		})
+	}
+}
+
+func TestEventAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		eventID string
+		id      string
+		want    bool
+	}{
+		{name: "newer event", eventID: "5-1", id: "5-0", want: true},
+		{name: "same event", eventID: "5-1", id: "5-1", want: false},
+		{name: "older event", eventID: "4-7", id: "5-0", want: false},
+		{name: "no previous id", eventID: "5-1", id: "", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := Event{ID: tt.eventID}
+			assert.Equal(t, tt.want, event.After(tt.id))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// clamdChunkSize is how much of the file is sent per INSTREAM chunk
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner streams files to a ClamAV daemon over its TCP socket using the INSTREAM command
+type ClamdScanner struct {
+	address string
+	timeout time.Duration
+}
+
+func NewClamdScanner(address string, timeout time.Duration) *ClamdScanner {
+	return &ClamdScanner{address: address, timeout: timeout}
+}
+
+func (c *ClamdScanner) Name() string {
+	return config.ScannerDriverClamd
+}
+
+func (c *ClamdScanner) Scan(ctx context.Context, file io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd at %s: %w", c.address, err)
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed to set clamd deadline: %w", err)
+	}
+
+	// the z prefix makes clamd expect and send null terminated messages
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to start clamd stream: %w", err)
+	}
+
+	if err := streamChunks(conn, file); err != nil {
+		// clamd hangs up when the stream exceeds its StreamMaxLength, its reply says why
+		if reply, replyErr := readReply(conn); replyErr == nil && reply != "" {
+			return parseReply(reply)
+		}
+		return nil, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReply(reply)
+}
+
+// streamChunks sends the file as length prefixed chunks followed by a zero length chunk
+func streamChunks(conn net.Conn, file io.Reader) error {
+	buffer := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := file.Read(buffer[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buffer[:4], uint32(n))
+			if _, writeErr := conn.Write(buffer[:4+n]); writeErr != nil {
+				return fmt.Errorf("failed to stream file to clamd: %w", writeErr)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file for scanning: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to end clamd stream: %w", err)
+	}
+
+	return nil
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseReply understands "stream: OK", "stream: <signature> FOUND" and "<reason> ERROR"
+func parseReply(reply string) (*Result, error) {
+	verdict := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case verdict == "OK":
+		return &Result{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Clean: false, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd could not scan the file: %s", reply)
+	}
+}
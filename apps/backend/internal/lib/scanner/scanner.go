@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// Result is the verdict of scanning one file
+type Result struct {
+	Clean bool
+	// Signature names what was found when the file is not clean
+	Signature string
+}
+
+// Scanner inspects uploaded files for malware
+type Scanner interface {
+	// Scan reads the file to the end and returns its verdict. An error means the
+	// file could not be scanned and should be retried, not that it is infected.
+	Scan(ctx context.Context, file io.Reader) (*Result, error)
+	Name() string
+}
+
+// New returns the scanner selected by the config
+func New(cfg *config.ScannerConfig) (Scanner, error) {
+	switch cfg.Driver {
+	case config.ScannerDriverNoop:
+		return NoopScanner{}, nil
+	case config.ScannerDriverClamd:
+		return NewClamdScanner(cfg.ClamdAddress, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown scanner driver %q", cfg.Driver)
+	}
+}
+
+// NoopScanner reports every file as clean, for environments without a virus scanner
+type NoopScanner struct{}
+
+func (NoopScanner) Scan(ctx context.Context, file io.Reader) (*Result, error) {
+	return &Result{Clean: true}, nil
+}
+
+func (NoopScanner) Name() string {
+	return config.ScannerDriverNoop
+}
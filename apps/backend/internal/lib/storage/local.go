@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// LocalStorage keeps objects as files under a root directory, with a JSON sidecar per object
+// holding its content type. Downloads and uploads go through URLs signed by the API.
+type LocalStorage struct {
+	objectsDir string
+	metaDir    string
+	signer     *Signer
+}
+
+type localMeta struct {
+	ContentType string `json:"contentType"`
+}
+
+func NewLocalStorage(root string, signer *Signer) (*LocalStorage, error) {
+	storage := &LocalStorage{
+		objectsDir: filepath.Join(root, "objects"),
+		metaDir:    filepath.Join(root, "meta"),
+		signer:     signer,
+	}
+
+	for _, dir := range []string{storage.objectsDir, storage.metaDir} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory %s: %w", dir, err)
+		}
+	}
+
+	return storage, nil
+}
+
+func (s *LocalStorage) Name() string {
+	return config.StorageDriverLocal
+}
+
+func (s *LocalStorage) Signer() *Signer {
+	return s.signer
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	objectPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, contentType, err := limitedBody(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	// write next to the destination and rename, so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file for %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, contextReader{ctx: ctx, reader: body})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return nil, ErrTooLarge
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	if err := s.writeMeta(metaPath, localMeta{ContentType: contentType}); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), objectPath); err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", key, err)
+	}
+
+	return &ObjectInfo{Key: key, Size: size, ContentType: contentType}, nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	objectPath, _, err := s.paths(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := s.Head(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(objectPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+
+	return file, info, nil
+}
+
+func (s *LocalStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	body, _, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return sectionReader(body.(*os.File), offset, length)
+}
+
+func (s *LocalStorage) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	objectPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(objectPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+
+	meta := localMeta{ContentType: "application/octet-stream"}
+	if data, err := os.ReadFile(metaPath); err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("failed to read metadata of %s: %w", key, err)
+		}
+	}
+
+	return &ObjectInfo{Key: key, Size: stat.Size(), ContentType: meta.ContentType, ModifiedAt: stat.ModTime()}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	objectPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{objectPath, metaPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) Copy(ctx context.Context, sourceKey, destinationKey string) error {
+	source, info, err := s.Get(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	_, err = s.Put(ctx, destinationKey, source, PutOptions{ContentType: info.ContentType})
+	return err
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string, fn func(info ObjectInfo) error) error {
+	err := filepath.WalkDir(s.objectsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// skip directories and uploads still being written
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(s.objectsDir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		return fn(ObjectInfo{Key: key, Size: stat.Size(), ModifiedAt: stat.ModTime()})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list objects under %s: %w", prefix, err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) PresignGet(ctx context.Context, key string, expiry time.Duration, opts PresignGetOptions) (string, error) {
+	if _, _, err := s.paths(key); err != nil {
+		return "", err
+	}
+
+	return s.signer.PresignGet(key, expiry, opts.ContentDisposition), nil
+}
+
+func (s *LocalStorage) PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (*PresignedUpload, error) {
+	if _, _, err := s.paths(key); err != nil {
+		return nil, err
+	}
+
+	return s.signer.PresignPut(key, contentType, size, expiry), nil
+}
+
+func (s *LocalStorage) PresignPost(ctx context.Context, key, contentType string, maxSize int64, expiry time.Duration) (*PresignedUpload, error) {
+	if _, _, err := s.paths(key); err != nil {
+		return nil, err
+	}
+
+	return s.signer.PresignPost(key, contentType, maxSize, expiry), nil
+}
+
+// paths maps a key to its object and metadata files, refusing keys that would escape the root
+func (s *LocalStorage) paths(key string) (string, string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("invalid storage key %q", key)
+	}
+
+	return filepath.Join(s.objectsDir, clean), filepath.Join(s.metaDir, clean+".json"), nil
+}
+
+func (s *LocalStorage) writeMeta(path string, meta localMeta) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	return nil
+}
+
+// contextReader stops a copy once the context is cancelled
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
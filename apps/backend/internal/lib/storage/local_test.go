@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStoragePaths(t *testing.T) {
+	root := t.TempDir()
+	s := &LocalStorage{
+		objectsDir: filepath.Join(root, "objects"),
+		metaDir:    filepath.Join(root, "meta"),
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "nested key", key: "todos/user/todo/attachment", want: filepath.Join("todos", "user", "todo", "attachment")},
+		{name: "redundant segments", key: "todos/./user//todo", want: filepath.Join("todos", "user", "todo")},
+		{name: "parent inside the key", key: "todos/user/../other", want: filepath.Join("todos", "other")},
+		{name: "dots in a name", key: "..hidden", want: "..hidden"},
+		{name: "empty", key: "", wantErr: true},
+		{name: "current directory", key: ".", wantErr: true},
+		{name: "parent directory", key: "..", wantErr: true},
+		{name: "escapes the root", key: "../outside", wantErr: true},
+		{name: "escapes after cleaning", key: "todos/../../outside", wantErr: true},
+		{name: "absolute", key: "/etc/passwd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objectPath, metaPath, err := s.paths(tt.key)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(s.objectsDir, tt.want), objectPath)
+			assert.Equal(t, filepath.Join(s.metaDir, tt.want+".json"), metaPath)
+		})
+	}
+}
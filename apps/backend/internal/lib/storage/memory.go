@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+)
+
+// MemoryStorage keeps objects in process memory, for tests and throwaway environments.
+// Everything is lost on restart and nothing is shared between instances.
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	signer  *Signer
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	modifiedAt  time.Time
+}
+
+func NewMemoryStorage(signer *Signer) *MemoryStorage {
+	return &MemoryStorage{
+		objects: map[string]memoryObject{},
+		signer:  signer,
+	}
+}
+
+func (s *MemoryStorage) Name() string {
+	return config.StorageDriverMemory
+}
+
+func (s *MemoryStorage) Signer() *Signer {
+	return s.signer
+}
+
+func (s *MemoryStorage) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	body, contentType, err := limitedBody(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(contextReader{ctx: ctx, reader: body})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if opts.MaxSize > 0 && int64(len(data)) > opts.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	s.mu.Lock()
+	s.objects[key] = memoryObject{data: data, contentType: contentType, modifiedAt: time.Now()}
+	s.mu.Unlock()
+
+	return &ObjectInfo{Key: key, Size: int64(len(data)), ContentType: contentType}, nil
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	s.mu.RLock()
+	object, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	// objects are replaced, never modified, so the slice can be shared with the reader
+	return memoryReader{bytes.NewReader(object.data)}, object.info(key), nil
+}
+
+func (s *MemoryStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	body, _, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return sectionReader(body.(memoryReader), offset, length)
+}
+
+func (s *MemoryStorage) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	s.mu.RLock()
+	object, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return object.info(key), nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.objects, key)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryStorage) Copy(ctx context.Context, sourceKey, destinationKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[sourceKey]
+	if !ok {
+		return ErrNotFound
+	}
+	object.modifiedAt = time.Now()
+	s.objects[destinationKey] = object
+
+	return nil
+}
+
+func (s *MemoryStorage) List(ctx context.Context, prefix string, fn func(info ObjectInfo) error) error {
+	// collect first so fn can call back into the storage without deadlocking
+	s.mu.RLock()
+	infos := []ObjectInfo{}
+	for key, object := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, *object.info(key))
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStorage) PresignGet(ctx context.Context, key string, expiry time.Duration, opts PresignGetOptions) (string, error) {
+	return s.signer.PresignGet(key, expiry, opts.ContentDisposition), nil
+}
+
+func (s *MemoryStorage) PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (*PresignedUpload, error) {
+	return s.signer.PresignPut(key, contentType, size, expiry), nil
+}
+
+func (s *MemoryStorage) PresignPost(ctx context.Context, key, contentType string, maxSize int64, expiry time.Duration) (*PresignedUpload, error) {
+	return s.signer.PresignPost(key, contentType, maxSize, expiry), nil
+}
+
+func (o memoryObject) info(key string) *ObjectInfo {
+	return &ObjectInfo{Key: key, Size: int64(len(o.data)), ContentType: o.contentType, ModifiedAt: o.modifiedAt}
+}
+
+// memoryReader gives a stored object the ReadCloser Get promises while staying seekable
+type memoryReader struct {
+	*bytes.Reader
+}
+
+func (memoryReader) Close() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+	"github.com/C0deNe0/go-tasker/internal/lib/aws"
+)
+
+// S3Storage keeps objects in one S3 compatible bucket
+type S3Storage struct {
+	client *aws.S3Client
+	bucket string
+}
+
+func NewS3Storage(client *aws.S3Client, bucket string) *S3Storage {
+	return &S3Storage{client: client, bucket: bucket}
+}
+
+func (s *S3Storage) Name() string {
+	return config.StorageDriverS3
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		// S3 caps a single object at 5 TiB
+		maxSize = 5 << 40
+	}
+
+	uploaded, err := s.client.UploadFile(ctx, s.bucket, key, opts.ContentType, body, maxSize)
+	if err != nil {
+		return nil, translateS3Error(err)
+	}
+
+	return &ObjectInfo{
+		Key:         uploaded.Key,
+		Size:        uploaded.Size,
+		ContentType: uploaded.ContentType,
+	}, nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	body, info, err := s.client.GetObject(ctx, s.bucket, key)
+	if err != nil {
+		return nil, nil, translateS3Error(err)
+	}
+
+	return body, fromS3Info(info), nil
+}
+
+func (s *S3Storage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	body, err := s.client.GetObjectRange(ctx, s.bucket, key, offset, length)
+	if err != nil {
+		return nil, translateS3Error(err)
+	}
+
+	return body, nil
+}
+
+func (s *S3Storage) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.client.HeadObject(ctx, s.bucket, key)
+	if err != nil {
+		return nil, translateS3Error(err)
+	}
+
+	return fromS3Info(info), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	return s.client.DeleteObject(ctx, s.bucket, key)
+}
+
+func (s *S3Storage) Copy(ctx context.Context, sourceKey, destinationKey string) error {
+	return translateS3Error(s.client.CopyObject(ctx, s.bucket, sourceKey, destinationKey))
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string, fn func(info ObjectInfo) error) error {
+	return s.client.ListObjects(ctx, s.bucket, prefix, func(info aws.ObjectInfo) error {
+		return fn(*fromS3Info(&info))
+	})
+}
+
+func (s *S3Storage) PresignGet(ctx context.Context, key string, expiry time.Duration, opts PresignGetOptions) (string, error) {
+	return s.client.CreatePresignedUrl(ctx, s.bucket, key, expiry, opts.ContentDisposition)
+}
+
+func (s *S3Storage) PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (*PresignedUpload, error) {
+	upload, err := s.client.CreatePresignedPutUpload(ctx, s.bucket, key, contentType, size, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return (*PresignedUpload)(upload), nil
+}
+
+func (s *S3Storage) PresignPost(ctx context.Context, key, contentType string, maxSize int64, expiry time.Duration) (*PresignedUpload, error) {
+	upload, err := s.client.CreatePresignedPostUpload(ctx, s.bucket, key, contentType, maxSize, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return (*PresignedUpload)(upload), nil
+}
+
+func fromS3Info(info *aws.ObjectInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:         info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModifiedAt:  info.LastModified,
+	}
+}
+
+// translateS3Error maps the S3 client's sentinel errors onto the storage ones
+func translateS3Error(err error) error {
+	switch {
+	case errors.Is(err, aws.ErrObjectNotFound):
+		return ErrNotFound
+	case errors.Is(err, aws.ErrFileTooLarge):
+		return ErrTooLarge
+	default:
+		return err
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sectionReader limits an opened object to length bytes from offset, closing it with the result
+func sectionReader(body io.ReadSeekCloser, offset, length int64) (io.ReadCloser, error) {
+	if _, err := body.Seek(offset, io.SeekStart); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to seek to %d: %w", offset, err)
+	}
+
+	if length < 0 {
+		return body, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, length), body}, nil
+}
+
+// OpenSeeker opens an object as an io.ReadSeekCloser, so it can be served with http.ServeContent.
+// Seeking is free, the object is fetched from the current offset on the next read.
+func OpenSeeker(ctx context.Context, storage Storage, key string) (io.ReadSeekCloser, *ObjectInfo, error) {
+	info, err := storage.Head(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &rangeSeeker{ctx: ctx, storage: storage, key: key, size: info.Size}, info, nil
+}
+
+type rangeSeeker struct {
+	ctx     context.Context
+	storage Storage
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+func (r *rangeSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, err := r.storage.GetRange(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *rangeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if offset != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = offset
+
+	return offset, nil
+}
+
+func (r *rangeSeeker) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner("http://localhost:8080/", "secret", time.Hour)
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	upload := SignedRequest{
+		Method:      http.MethodPut,
+		Key:         "todos/user/todo/attachment",
+		ContentType: "application/pdf",
+		Size:        1024,
+		ExpiresAt:   expiresAt,
+	}
+	download := SignedRequest{
+		Method:             http.MethodGet,
+		Key:                "todos/user/todo/attachment",
+		ContentDisposition: `attachment; filename="report.pdf"`,
+		ExpiresAt:          expiresAt,
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		values  func() url.Values
+		want    *SignedRequest
+		wantErr error
+	}{
+		{
+			name:   "valid upload",
+			method: http.MethodPut,
+			values: func() url.Values { return signer.Sign(upload) },
+			want:   &upload,
+		},
+		{
+			name:   "valid download",
+			method: http.MethodGet,
+			values: func() url.Values { return signer.Sign(download) },
+			want:   &download,
+		},
+		{
+			name:    "other method",
+			method:  http.MethodDelete,
+			values:  func() url.Values { return signer.Sign(upload) },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "other key",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Set("key", "todos/someone/else")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "larger size",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Set("size", "2048")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "other content type",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Set("content_type", "text/html")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "other disposition",
+			method: http.MethodGet,
+			values: func() url.Values {
+				values := signer.Sign(download)
+				values.Set("disposition", "inline")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "extended expiry",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Set("expires", "99999999999")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "missing signature",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Del("signature")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "malformed expiry",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Set("expires", "soon")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "malformed size",
+			method: http.MethodPut,
+			values: func() url.Values {
+				values := signer.Sign(upload)
+				values.Set("size", "big")
+				return values
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "empty key",
+			method: http.MethodGet,
+			values: func() url.Values {
+				return signer.Sign(SignedRequest{Method: http.MethodGet, ExpiresAt: expiresAt})
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "signed with another secret",
+			method: http.MethodPut,
+			values: func() url.Values {
+				return NewSigner("http://localhost:8080", "other", time.Hour).Sign(upload)
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "expired",
+			method: http.MethodGet,
+			values: func() url.Values {
+				expired := download
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+				return signer.Sign(expired)
+			},
+			wantErr: ErrURLExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.Verify(tt.method, tt.values())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Method, got.Method)
+			assert.Equal(t, tt.want.Key, got.Key)
+			assert.Equal(t, tt.want.ContentType, got.ContentType)
+			assert.Equal(t, tt.want.Size, got.Size)
+			assert.Equal(t, tt.want.ContentDisposition, got.ContentDisposition)
+			assert.True(t, tt.want.ExpiresAt.Equal(got.ExpiresAt))
+		})
+	}
+}
+
+func TestSignerPresignCapsExpiry(t *testing.T) {
+	signer := NewSigner("http://localhost:8080/", "secret", time.Minute)
+
+	presigned := signer.PresignGet("todos/a", 24*time.Hour, "")
+	require.True(t, strings.HasPrefix(presigned, "http://localhost:8080"+SignedURLPath+"?"))
+
+	parsed, err := url.Parse(presigned)
+	require.NoError(t, err)
+
+	request, err := signer.Verify(http.MethodGet, parsed.Query())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), request.ExpiresAt, 2*time.Second)
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/config"
+	"github.com/C0deNe0/go-tasker/internal/lib/aws"
+	"github.com/C0deNe0/go-tasker/internal/server"
+)
+
+var (
+	// ErrNotFound is returned when the key does not exist
+	ErrNotFound = errors.New("object not found")
+	// ErrTooLarge is returned by Put when the body is longer than the allowed size
+	ErrTooLarge = errors.New("object exceeds the maximum size")
+)
+
+// ObjectInfo is the metadata kept for a stored object. List leaves ContentType empty
+// and Put leaves ModifiedAt unset.
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModifiedAt  time.Time
+}
+
+// PutOptions controls how Put stores a body
+type PutOptions struct {
+	// ContentType is sniffed from the first bytes when empty
+	ContentType string
+	// MaxSize rejects longer bodies with ErrTooLarge, zero means unlimited
+	MaxSize int64
+}
+
+// PresignGetOptions controls the response a presigned download produces
+type PresignGetOptions struct {
+	// ContentDisposition overrides the Content-Disposition header when set
+	ContentDisposition string
+}
+
+// PresignedUpload describes how a client sends a file straight to storage.
+// PUT uploads replay Headers on the request, POST uploads submit Fields as form data before the file.
+type PresignedUpload struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Fields  map[string]string
+}
+
+// Storage keeps attachment blobs by key
+type Storage interface {
+	// Put streams body to key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error)
+	// Get opens the object for reading, the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// GetRange opens length bytes from offset, a negative length reads to the end
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete removes the object, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	Copy(ctx context.Context, sourceKey, destinationKey string) error
+	// List calls fn for every object whose key starts with prefix, stopping at the first error fn returns
+	List(ctx context.Context, prefix string, fn func(info ObjectInfo) error) error
+	// PresignGet returns a URL anyone can download the object from until it expires
+	PresignGet(ctx context.Context, key string, expiry time.Duration, opts PresignGetOptions) (string, error)
+	// PresignPut returns an upload that only accepts exactly size bytes of contentType
+	PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (*PresignedUpload, error)
+	// PresignPost returns a form upload that accepts up to maxSize bytes of contentType
+	PresignPost(ctx context.Context, key, contentType string, maxSize int64, expiry time.Duration) (*PresignedUpload, error)
+	Name() string
+}
+
+// Served is implemented by drivers whose presigned URLs point back at this API
+type Served interface {
+	Storage
+	Signer() *Signer
+}
+
+// New returns the storage driver selected by the config
+func New(s *server.Server) (Storage, error) {
+	cfg := s.Config.Storage
+
+	switch cfg.Driver {
+	case config.StorageDriverS3:
+		awsClient, err := aws.NewAWS(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS client: %w", err)
+		}
+		return NewS3Storage(awsClient.S3, s.Config.AWS.UploadBucket), nil
+	case config.StorageDriverLocal:
+		return NewLocalStorage(cfg.LocalPath, NewSigner(cfg.PublicURL, cfg.SigningKey, cfg.MaxSignedURLExpiry))
+	case config.StorageDriverMemory:
+		return NewMemoryStorage(NewSigner(cfg.PublicURL, cfg.SigningKey, cfg.MaxSignedURLExpiry)), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// limitedBody enforces PutOptions on a body and sniffs its content type when none was given
+func limitedBody(body io.Reader, opts PutOptions) (io.Reader, string, error) {
+	if opts.MaxSize > 0 {
+		// read one byte past the limit so an oversized body is rejected rather than truncated
+		body = io.LimitReader(body, opts.MaxSize+1)
+	}
+
+	if opts.ContentType != "" {
+		return body, opts.ContentType, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", fmt.Errorf("failed to read object: %w", err)
+	}
+
+	return io.MultiReader(bytes.NewReader(head[:n]), body), http.DetectContentType(head[:n]), nil
+}
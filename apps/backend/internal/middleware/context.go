@@ -7,13 +7,15 @@ import (
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
 	"github.com/C0deNe0/go-tasker/internal/logger"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
 	"github.com/C0deNe0/go-tasker/internal/server"
 )
 
 const (
-	UserIDKey   = "user_id"
-	UserRoleKey = "user_role"
-	LoggerKey   = "logger"
+	UserIDKey       = "user_id"
+	UserRoleKey     = "user_role"
+	OrganizationKey = "organization"
+	LoggerKey       = "logger"
 )
 
 type ContextEnhancer struct {
@@ -87,6 +89,14 @@ func GetUserID(c echo.Context) string {
 	return ""
 }
 
+// GetMembership returns the caller's active organization, or nil in the personal workspace
+func GetMembership(c echo.Context) *organization.Membership {
+	if membership, ok := c.Get(OrganizationKey).(*organization.Membership); ok {
+		return membership
+	}
+	return nil
+}
+
 func GetLogger(c echo.Context) *zerolog.Logger {
 	if logger, ok := c.Get(LoggerKey).(*zerolog.Logger); ok {
 		return logger
@@ -1,11 +1,85 @@
 package category
 
-import "github.com/C0deNe0/go-tasker/internal/model"
+import (
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
+)
 
 type Category struct {
 	model.Base
-	UserID      string  `json:"userId" db:"user_id"`
-	Name        string  `json:"name" db:"name"`
-	Color       string  `json:"color" db:"color"`
-	Description *string `json:"description" db:"description"`
+	UserID           string     `json:"userId" db:"user_id"`
+	OrganizationID   *string    `json:"organizationId" db:"organization_id"`
+	Name             string     `json:"name" db:"name"`
+	Color            string     `json:"color" db:"color"`
+	Description      *string    `json:"description" db:"description"`
+	ParentCategoryID *uuid.UUID `json:"parentCategoryId" db:"parent_category_id"`
+	Position         int        `json:"position" db:"position"`
+	ArchivedAt       *time.Time `json:"archivedAt" db:"archived_at"`
+}
+
+// CategoryStats counts the todos filed directly under a category that the caller can see.
+// CompletionPercent is the share of completed todos among those not archived.
+type CategoryStats struct {
+	Total             int     `json:"total"`
+	Draft             int     `json:"draft"`
+	Active            int     `json:"active"`
+	Completed         int     `json:"completed"`
+	Archived          int     `json:"archived"`
+	Overdue           int     `json:"overdue"`
+	CompletionPercent float64 `json:"completionPercent"`
+}
+
+// CategoryWithStats is a category as listed by GetCategories
+type CategoryWithStats struct {
+	Category
+	Stats CategoryStats `json:"stats" db:"stats"`
+}
+
+// DeleteCategoryResult summarizes a category deletion. DeletedCategories counts the category and its
+// subcategories, TodoIDs are the todos that were reassigned, uncategorized or deleted with them.
+type DeleteCategoryResult struct {
+	CategoryID        uuid.UUID      `json:"categoryId"`
+	Strategy          DeleteStrategy `json:"strategy"`
+	TargetCategoryID  *uuid.UUID     `json:"targetCategoryId,omitempty"`
+	DeletedCategories int            `json:"deletedCategories"`
+	AffectedTodos     int            `json:"affectedTodos"`
+	TodoIDs           []uuid.UUID    `json:"todoIds"`
+}
+
+// CategoryNode is a category with its subcategories, as listed by the category tree
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+// BuildTree nests the categories under their parents, keeping their order among siblings.
+// A category whose parent is not in the list, one shared without its parent, becomes a root.
+func BuildTree(categories []Category) []CategoryNode {
+	present := make(map[uuid.UUID]bool, len(categories))
+	for _, c := range categories {
+		present[c.ID] = true
+	}
+
+	children := make(map[uuid.UUID][]Category)
+	roots := []Category{}
+	for _, c := range categories {
+		if c.ParentCategoryID != nil && present[*c.ParentCategoryID] {
+			children[*c.ParentCategoryID] = append(children[*c.ParentCategoryID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var nest func(level []Category) []CategoryNode
+	nest = func(level []Category) []CategoryNode {
+		nodes := make([]CategoryNode, 0, len(level))
+		for _, c := range level {
+			nodes = append(nodes, CategoryNode{Category: c, Children: nest(children[c.ID])})
+		}
+		return nodes
+	}
+
+	return nest(roots)
 }
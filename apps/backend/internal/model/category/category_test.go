@@ -0,0 +1,98 @@
+package category
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTree(t *testing.T) {
+	ids := map[string]uuid.UUID{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "missing"} {
+		ids[name] = uuid.New()
+	}
+
+	category := func(name, parent string) Category {
+		c := Category{Base: model.Base{BaseWithId: model.BaseWithId{ID: ids[name]}}, Name: name}
+		if parent != "" {
+			parentID := ids[parent]
+			c.ParentCategoryID = &parentID
+		}
+		return c
+	}
+
+	tests := []struct {
+		name       string
+		categories []Category
+		want       string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name:       "flat roots keep order",
+			categories: []Category{category("b", ""), category("a", ""), category("c", "")},
+			want:       "b,a,c",
+		},
+		{
+			name: "children nest in sibling order",
+			categories: []Category{
+				category("a", ""),
+				category("c", "a"),
+				category("b", "a"),
+				category("d", ""),
+			},
+			want: "a(c,b),d",
+		},
+		{
+			name: "grandchildren",
+			categories: []Category{
+				category("a", ""),
+				category("b", "a"),
+				category("c", "b"),
+				category("d", "a"),
+			},
+			want: "a(b(c),d)",
+		},
+		{
+			name:       "absent parent becomes root",
+			categories: []Category{category("a", ""), category("b", "missing")},
+			want:       "a,b",
+		},
+		{
+			name: "child listed before parent",
+			categories: []Category{
+				category("c", "b"),
+				category("b", "a"),
+				category("a", ""),
+				category("e", ""),
+			},
+			want: "a(b(c)),e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := BuildTree(tt.categories)
+			assert.NotNil(t, tree)
+			assert.Equal(t, tt.want, shape(tree))
+		})
+	}
+}
+
+// shape renders a tree as "name(children),name" so expectations stay readable.
+func shape(nodes []CategoryNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		part := n.Name
+		if len(n.Children) > 0 {
+			part += "(" + shape(n.Children) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
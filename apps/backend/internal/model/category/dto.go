@@ -6,9 +6,10 @@ import (
 )
 
 type CreateCategoryPayload struct {
-	Name        string  `json:"name" validate:"required,min=2,max=100"`
-	Color       string  `json:"color" validate:"required,hexcolor"`
-	Description *string `json:"description" validate:"omitempty,max:255"`
+	Name             string     `json:"name" validate:"required,min=2,max=100"`
+	Color            string     `json:"color" validate:"required,hexcolor"`
+	Description      *string    `json:"description" validate:"omitempty,max:255"`
+	ParentCategoryID *uuid.UUID `json:"parentCategoryId" validate:"omitempty,uuid"`
 }
 
 func (p *CreateCategoryPayload) Validate() error {
@@ -29,11 +30,12 @@ func (p *UpdateCategoryPayload) Validate() error {
 }
 
 type GetCategoriesQuery struct {
-	Page   *int    `query:"page" validate:"omitempty,min=1"`
-	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
-	Sort   *string `query:"sort" validate:"omitempty,oneof=created_at updated_at name"`
-	Order  *string `query:"order" validate:"omitempty,oneof=asc desc"`
-	Search *string `query:"search" validate:"omitempty,min=1"`
+	Page            *int    `query:"page" validate:"omitempty,min=1"`
+	Limit           *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Sort            *string `query:"sort" validate:"omitempty,oneof=created_at updated_at name position"`
+	Order           *string `query:"order" validate:"omitempty,oneof=asc desc"`
+	Search          *string `query:"search" validate:"omitempty,min=1"`
+	IncludeArchived *bool   `query:"includeArchived"`
 }
 
 
@@ -65,11 +67,75 @@ func (q *GetCategoriesQuery) Validate() error {
 	}
 	return nil
 }
-type DeleteCategoryPayload struct {
+
+// MoveCategoryPayload puts a category under another parent, or at the top level when ParentCategoryID is nil
+type MoveCategoryPayload struct {
+	ID               uuid.UUID  `param:"id" validate:"required,uuid"`
+	ParentCategoryID *uuid.UUID `json:"parentCategoryId" validate:"omitempty,uuid"`
+}
+
+func (p *MoveCategoryPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetCategoryTreePayload struct {
+	IncludeArchived *bool `query:"includeArchived"`
+}
+
+func (p *GetCategoryTreePayload) Validate() error {
+	return nil
+}
+
+// ReorderCategoriesPayload orders the subcategories of a parent, the top level categories of the
+// workspace when ParentCategoryID is nil. Siblings left out keep their order after the listed ones.
+type ReorderCategoriesPayload struct {
+	ParentCategoryID *uuid.UUID  `json:"parentCategoryId" validate:"omitempty,uuid"`
+	CategoryIDs      []uuid.UUID `json:"categoryIds" validate:"required,min=1,max=500,unique"`
+}
+
+func (p *ReorderCategoriesPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type ArchiveCategoryPayload struct {
 	ID uuid.UUID `param:"id" validate:"required,uuid"`
 }
 
-func (p *DeleteCategoryPayload) Validate() error {
+func (p *ArchiveCategoryPayload) Validate() error {
 	validate := validator.New()
 	return validate.Struct(p)
+}
+
+// DeleteStrategy decides what happens to the todos of a deleted category and its subcategories
+type DeleteStrategy string
+
+const (
+	// DeleteStrategyReassign moves the todos to the target category
+	DeleteStrategyReassign DeleteStrategy = "reassign"
+	// DeleteStrategyUncategorize keeps the todos without a category
+	DeleteStrategyUncategorize DeleteStrategy = "uncategorize"
+	// DeleteStrategyCascade deletes the todos together with their subtasks
+	DeleteStrategyCascade DeleteStrategy = "cascade"
+)
+
+type DeleteCategoryPayload struct {
+	ID       uuid.UUID       `param:"id" validate:"required,uuid"`
+	Strategy *DeleteStrategy `query:"strategy" validate:"omitempty,oneof=reassign uncategorize cascade"`
+	Target   *uuid.UUID      `query:"target" validate:"required_if=Strategy reassign,excluded_unless=Strategy reassign"`
+}
+
+func (p *DeleteCategoryPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	//todos are kept unless deleting them is asked for
+	if p.Strategy == nil {
+		defaultStrategy := DeleteStrategyUncategorize
+		p.Strategy = &defaultStrategy
+	}
+	return nil
 }
\ No newline at end of file
@@ -1,13 +1,60 @@
 package comment
 
 import (
-	"github.com/google/uuid"
+	"time"
+
 	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
 )
 
 type Comment struct {
 	model.Base
-	TodoID uuid.UUID `json:"todoId" db:"todo_id"`
-	UserID  string    `json:"userId" db:"user_id"`
-	Content string    `json:"content" db:"content"`
-}
\ No newline at end of file
+	TodoID          uuid.UUID  `json:"todoId" db:"todo_id"`
+	UserID          string     `json:"userId" db:"user_id"`
+	Content         string     `json:"content" db:"content"`
+	ContentHTML     string     `json:"contentHtml" db:"content_html"`
+	ParentCommentID *uuid.UUID `json:"parentCommentId" db:"parent_comment_id"`
+	EditedAt        *time.Time `json:"editedAt" db:"edited_at"`
+	DeletedAt       *time.Time `json:"deletedAt" db:"deleted_at"`
+	DeletedBy       *string    `json:"deletedBy" db:"deleted_by"`
+	HiddenAt        *time.Time `json:"hiddenAt" db:"hidden_at"`
+	HiddenBy        *string    `json:"hiddenBy" db:"hidden_by"`
+}
+
+// IsDeleted reports whether the comment is only kept as a placeholder for its replies
+func (c *Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// IsHidden reports whether a moderator collapsed the comment
+func (c *Comment) IsHidden() bool {
+	return c.HiddenAt != nil
+}
+
+// Redacted returns the comment as readers other than the todo's owners see it,
+// without the content of a hidden comment
+func (c *Comment) Redacted() *Comment {
+	redacted := *c
+	if redacted.IsHidden() {
+		redacted.Content = ""
+		redacted.ContentHTML = ""
+	}
+	return &redacted
+}
+
+// Revision is a version of a comment that an edit replaced; CreatedAt is when it was replaced
+type Revision struct {
+	model.BaseWithId
+	model.BaseWithCreatedAt
+	CommentID   uuid.UUID `json:"commentId" db:"comment_id"`
+	Content     string    `json:"content" db:"content"`
+	ContentHTML string    `json:"contentHtml" db:"content_html"`
+	EditedBy    string    `json:"editedBy" db:"edited_by"`
+}
+
+// PopulatedComment is a comment as listed in a thread, with its reactions as seen by the caller
+type PopulatedComment struct {
+	Comment
+	ReplyCount int               `json:"replyCount" db:"reply_count"`
+	Reactions  []ReactionSummary `json:"reactions" db:"reactions"`
+}
@@ -0,0 +1,40 @@
+package comment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedacted(t *testing.T) {
+	hiddenAt := time.Now()
+
+	tests := []struct {
+		name        string
+		comment     Comment
+		wantContent string
+		wantHTML    string
+	}{
+		{
+			name:        "visible comment keeps its content",
+			comment:     Comment{Content: "hello", ContentHTML: "<p>hello</p>"},
+			wantContent: "hello",
+			wantHTML:    "<p>hello</p>",
+		},
+		{
+			name:    "hidden comment loses its content",
+			comment: Comment{Content: "hello", ContentHTML: "<p>hello</p>", HiddenAt: &hiddenAt},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted := tt.comment.Redacted()
+			assert.Equal(t, tt.wantContent, redacted.Content)
+			assert.Equal(t, tt.wantHTML, redacted.ContentHTML)
+			assert.Equal(t, tt.comment.HiddenAt, redacted.HiddenAt)
+			assert.Equal(t, "hello", tt.comment.Content, "the original is left untouched")
+		})
+	}
+}
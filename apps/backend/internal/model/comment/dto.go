@@ -1,13 +1,18 @@
 package comment
 
 import (
+	"net/url"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/C0deNe0/go-tasker/internal/validation"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
 type AddCommentPayload struct {
-	TodoID  uuid.UUID `param:"todoId" validate:"required,uuid"`
-	Content string    `json:"content" validate:"required,min=1,max=1000"`
+	TodoID          uuid.UUID  `param:"todoId" validate:"required,uuid"`
+	Content         string     `json:"content" validate:"required,min=1,max=1000"`
+	ParentCommentID *uuid.UUID `json:"parentCommentId" validate:"omitempty,uuid"`
 }
 
 func (p *AddCommentPayload) Validate() error {
@@ -15,13 +20,55 @@ func (p *AddCommentPayload) Validate() error {
 	return validate.Struct(p)
 }
 
+// CommentPageQuery pages through a thread with the nextCursor of the previous page
+type CommentPageQuery struct {
+	Cursor *string `query:"cursor"`
+	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *CommentPageQuery) validate() error {
+	if q.Cursor != nil {
+		if _, _, err := model.DecodeCursor(*q.Cursor); err != nil {
+			return validation.CustomValidationErrors{
+				{Field: "cursor", Message: "is not a valid cursor"},
+			}
+		}
+	}
+
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
 type GetCommentByTodoIDPayload struct {
 	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	CommentPageQuery
 }
 
 func (p *GetCommentByTodoIDPayload) Validate() error {
 	validate := validator.New()
-	return validate.Struct(p)
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	return p.CommentPageQuery.validate()
+}
+
+type GetCommentRepliesPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+	CommentPageQuery
+}
+
+func (p *GetCommentRepliesPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	return p.CommentPageQuery.validate()
 }
 
 type UpdateCommentPayload struct {
@@ -42,3 +89,80 @@ func (p *DeleteCommentPayload) Validate() error {
 	validate := validator.New()
 	return validate.Struct(p)
 }
+
+type ModerateCommentPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *ModerateCommentPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetCommentRevisionsPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetCommentRevisionsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type AddReactionPayload struct {
+	ID    uuid.UUID `param:"id" validate:"required,uuid"`
+	Emoji string    `json:"emoji" validate:"required,max=32"`
+}
+
+func (p *AddReactionPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	if !IsEmoji(p.Emoji) {
+		return validation.CustomValidationErrors{
+			{Field: "emoji", Message: "must be a single emoji"},
+		}
+	}
+
+	return nil
+}
+
+type RemoveReactionPayload struct {
+	ID    uuid.UUID `param:"id" validate:"required,uuid"`
+	Emoji string    `param:"emoji" validate:"required,max=32"`
+}
+
+func (p *RemoveReactionPayload) Validate() error {
+	// path parameters arrive percent-encoded
+	if emoji, err := url.PathUnescape(p.Emoji); err == nil {
+		p.Emoji = emoji
+	}
+
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetMentionsQuery struct {
+	Page  *int `query:"page" validate:"omitempty,min=1"`
+	Limit *int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *GetMentionsQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
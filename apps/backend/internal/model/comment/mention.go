@@ -0,0 +1,58 @@
+package comment
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
+)
+
+// MaxMentions bounds how many users a single comment can notify
+const MaxMentions = 20
+
+// mentionPattern matches @username and @user@example.com handles that are not
+// part of a longer word, so plain email addresses in the text are not mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9][A-Za-z0-9._-]*(?:@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+)?)`)
+
+type Mention struct {
+	model.BaseWithCreatedAt
+	CommentID   uuid.UUID `json:"commentId" db:"comment_id"`
+	TodoID      uuid.UUID `json:"todoId" db:"todo_id"`
+	UserID      string    `json:"userId" db:"user_id"`
+	MentionedBy string    `json:"mentionedBy" db:"mentioned_by"`
+}
+
+// PopulatedMention is an entry of a user's mentions feed
+type PopulatedMention struct {
+	Mention
+	TodoTitle string  `json:"todoTitle" db:"todo_title"`
+	Comment   Comment `json:"comment" db:"comment"`
+}
+
+// ParseMentions returns the distinct handles mentioned in the content, lowercased
+// and in order of appearance. A handle containing an @ is an email address.
+func ParseMentions(content string) []string {
+	handles := []string{}
+	seen := map[string]bool{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], "._-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == MaxMentions {
+			break
+		}
+	}
+
+	return handles
+}
+
+// IsEmailHandle reports whether a parsed handle refers to an email address rather than a username
+func IsEmailHandle(handle string) bool {
+	return strings.Contains(handle, "@")
+}
@@ -0,0 +1,45 @@
+package comment
+
+import (
+	"unicode"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
+)
+
+type Reaction struct {
+	model.BaseWithCreatedAt
+	CommentID uuid.UUID `json:"commentId" db:"comment_id"`
+	UserID    string    `json:"userId" db:"user_id"`
+	Emoji     string    `json:"emoji" db:"emoji"`
+}
+
+// ReactionSummary counts the reactions with one emoji on a comment
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reactedByMe"`
+}
+
+// IsEmoji accepts a single emoji, including skin tone modifiers, ZWJ sequences,
+// flags and keycaps, and rejects words and shortcodes
+func IsEmoji(value string) bool {
+	hasSymbol := false
+	for _, r := range value {
+		switch {
+		case unicode.Is(unicode.So, r), unicode.Is(unicode.Regional_Indicator, r):
+			hasSymbol = true
+		case r == '⃣':
+			// combining enclosing keycap, as in 1️⃣
+			hasSymbol = true
+		case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Sk, r), r == '‍':
+			// variation selectors, skin tones and zero width joiners
+		case r == '#' || r == '*' || unicode.IsDigit(r):
+			// keycap bases
+		default:
+			return false
+		}
+	}
+
+	return hasSymbol
+}
@@ -0,0 +1,16 @@
+package markdown
+
+import "github.com/go-playground/validator/v10"
+
+type PreviewPayload struct {
+	Content string `json:"content" validate:"max=10000"`
+}
+
+func (p *PreviewPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type Preview struct {
+	HTML string `json:"html"`
+}
@@ -0,0 +1,95 @@
+package organization
+
+import (
+	"context"
+	"strings"
+
+	"github.com/C0deNe0/go-tasker/internal/model/share"
+)
+
+// RoleAdmin is Clerk's built-in organization admin role; admins manage every item in the org
+const RoleAdmin = "org:admin"
+
+const (
+	PermissionTodosRead       = "todos:read"
+	PermissionTodosWrite      = "todos:write"
+	PermissionCategoriesRead  = "categories:read"
+	PermissionCategoriesWrite = "categories:write"
+)
+
+// Membership is the caller's active Clerk organization, taken from the session claims.
+// A request without one works in the caller's personal workspace.
+type Membership struct {
+	OrganizationID string
+	Role           string
+	Permissions    []string
+}
+
+func (m *Membership) IsAdmin() bool {
+	return m.Role == RoleAdmin
+}
+
+// HasPermission accepts both the bare key ("todos:write") and Clerk's
+// namespaced form ("org:todos:write")
+func (m *Membership) HasPermission(permission string) bool {
+	if m.IsAdmin() {
+		return true
+	}
+
+	for _, granted := range m.Permissions {
+		if granted == permission || strings.TrimPrefix(granted, "org:") == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AccessRole maps the member's permissions on a resource onto the share roles
+// used for authorization, or "" when the member cannot see the resource
+func (m *Membership) AccessRole(readPermission, writePermission string) share.Role {
+	switch {
+	case m.IsAdmin():
+		return share.RoleOwner
+	case m.HasPermission(writePermission):
+		return share.RoleEditor
+	case m.HasPermission(readPermission):
+		return share.RoleViewer
+	default:
+		return ""
+	}
+}
+
+type contextKey struct{}
+
+func WithMembership(ctx context.Context, m *Membership) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromContext returns the active membership, or nil in the personal workspace
+func FromContext(ctx context.Context) *Membership {
+	m, _ := ctx.Value(contextKey{}).(*Membership)
+	return m
+}
+
+// WorkspaceID returns the active organization ID, or nil in the personal workspace
+func WorkspaceID(ctx context.Context) *string {
+	if m := FromContext(ctx); m != nil {
+		return &m.OrganizationID
+	}
+	return nil
+}
+
+// InWorkspace reports whether an item with the given organization ID belongs to
+// the workspace the request is working in
+func InWorkspace(ctx context.Context, organizationID *string) bool {
+	return SameWorkspace(WorkspaceID(ctx), organizationID)
+}
+
+// SameWorkspace reports whether two organization IDs name the same workspace
+func SameWorkspace(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,96 @@
+package share
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type CreateShareBody struct {
+	GranteeID *string `json:"granteeId" validate:"required_without=Email,omitempty,min=1"`
+	Email     *string `json:"email" validate:"required_without=GranteeID,omitempty,email"`
+	Role      Role    `json:"role" validate:"required,oneof=viewer commenter editor"`
+}
+
+type ShareCategoryPayload struct {
+	CategoryID uuid.UUID `param:"id" validate:"required,uuid"`
+	CreateShareBody
+}
+
+func (p *ShareCategoryPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type ShareTodoPayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	CreateShareBody
+}
+
+func (p *ShareTodoPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetCategorySharesPayload struct {
+	CategoryID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetCategorySharesPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetTodoSharesPayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetTodoSharesPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type UpdateSharePayload struct {
+	ID   uuid.UUID `param:"id" validate:"required,uuid"`
+	Role Role      `json:"role" validate:"required,oneof=viewer commenter editor"`
+}
+
+func (p *UpdateSharePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type DeleteSharePayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *DeleteSharePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type RespondToSharePayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *RespondToSharePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetSharedWithMeQuery struct {
+	Status *Status `query:"status" validate:"omitempty,oneof=pending accepted declined"`
+}
+
+func (q *GetSharedWithMeQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Status == nil {
+		defaultStatus := StatusAccepted
+		q.Status = &defaultStatus
+	}
+
+	return nil
+}
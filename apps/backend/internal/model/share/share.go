@@ -0,0 +1,65 @@
+package share
+
+import (
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/C0deNe0/go-tasker/internal/model/category"
+	"github.com/C0deNe0/go-tasker/internal/model/todo"
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleCommenter Role = "commenter"
+	RoleEditor    Role = "editor"
+	// RoleOwner is never stored on a share; it is reported for the item's owner
+	RoleOwner Role = "owner"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleCommenter:
+		return 2
+	case RoleEditor:
+		return 3
+	case RoleOwner:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Allows reports whether a holder of r may perform an action that requires the given role
+func (r Role) Allows(required Role) bool {
+	return r.rank() > 0 && r.rank() >= required.rank()
+}
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusAccepted Status = "accepted"
+	StatusDeclined Status = "declined"
+)
+
+type Share struct {
+	model.Base
+	OwnerID     string     `json:"ownerId" db:"owner_id"`
+	GranteeID   string     `json:"granteeId" db:"grantee_id"`
+	CategoryID  *uuid.UUID `json:"categoryId" db:"category_id"`
+	TodoID      *uuid.UUID `json:"todoId" db:"todo_id"`
+	Role        Role       `json:"role" db:"role"`
+	Status      Status     `json:"status" db:"status"`
+	RespondedAt *time.Time `json:"respondedAt" db:"responded_at"`
+}
+
+type PopulatedShare struct {
+	Share
+	Category *category.Category `json:"category" db:"category"`
+	Todo     *todo.Todo         `json:"todo" db:"todo"`
+}
@@ -0,0 +1,43 @@
+package todo
+
+import (
+	"io"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
+)
+
+type ArchiveStatus string
+
+const (
+	ArchiveStatusPending ArchiveStatus = "pending"
+	ArchiveStatusReady   ArchiveStatus = "ready"
+	ArchiveStatusFailed  ArchiveStatus = "failed"
+)
+
+// AttachmentArchive is a ZIP of a todo's attachments too large to stream, built by a job and kept
+// until it expires. Download is presigned when a ready archive is served.
+type AttachmentArchive struct {
+	model.Base
+	TodoID        *uuid.UUID             `json:"todoId" db:"todo_id"`
+	RequestedBy   string                 `json:"requestedBy" db:"requested_by"`
+	Name          string                 `json:"name" db:"name"`
+	AttachmentIDs []uuid.UUID            `json:"attachmentIds" db:"attachment_ids"`
+	Status        ArchiveStatus          `json:"status" db:"status"`
+	StorageKey    *string                `json:"-" db:"storage_key"`
+	FileSize      *int64                 `json:"fileSize" db:"file_size"`
+	FileCount     *int                   `json:"fileCount" db:"file_count"`
+	Error         *string                `json:"error" db:"error"`
+	CompletedAt   *time.Time             `json:"completedAt" db:"completed_at"`
+	ExpiresAt     time.Time              `json:"expiresAt" db:"expires_at"`
+	Download      *AttachmentDownloadURL `json:"download,omitempty" db:"-"`
+}
+
+// AttachmentArchiveDownload is either a ZIP streamed while Write produces it, or Archive when the
+// attachments were too large to stream and a job builds it instead
+type AttachmentArchiveDownload struct {
+	Name    string
+	Write   func(w io.Writer) error
+	Archive *AttachmentArchive
+}
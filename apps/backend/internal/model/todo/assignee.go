@@ -0,0 +1,13 @@
+package todo
+
+import (
+	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/google/uuid"
+)
+
+type TodoAssignee struct {
+	model.BaseWithCreatedAt
+	TodoID     uuid.UUID `json:"todoId" db:"todo_id"`
+	UserID     string    `json:"userId" db:"user_id"`
+	AssignedBy string    `json:"assignedBy" db:"assigned_by"`
+}
@@ -1,16 +1,313 @@
 package todo
 
 import (
+	"io"
+	"strings"
+	"time"
+
 	"github.com/C0deNe0/go-tasker/internal/model"
 	"github.com/google/uuid"
 )
 
+type ScanStatus string
+
+const (
+	ScanStatusPending     ScanStatus = "pending"
+	ScanStatusClean       ScanStatus = "clean"
+	ScanStatusQuarantined ScanStatus = "quarantined"
+)
+
+type AttachmentKind string
+
+const (
+	AttachmentKindFile AttachmentKind = "file"
+	AttachmentKindLink AttachmentKind = "link"
+)
+
+// TodoAttachment is a stored file, or a bookmarked URL when Kind is link. Links keep their title
+// in Name, have no object in storage and so no download key, size, type or checksum.
 type TodoAttachment struct {
 	model.Base
-	TodoID      uuid.UUID `json:"todoId" db:"todo_id"`
-	Name        string    `json:"name" db:"name"`
-	UploadedBy  string    `json:"uploadedBy" db:"uploaded_by"`
-	DownloadKey string    `json:"downloadKey" db:"download_key"`
-	FileSize    *int64    `json:"fileSize" db:"file_size"`
-	MimeType    *string   `json:"mimeType" db:"mime_type"`
+	TodoID        uuid.UUID      `json:"todoId" db:"todo_id"`
+	Kind          AttachmentKind `json:"kind" db:"kind"`
+	Name          string         `json:"name" db:"name"`
+	URL           *string        `json:"url" db:"url"`
+	Description   *string        `json:"description" db:"description"`
+	UploadedBy    string         `json:"uploadedBy" db:"uploaded_by"`
+	DownloadKey   string         `json:"downloadKey,omitempty" db:"download_key"`
+	FileSize      *int64         `json:"fileSize" db:"file_size"`
+	MimeType      *string        `json:"mimeType" db:"mime_type"`
+	Checksum      *string        `json:"checksum" db:"checksum"`
+	ScanStatus    ScanStatus     `json:"scanStatus" db:"scan_status"`
+	ScanSignature *string        `json:"scanSignature" db:"scan_signature"`
+	ScannedAt     *time.Time     `json:"scannedAt" db:"scanned_at"`
+	Width         *int           `json:"width" db:"width"`
+	Height        *int           `json:"height" db:"height"`
+	PageCount     *int           `json:"pageCount" db:"page_count"`
+	Thumbnails    []Thumbnail    `json:"thumbnails" db:"thumbnails"`
+	ProcessedAt   *time.Time     `json:"processedAt" db:"processed_at"`
+	Version       int            `json:"version" db:"version"`
+	VersionID     uuid.UUID      `json:"versionId" db:"version_id"`
+	VersionedAt   time.Time      `json:"versionedAt" db:"versioned_at"`
+	// ContentText is the text extracted from a document for search, ExtractedAt is set once it was tried
+	ContentText *string    `json:"-" db:"content_text"`
+	ExtractedAt *time.Time `json:"extractedAt" db:"extracted_at"`
+}
+
+// AttachmentMatch is an attachment whose extracted text matched a todo search, with the text around the match
+type AttachmentMatch struct {
+	TodoID       uuid.UUID `json:"-" db:"todo_id"`
+	AttachmentID uuid.UUID `json:"attachmentId" db:"attachment_id"`
+	Name         string    `json:"name" db:"name"`
+	Snippet      string    `json:"snippet" db:"snippet"`
+}
+
+// AttachmentVersion is one uploaded file of an attachment. The current version is the attachment
+// itself, earlier ones are kept until the retention limit pushes them out.
+type AttachmentVersion struct {
+	ID            uuid.UUID   `json:"id" db:"id"`
+	AttachmentID  uuid.UUID   `json:"attachmentId" db:"attachment_id"`
+	Version       int         `json:"version" db:"version"`
+	Current       bool        `json:"current" db:"current"`
+	CreatedAt     time.Time   `json:"createdAt" db:"created_at"`
+	ArchivedAt    *time.Time  `json:"archivedAt" db:"archived_at"`
+	Name          string      `json:"name" db:"name"`
+	UploadedBy    string      `json:"uploadedBy" db:"uploaded_by"`
+	DownloadKey   string      `json:"downloadKey" db:"download_key"`
+	FileSize      *int64      `json:"fileSize" db:"file_size"`
+	MimeType      *string     `json:"mimeType" db:"mime_type"`
+	Checksum      *string     `json:"checksum" db:"checksum"`
+	ScanStatus    ScanStatus  `json:"scanStatus" db:"scan_status"`
+	ScanSignature *string     `json:"scanSignature" db:"scan_signature"`
+	ScannedAt     *time.Time  `json:"scannedAt" db:"scanned_at"`
+	Width         *int        `json:"width" db:"width"`
+	Height        *int        `json:"height" db:"height"`
+	PageCount     *int        `json:"pageCount" db:"page_count"`
+	Thumbnails    []Thumbnail `json:"thumbnails" db:"thumbnails"`
+	ProcessedAt   *time.Time  `json:"processedAt" db:"processed_at"`
+}
+
+// AttachmentBlob is a stored object shared by every attachment version with the same SHA-256
+// checksum. The reference count is kept by the database, a blob at zero is released on cleanup.
+type AttachmentBlob struct {
+	Checksum   string    `json:"checksum" db:"checksum"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+	StorageKey string    `json:"storageKey" db:"storage_key"`
+	FileSize   int64     `json:"fileSize" db:"file_size"`
+	MimeType   string    `json:"mimeType" db:"mime_type"`
+	RefCount   int       `json:"refCount" db:"ref_count"`
+}
+
+// Thumbnail is a scaled down copy of an image attachment. URL is presigned when the attachment is served.
+type Thumbnail struct {
+	Size   string `json:"size"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Key    string `json:"key"`
+	URL    string `json:"url,omitempty"`
+}
+
+// AttachmentMedia is what the media job learned about an attachment, fields that do not apply stay nil
+type AttachmentMedia struct {
+	Width      *int
+	Height     *int
+	PageCount  *int
+	Thumbnails []Thumbnail
+}
+
+// AttachmentWithTodoTitle is an attachment loaded outside a request, with the title notifications need
+type AttachmentWithTodoTitle struct {
+	TodoAttachment
+	TodoTitle string `json:"todoTitle" db:"todo_title"`
+}
+
+const (
+	UploadMethodPut  = "PUT"
+	UploadMethodPost = "POST"
+)
+
+// PendingUpload is an upload handed to a client that has not been confirmed yet
+type PendingUpload struct {
+	model.BaseWithId
+	model.BaseWithCreatedAt
+	TodoID     *uuid.UUID `json:"todoId" db:"todo_id"`
+	UploadedBy string     `json:"uploadedBy" db:"uploaded_by"`
+	UploadKey  string     `json:"uploadKey" db:"upload_key"`
+	Name       string     `json:"name" db:"name"`
+	MimeType   string     `json:"mimeType" db:"mime_type"`
+	FileSize   int64      `json:"fileSize" db:"file_size"`
+	Method     string     `json:"method" db:"method"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	// AttachmentID is set when the upload becomes a new version of an existing attachment
+	AttachmentID *uuid.UUID `json:"attachmentId" db:"attachment_id"`
+}
+
+// AttachmentUpload tells the client where and how to send the file.
+// Headers must be sent with a PUT, Fields go before the file in a multipart POST.
+type AttachmentUpload struct {
+	UploadID  uuid.UUID         `json:"uploadId"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// AttachmentDownloadURL is a presigned link that fetches the file straight from storage.
+// For a link attachment it is the bookmarked URL itself, which does not expire.
+type AttachmentDownloadURL struct {
+	URL         string     `json:"url"`
+	Disposition string     `json:"disposition"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// AttachmentContent is an attachment opened for streaming through the API, the caller closes Body
+type AttachmentContent struct {
+	Attachment  *TodoAttachment
+	Disposition string
+	Body        io.ReadSeekCloser
+	Size        int64
+	ModifiedAt  time.Time
+}
+
+// Attachment types group media types for storage usage reporting
+const (
+	AttachmentTypeImage    = "image"
+	AttachmentTypeVideo    = "video"
+	AttachmentTypeAudio    = "audio"
+	AttachmentTypeDocument = "document"
+	AttachmentTypeArchive  = "archive"
+	AttachmentTypeOther    = "other"
+)
+
+var documentMimeTypes = map[string]bool{
+	"application/pdf":               true,
+	"application/msword":            true,
+	"application/rtf":               true,
+	"application/json":              true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+}
+
+var archiveMimeTypes = map[string]bool{
+	"application/zip":              true,
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/x-tar":            true,
+	"application/x-7z-compressed":  true,
+	"application/vnd.rar":          true,
+	"application/x-rar-compressed": true,
+}
+
+// AttachmentType returns which of the attachment types a media type belongs to
+func AttachmentType(mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return AttachmentTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return AttachmentTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return AttachmentTypeAudio
+	case archiveMimeTypes[mimeType]:
+		return AttachmentTypeArchive
+	case strings.HasPrefix(mimeType, "text/"), documentMimeTypes[mimeType],
+		strings.HasPrefix(mimeType, "application/vnd.openxmlformats-officedocument."),
+		strings.HasPrefix(mimeType, "application/vnd.oasis.opendocument."):
+		return AttachmentTypeDocument
+	default:
+		return AttachmentTypeOther
+	}
+}
+
+// StorageUsage is how much of their quota a user's attachments take up.
+// Reserved bytes belong to direct uploads that have been requested but not confirmed yet.
+// Earlier versions of attachments count towards used bytes as VersionBytes, the breakdowns only
+// cover current versions.
+type StorageUsage struct {
+	QuotaBytes     int64                    `json:"quotaBytes" db:"-"`
+	UsedBytes      int64                    `json:"usedBytes" db:"used_bytes"`
+	VersionBytes   int64                    `json:"versionBytes" db:"version_bytes"`
+	ReservedBytes  int64                    `json:"reservedBytes" db:"reserved_bytes"`
+	RemainingBytes int64                    `json:"remainingBytes" db:"-"`
+	FileCount      int64                    `json:"fileCount" db:"file_count"`
+	ByType         []StorageUsageByType     `json:"byType" db:"-"`
+	ByCategory     []StorageUsageByCategory `json:"byCategory" db:"-"`
+}
+
+// Remaining is what is left of the quota after used and reserved bytes, never negative
+func (u *StorageUsage) Remaining() int64 {
+	return max(u.QuotaBytes-u.UsedBytes-u.ReservedBytes, 0)
+}
+
+type StorageUsageByType struct {
+	Type      string `json:"type" db:"-"`
+	MimeType  string `json:"-" db:"mime_type"`
+	FileCount int64  `json:"fileCount" db:"file_count"`
+	Bytes     int64  `json:"bytes" db:"bytes"`
+}
+
+// StorageUsageByCategory groups usage by the category of the attachment's todo, nil when uncategorized
+type StorageUsageByCategory struct {
+	CategoryID   *uuid.UUID `json:"categoryId" db:"category_id"`
+	CategoryName *string    `json:"categoryName" db:"category_name"`
+	FileCount    int64      `json:"fileCount" db:"file_count"`
+	Bytes        int64      `json:"bytes" db:"bytes"`
+}
+
+// ContentType is the stored media type, or a generic binary type when none was recorded
+func (a *TodoAttachment) ContentType() string {
+	if a.MimeType == nil || *a.MimeType == "" {
+		return "application/octet-stream"
+	}
+
+	return *a.MimeType
+}
+
+// StorageKeys are the objects that belong to the attachment, the file and its thumbnails. Links have none.
+func (a *TodoAttachment) StorageKeys() []string {
+	if a.Kind == AttachmentKindLink {
+		return nil
+	}
+
+	return storageKeys(a.DownloadKey, a.Thumbnails)
+}
+
+// ContentType is the stored media type, or a generic binary type when none was recorded
+func (v *AttachmentVersion) ContentType() string {
+	if v.MimeType == nil || *v.MimeType == "" {
+		return "application/octet-stream"
+	}
+
+	return *v.MimeType
+}
+
+// StorageKeys are the objects that belong to the version, the file and its thumbnails
+func (v *AttachmentVersion) StorageKeys() []string {
+	return storageKeys(v.DownloadKey, v.Thumbnails)
+}
+
+func storageKeys(downloadKey string, thumbnails []Thumbnail) []string {
+	keys := []string{downloadKey}
+	for _, thumbnail := range thumbnails {
+		keys = append(keys, thumbnail.Key)
+	}
+
+	return keys
+}
+
+// StorageReconciliation summarizes one comparison of stored objects against attachment records.
+// Orphans are objects nothing points at, missing keys are records whose object is gone.
+// Released blobs had no references left, their objects count among the orphans.
+type StorageReconciliation struct {
+	Prefix         string   `json:"prefix"`
+	DryRun         bool     `json:"dryRun"`
+	ReleasedBlobs  int      `json:"releasedBlobs"`
+	ScannedObjects int      `json:"scannedObjects"`
+	OrphanedKeys   []string `json:"orphanedKeys"`
+	DeletedObjects int      `json:"deletedObjects"`
+	MissingKeys    []string `json:"missingKeys"`
 }
@@ -40,19 +40,23 @@ func (p *UpdateTodoPayload) Validate() error {
 }
 
 type GetTodosQuery struct {
-	Page         *int       `query:"page" validate:"omitempty,min=1"`
-	Limit        *int       `query:"limit" validate:"omitempty,min=1,max=100"`
-	Sort         *string    `query:"sort" validate:"omitempty,oneof=created_at updated_at title priority due_date"`
-	Order        *string    `query:"order" validate:"omitempty,oneof=asc desc"`
-	Search       *string    `query:"search" validate:"omitempty,min=1"`
-	Status       *Status    `query:"status" validate:"omitempty,oneof=draft active completed archived"`
-	Priority     *Priority  `query:"priority" validate:"omitempty,oneof=low medium high"`
-	CategoryID   *uuid.UUID `query:"categoryId" validate:"omitempty,uuid"`
-	ParentTodoID *uuid.UUID `query:"parentTodoId" validate:"omitempty,uuid"`
-	DueFrom      *time.Time `query:"dueFrom"`
-	DueTo        *time.Time `query:"dueTo"`
-	OverDue      *bool      `query:"overDue"`
-	Completed    *bool      `query:"completed"`
+	Page                      *int       `query:"page" validate:"omitempty,min=1"`
+	Limit                     *int       `query:"limit" validate:"omitempty,min=1,max=100"`
+	Sort                      *string    `query:"sort" validate:"omitempty,oneof=created_at updated_at title priority due_date"`
+	Order                     *string    `query:"order" validate:"omitempty,oneof=asc desc"`
+	Search                    *string    `query:"search" validate:"omitempty,min=1"`
+	Status                    *Status    `query:"status" validate:"omitempty,oneof=draft active completed archived"`
+	Priority                  *Priority  `query:"priority" validate:"omitempty,oneof=low medium high"`
+	CategoryID                *uuid.UUID `query:"categoryId" validate:"omitempty,uuid"`
+	IncludeSubcategories      *bool      `query:"includeSubcategories"`
+	ParentTodoID              *uuid.UUID `query:"parentTodoId" validate:"omitempty,uuid"`
+	DueFrom                   *time.Time `query:"dueFrom"`
+	DueTo                     *time.Time `query:"dueTo"`
+	OverDue                   *bool      `query:"overDue"`
+	Completed                 *bool      `query:"completed"`
+	Assignee                  *string    `query:"assignee" validate:"omitempty,min=1"`
+	Unassigned                *bool      `query:"unassigned"`
+	IncludeArchivedCategories *bool      `query:"includeArchivedCategories"`
 }
 
 func (q *GetTodosQuery) Validate() error {
@@ -84,6 +88,78 @@ func (q *GetTodosQuery) Validate() error {
 	return nil
 }
 
+type GetInboxQuery struct {
+	Page      *int    `query:"page" validate:"omitempty,min=1"`
+	Limit     *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Sort      *string `query:"sort" validate:"omitempty,oneof=created_at updated_at title priority due_date"`
+	Order     *string `query:"order" validate:"omitempty,oneof=asc desc"`
+	Status    *Status `query:"status" validate:"omitempty,oneof=draft active completed archived"`
+	Completed *bool   `query:"completed"`
+}
+
+func (q *GetInboxQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	// the most pressing work comes first
+	if q.Sort == nil {
+		defaultSort := "due_date"
+		q.Sort = &defaultSort
+	}
+
+	if q.Order == nil {
+		defaultOrder := "asc"
+		q.Order = &defaultOrder
+	}
+
+	return nil
+}
+
+type AssignTodoPayload struct {
+	TodoID  uuid.UUID `param:"id" validate:"required,uuid"`
+	UserIDs []string  `json:"userIds" validate:"required,min=1,max=20,dive,min=1"`
+}
+
+func (p *AssignTodoPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type UnassignTodoPayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	UserID string    `param:"userId" validate:"required,min=1"`
+}
+
+func (p *UnassignTodoPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ToggleDescriptionTaskPayload checks or unchecks one task list item of the
+// description, counted from zero in document order
+type ToggleDescriptionTaskPayload struct {
+	TodoID  uuid.UUID `param:"id" validate:"required,uuid"`
+	Index   int       `param:"index" validate:"min=0"`
+	Checked *bool     `json:"checked" validate:"required"`
+}
+
+func (p *ToggleDescriptionTaskPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
 type GetTodoByIDPayload struct {
 	ID uuid.UUID `param:"id" validate:"required,uuid"`
 }
@@ -122,6 +198,35 @@ func (p *UploadTodoAttachmentPayload) Validate() error {
 	return validate.Struct(p)
 }
 
+// StreamedBody leaves the multipart body unread so the file can be streamed to storage
+func (p *UploadTodoAttachmentPayload) StreamedBody() {}
+
+type GetTodoAttachmentsPayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetTodoAttachmentsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetTodoAttachmentPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+}
+
+func (p *GetTodoAttachmentPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetStorageUsagePayload struct {
+}
+
+func (p *GetStorageUsagePayload) Validate() error {
+	return nil
+}
+
 type DeleteTodoAttachmentPayload struct {
 	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
 	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
@@ -135,9 +240,148 @@ func (p *DeleteTodoAttachmentPayload) Validate() error {
 type GetAttachmentPresignedURLPayload struct {
 	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
 	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+	// ExpiresIn is the link lifetime in seconds, the configured default applies when it is unset
+	ExpiresIn   *int   `query:"expiresIn" validate:"omitempty,min=60"`
+	Disposition string `query:"disposition" validate:"omitempty,oneof=inline attachment"`
 }
 
 func (p *GetAttachmentPresignedURLPayload) Validate() error {
 	validate := validator.New()
+
+	if p.Disposition == "" {
+		p.Disposition = "attachment"
+	}
+
+	return validate.Struct(p)
+}
+
+type GetAttachmentContentPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+	Disposition  string    `query:"disposition" validate:"omitempty,oneof=inline attachment"`
+}
+
+func (p *GetAttachmentContentPayload) Validate() error {
+	validate := validator.New()
+
+	if p.Disposition == "" {
+		p.Disposition = "attachment"
+	}
+
+	return validate.Struct(p)
+}
+
+type UploadAttachmentVersionPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+}
+
+func (p *UploadAttachmentVersionPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetAttachmentVersionsPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+}
+
+func (p *GetAttachmentVersionsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetAttachmentVersionPresignedURLPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+	VersionID    uuid.UUID `param:"versionId" validate:"required,uuid"`
+	// ExpiresIn is the link lifetime in seconds, the configured default applies when it is unset
+	ExpiresIn   *int   `query:"expiresIn" validate:"omitempty,min=60"`
+	Disposition string `query:"disposition" validate:"omitempty,oneof=inline attachment"`
+}
+
+func (p *GetAttachmentVersionPresignedURLPayload) Validate() error {
+	validate := validator.New()
+
+	if p.Disposition == "" {
+		p.Disposition = "attachment"
+	}
+
+	return validate.Struct(p)
+}
+
+type RestoreAttachmentVersionPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+	VersionID    uuid.UUID `param:"versionId" validate:"required,uuid"`
+}
+
+func (p *RestoreAttachmentVersionPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetAttachmentArchivePayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	// AttachmentIDs limits the archive to these attachments, every downloadable one goes in when it is empty
+	AttachmentIDs   []uuid.UUID `query:"attachmentIds" validate:"omitempty,max=500"`
+	IncludeSubtasks bool        `query:"includeSubtasks"`
+}
+
+func (p *GetAttachmentArchivePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetAttachmentArchiveStatusPayload struct {
+	TodoID    uuid.UUID `param:"id" validate:"required,uuid"`
+	ArchiveID uuid.UUID `param:"archiveId" validate:"required,uuid"`
+}
+
+func (p *GetAttachmentArchiveStatusPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type CreateAttachmentUploadPayload struct {
+	TodoID   uuid.UUID `param:"id" validate:"required,uuid"`
+	Name     string    `json:"name" validate:"required,min=1,max=255"`
+	MimeType string    `json:"mimeType" validate:"required,max=255"`
+	FileSize int64     `json:"fileSize" validate:"required,min=1"`
+	Method   string    `json:"method" validate:"omitempty,oneof=PUT POST"`
+	// AttachmentID uploads a new version of an existing attachment instead of creating one
+	AttachmentID *uuid.UUID `json:"attachmentId" validate:"omitempty"`
+}
+
+func (p *CreateAttachmentUploadPayload) Validate() error {
+	validate := validator.New()
+
+	if p.Method == "" {
+		p.Method = UploadMethodPut
+	}
+
+	return validate.Struct(p)
+}
+
+type ConfirmAttachmentUploadPayload struct {
+	TodoID   uuid.UUID `param:"id" validate:"required,uuid"`
+	UploadID uuid.UUID `param:"uploadId" validate:"required,uuid"`
+}
+
+func (p *ConfirmAttachmentUploadPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type CreateAttachmentLinkPayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	URL    string    `json:"url" validate:"required,max=2048,http_url"`
+	// Title names the link in attachment lists, the URL itself is shown when it is unset
+	Title       *string `json:"title" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+}
+
+func (p *CreateAttachmentLinkPayload) Validate() error {
+	validate := validator.New()
 	return validate.Struct(p)
 }
@@ -28,17 +28,19 @@ const (
 
 type Todo struct {
 	model.Base
-	UserID       string     `json:"userId" db:"user_id"`
-	Title        string     `json:"title" db:"title"`
-	Description  *string    `json:"description" db:"description"`
-	Status       Status     `json:"status" db:"status"`
-	Priority     Priority   `json:"priority" db:"priority"`
-	DueDate      *time.Time `json:"dueDate" db:"due_date"`
-	CompletedAt  *time.Time `json:"completedAt" db:"completed_at"`
-	ParentTodoID *uuid.UUID `json:"parentTodoId" db:"parent_todo_id"`
-	CategoryID   *uuid.UUID `json:"categoryId" db:"category_id"`
-	MetaData     *MetaData  `json:"metaData" db:"metadata"`
-	SortOrder    int        `json:"sortOrder" db:"sort_order"`
+	UserID          string     `json:"userId" db:"user_id"`
+	OrganizationID  *string    `json:"organizationId" db:"organization_id"`
+	Title           string     `json:"title" db:"title"`
+	Description     *string    `json:"description" db:"description"`
+	DescriptionHTML *string    `json:"descriptionHtml" db:"description_html"`
+	Status          Status     `json:"status" db:"status"`
+	Priority        Priority   `json:"priority" db:"priority"`
+	DueDate         *time.Time `json:"dueDate" db:"due_date"`
+	CompletedAt     *time.Time `json:"completedAt" db:"completed_at"`
+	ParentTodoID    *uuid.UUID `json:"parentTodoId" db:"parent_todo_id"`
+	CategoryID      *uuid.UUID `json:"categoryId" db:"category_id"`
+	MetaData        *MetaData  `json:"metaData" db:"metadata"`
+	SortOrder       int        `json:"sortOrder" db:"sort_order"`
 }
 
 type MetaData struct {
@@ -54,6 +56,10 @@ type PopulatedTodo struct {
 	Children   []Todo             `json:"children" db:"children"`
 	Comments   []comment.Comment  `json:"comments" db:"comments"`
 	Attachment []TodoAttachment   `json:"attachments" db:"attachments"`
+	Assignees  []TodoAssignee     `json:"assignees" db:"assignees"`
+	AccessRole *string            `json:"accessRole" db:"access_role"`
+	// MatchedAttachments lists the attachments whose contents matched the search the todo was found by
+	MatchedAttachments []AttachmentMatch `json:"matchedAttachments,omitempty" db:"-"`
 }
 
 type TodoStats struct {
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
+	"github.com/jackc/pgx/v5"
+)
+
+// todoCategoryLineageSQL selects the category of the todo aliased t and every category above it,
+// so that a share of a category reaches everything nested in it
+const todoCategoryLineageSQL = `
+	WITH RECURSIVE lineage AS (
+		SELECT lc.id, lc.parent_category_id FROM todo_categories lc WHERE lc.id = t.category_id
+		UNION
+		SELECT lp.id, lp.parent_category_id FROM todo_categories lp JOIN lineage l ON lp.id = l.parent_category_id
+	)
+	SELECT id FROM lineage`
+
+// categoryLineageSQL is the todo_categories (aliased c) counterpart of todoCategoryLineageSQL
+const categoryLineageSQL = `
+	WITH RECURSIVE lineage AS (
+		SELECT lc.id, lc.parent_category_id FROM todo_categories lc WHERE lc.id = c.id
+		UNION
+		SELECT lp.id, lp.parent_category_id FROM todo_categories lp JOIN lineage l ON lp.id = l.parent_category_id
+	)
+	SELECT id FROM lineage`
+
+// todoMembershipSQL selects the role the memberships in @org_ids and @org_todo_roles
+// grant on the todo aliased t, evaluated against the todo's own organization
+const todoMembershipSQL = `
+	SELECT
+		m.role
+	FROM
+		UNNEST(@org_ids::TEXT[], @org_todo_roles::TEXT[]) m (org_id, role)
+	WHERE
+		m.org_id = t.organization_id
+		AND m.role <> ''`
+
+// todoAccessRoleSQL evaluates to the role @user_id holds on the todo aliased t,
+// or NULL when the todo is neither owned by nor shared with them. A todo is
+// reachable through membership of its organization or through an accepted share
+// of itself, of its parent or of its category or any category above it. The
+// strongest of these wins. Being assigned grants nothing on its own, assignees
+// keep the role they were given by one of these.
+const todoAccessRoleSQL = `
+	CASE
+		WHEN t.user_id = @user_id THEN 'owner'
+		ELSE (
+			SELECT
+				grants.role
+			FROM
+				(
+					` + todoMembershipSQL + `
+					UNION ALL
+					SELECT
+						s.role
+					FROM
+						todo_shares s
+					WHERE
+						s.grantee_id = @user_id
+						AND s.status = 'accepted'
+						AND (
+							s.todo_id = t.id
+							OR s.todo_id = t.parent_todo_id
+							OR s.category_id IN (` + todoCategoryLineageSQL + `)
+						)
+				) grants
+			ORDER BY
+				CASE grants.role WHEN 'owner' THEN 4 WHEN 'editor' THEN 3 WHEN 'commenter' THEN 2 ELSE 1 END DESC
+			LIMIT 1
+		)
+	END`
+
+// todoAccessSQL restricts the todos aliased t to those @user_id owns or holds one
+// of @access_roles on
+const todoAccessSQL = `(
+		t.user_id = @user_id
+		OR EXISTS (
+			SELECT
+				1
+			FROM
+				(` + todoMembershipSQL + `) m
+			WHERE
+				m.role = ANY(@access_roles::TEXT[])
+		)
+		OR EXISTS (
+			SELECT
+				1
+			FROM
+				todo_shares s
+			WHERE
+				s.grantee_id = @user_id
+				AND s.status = 'accepted'
+				AND s.role = ANY(@access_roles::TEXT[])
+				AND (
+					s.todo_id = t.id
+					OR s.todo_id = t.parent_todo_id
+					OR s.category_id IN (` + todoCategoryLineageSQL + `)
+				)
+		)
+	)`
+
+// visibleCommentSQL reads the todo_comments row com on the todo aliased t the way
+// @user_id may see it, for use as a LATERAL item. Hidden comments keep their content
+// only for the todo's owners, who moderate them.
+const visibleCommentSQL = `
+	jsonb_populate_record(
+		com,
+		CASE
+			WHEN com.hidden_at IS NULL
+			OR (` + todoAccessRoleSQL + `) = 'owner' THEN '{}'::JSONB
+			ELSE '{"content": "", "content_html": ""}'::JSONB
+		END
+	)`
+
+// categoryAccessRoleSQL is the todo_categories (aliased c) counterpart of todoAccessRoleSQL
+const categoryAccessRoleSQL = `
+	CASE
+		WHEN c.user_id = @user_id THEN 'owner'
+		WHEN c.organization_id = @org_id::TEXT
+		AND @org_category_role::TEXT <> '' THEN @org_category_role::TEXT
+		ELSE (
+			SELECT
+				s.role
+			FROM
+				todo_shares s
+			WHERE
+				s.grantee_id = @user_id
+				AND s.status = 'accepted'
+				AND s.category_id IN (` + categoryLineageSQL + `)
+			ORDER BY
+				CASE s.role WHEN 'owner' THEN 4 WHEN 'editor' THEN 3 WHEN 'commenter' THEN 2 ELSE 1 END DESC
+			LIMIT 1
+		)
+	END`
+
+// categoryAccessSQL is the todo_categories (aliased c) counterpart of todoAccessSQL
+const categoryAccessSQL = `(
+		c.user_id = @user_id
+		OR (
+			c.organization_id = @org_id::TEXT
+			AND @org_category_role::TEXT = ANY(@access_roles::TEXT[])
+		)
+		OR EXISTS (
+			SELECT
+				1
+			FROM
+				todo_shares s
+			WHERE
+				s.grantee_id = @user_id
+				AND s.status = 'accepted'
+				AND s.role = ANY(@access_roles::TEXT[])
+				AND s.category_id IN (` + categoryLineageSQL + `)
+		)
+	)`
+
+// workspaceSQL restricts the rows of the given alias to the workspace of @org_id,
+// which is NULL for the personal workspace
+func workspaceSQL(alias string) string {
+	return alias + ".organization_id IS NOT DISTINCT FROM @org_id::TEXT"
+}
+
+// withAccess adds the arguments used by the access fragments above, taking the
+// caller's organization membership from the request context
+func withAccess(ctx context.Context, args pgx.NamedArgs, userID string, required share.Role) pgx.NamedArgs {
+	roles := []string{}
+	for _, role := range []share.Role{share.RoleViewer, share.RoleCommenter, share.RoleEditor, share.RoleOwner} {
+		if role.Allows(required) {
+			roles = append(roles, string(role))
+		}
+	}
+
+	var orgID *string
+	categoryRole := ""
+	memberships := []organization.Membership{}
+	if membership := organization.FromContext(ctx); membership != nil {
+		orgID = &membership.OrganizationID
+		categoryRole = string(membership.AccessRole(organization.PermissionCategoriesRead, organization.PermissionCategoriesWrite))
+		memberships = append(memberships, *membership)
+	}
+
+	args["user_id"] = userID
+	args["access_roles"] = roles
+	args["org_id"] = orgID
+	args["org_category_role"] = categoryRole
+	return withMemberships(args, memberships)
+}
+
+// withMemberships lets the todo access fragments above reach todos through each of
+// the given organization memberships instead of only the active one
+func withMemberships(args pgx.NamedArgs, memberships []organization.Membership) pgx.NamedArgs {
+	orgIDs := make([]string, 0, len(memberships))
+	todoRoles := make([]string, 0, len(memberships))
+	for _, membership := range memberships {
+		orgIDs = append(orgIDs, membership.OrganizationID)
+		todoRoles = append(todoRoles, string(membership.AccessRole(organization.PermissionTodosRead, organization.PermissionTodosWrite)))
+	}
+
+	args["org_ids"] = orgIDs
+	args["org_todo_roles"] = todoRoles
+	return args
+}
+
+// checkAccess turns a resolved role into the error the caller should see: items
+// the user cannot see at all are reported as missing, the rest as forbidden
+func checkAccess(role *share.Role, required share.Role, notFoundCode string, entity string) error {
+	if role == nil {
+		return errs.NewNotFoundError(entity+" not found", false, &notFoundCode)
+	}
+
+	if !role.Allows(required) {
+		return errs.NewForbiddenError("you need "+string(required)+" access to this "+entity, false)
+	}
+
+	return nil
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/C0deNe0/go-tasker/internal/errs"
 	"github.com/C0deNe0/go-tasker/internal/model"
 	"github.com/C0deNe0/go-tasker/internal/model/category"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -26,16 +30,19 @@ func NewCategoryRepository(server *server.Server) *CategoryRepository {
 func (r *CategoryRepository) CreateCategory(ctx context.Context, userID string, payload *category.CreateCategoryPayload) (*category.Category, error) {
 	stmt := `
 	
-	INSERT INTO todo_categories (user_id, name, color, description)
-	VALUES (@user_id, @name, @color, @description)
+	INSERT INTO todo_categories (user_id, organization_id, name, color, description, parent_category_id, position)
+	VALUES (@user_id, @organization_id, @name, @color, @description, @parent_category_id,
+		` + nextCategoryPositionSQL("@organization_id::TEXT", "@user_id") + `)
 	RETURNING *;
 	`
 
 	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id":     userID,
-		"name":        payload.Name,
-		"color":       payload.Color,
-		"description": payload.Description,
+		"user_id":            userID,
+		"organization_id":    organization.WorkspaceID(ctx),
+		"name":               payload.Name,
+		"color":              payload.Color,
+		"description":        payload.Description,
+		"parent_category_id": payload.ParentCategoryID,
 	})
 
 	if err != nil {
@@ -49,38 +56,109 @@ func (r *CategoryRepository) CreateCategory(ctx context.Context, userID string,
 	return &categoryItem, nil
 }
 
-func (r *CategoryRepository) GetCategoryByID(ctx context.Context, userID string, categoryID uuid.UUID) (*category.Category, error) {
+// categoryDescendantsSQL selects the ids of category @category_id and of its subcategories at any depth
+const categoryDescendantsSQL = `
+	WITH RECURSIVE descendants AS (
+		SELECT id FROM todo_categories WHERE id = @category_id
+		UNION ALL
+		SELECT sub.id FROM todo_categories sub JOIN descendants d ON sub.parent_category_id = d.id
+	)
+	SELECT id FROM descendants`
+
+// unarchivedCategorySQL keeps todos t whose category is not archived, uncategorized todos included
+const unarchivedCategorySQL = `NOT EXISTS (SELECT 1 FROM todo_categories ac WHERE ac.id = t.category_id AND ac.archived_at IS NOT NULL)`
+
+// nextCategoryPositionSQL evaluates to the position after the last sibling under @parent_category_id
+// in the workspace of the given organization, which for the personal workspace is the given user's
+func nextCategoryPositionSQL(orgID, userID string) string {
+	return `(
+		SELECT COALESCE(MAX(sib.position) + 1, 0) FROM todo_categories sib
+		WHERE sib.parent_category_id IS NOT DISTINCT FROM @parent_category_id::UUID
+		AND sib.organization_id IS NOT DISTINCT FROM ` + orgID + `
+		AND (sib.organization_id IS NOT NULL OR sib.user_id = ` + userID + `)
+	)`
+}
+
+// categoryStatsSQL counts the todos of category c by status as a JSON object. Like the todo listings
+// it only counts todos the caller can see, which takes the todoAccessSQL arguments.
+const categoryStatsSQL = `(
+		SELECT
+			JSONB_BUILD_OBJECT(
+				'total', COUNT(*),
+				'draft', COUNT(*) FILTER (WHERE t.status = 'draft'),
+				'active', COUNT(*) FILTER (WHERE t.status = 'active'),
+				'completed', COUNT(*) FILTER (WHERE t.status = 'completed'),
+				'archived', COUNT(*) FILTER (WHERE t.status = 'archived'),
+				'overdue', COUNT(*) FILTER (WHERE t.due_date < NOW() AND t.status NOT IN ('completed', 'archived')),
+				'completionPercent', COALESCE(
+					ROUND(
+						COUNT(*) FILTER (WHERE t.status = 'completed') * 100.0
+						/ NULLIF(COUNT(*) FILTER (WHERE t.status != 'archived'), 0),
+						1
+					),
+					0
+				)
+			)
+		FROM
+			todos t
+		WHERE
+			t.category_id = c.id
+			AND ` + todoAccessSQL + `
+	)`
+
+// accessibleCategory is a category together with the role the caller holds on it
+type accessibleCategory struct {
+	category.Category
+	AccessRole *share.Role `db:"access_role"`
+}
+
+// GetCategoryByID returns the category if the user holds at least the required role on it
+func (r *CategoryRepository) GetCategoryByID(ctx context.Context, userID string, categoryID uuid.UUID, required share.Role) (*category.Category, error) {
 	stmt := `
-	SELECT * FROM todo_categories
-	WHERE id = @id AND user_id = @user_id;
+	SELECT
+		c.*,
+		` + categoryAccessRoleSQL + ` AS access_role
+	FROM todo_categories c
+	WHERE c.id = @id;
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      categoryID,
-		"user_id": userID,
-	})
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": categoryID,
+	}, userID, required))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute get category by id query for user_id=%s, category_id=%s: %w", userID, categoryID, err)
 	}
 
-	categoryItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[category.Category])
+	categoryItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[accessibleCategory])
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, checkAccess(nil, required, "CATEGORY_NOT_FOUND", "category")
+		}
 		return nil, fmt.Errorf("failed to collect category for user_id=%s, category_id=%s: %w", userID, categoryID, err)
 	}
-	return &categoryItem, nil
+
+	if err := checkAccess(categoryItem.AccessRole, required, "CATEGORY_NOT_FOUND", "category"); err != nil {
+		return nil, err
+	}
+
+	return &categoryItem.Category, nil
 }
 
-func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, query *category.GetCategoriesQuery) (*model.PaginatedResponse[category.Category], error) {
+// GetCategories lists the categories of the current workspace the user can see with the counts of
+// their todos. Archived categories are left out unless the query includes them.
+func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, query *category.GetCategoriesQuery) (*model.PaginatedResponse[category.CategoryWithStats], error) {
 	stmt := `
-	SELECT * FROM todo_categories
-	WHERE user_id = @user_id
-	`
-	args := pgx.NamedArgs{
-		"user_id": userID,
+	SELECT c.*, ` + categoryStatsSQL + ` AS stats FROM todo_categories c
+	WHERE ` + workspaceSQL("c") + ` AND ` + categoryAccessSQL
+	args := withAccess(ctx, pgx.NamedArgs{}, userID, share.RoleViewer)
+
+	includeArchived := query.IncludeArchived != nil && *query.IncludeArchived
+	if !includeArchived {
+		stmt += " AND c.archived_at IS NULL "
 	}
 
 	if query.Search != nil {
-		stmt += " AND name ILIKE '%' || @search || '%' "
+		stmt += " AND c.name ILIKE '%' || @search || '%' "
 		args["search"] = *query.Search
 	}
 
@@ -94,7 +172,11 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, q
 		sortOrder = "DESC"
 	}
 
-	stmt += fmt.Sprintf(" ORDER BY %s %s ", sortColumn, sortOrder)
+	stmt += fmt.Sprintf(" ORDER BY c.%s %s ", sortColumn, sortOrder)
+	// siblings under different parents share positions, the name keeps their order stable
+	if sortColumn == "position" {
+		stmt += ", c.name ASC "
+	}
 
 	stmt += ` LIMIT @limit OFFSET @offset`
 	args["limit"] = query.Limit
@@ -105,11 +187,11 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, q
 		return nil, fmt.Errorf("failed to execute get categories query for user_id=%s: %w", userID, err)
 	}
 
-	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[category.Category])
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[category.CategoryWithStats])
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return &model.PaginatedResponse[category.Category]{
-				Data:       []category.Category{},
+			return &model.PaginatedResponse[category.CategoryWithStats]{
+				Data:       []category.CategoryWithStats{},
 				Page:       *query.Page,
 				Limit:      *query.Limit,
 				Total:      0,
@@ -121,15 +203,16 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, q
 
 	countStmt := `
 	
-	SELECT COUNT(*) FROM todo_categories
-	WHERE user_id = @user_id
-	`
-	countArgs := pgx.NamedArgs{
-		"user_id": userID,
+	SELECT COUNT(*) FROM todo_categories c
+	WHERE ` + workspaceSQL("c") + ` AND ` + categoryAccessSQL
+	countArgs := withAccess(ctx, pgx.NamedArgs{}, userID, share.RoleViewer)
+
+	if !includeArchived {
+		countStmt += " AND c.archived_at IS NULL "
 	}
 
 	if query.Search != nil {
-		countStmt += " AND name ILIKE '%' || @search || '%' "
+		countStmt += " AND c.name ILIKE '%' || @search || '%' "
 		countArgs["search"] = *query.Search
 	}
 	var total int
@@ -139,7 +222,7 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, q
 		return nil, fmt.Errorf("failed to count categories for user_id=%s: %w", userID, err)
 	}
 
-	return &model.PaginatedResponse[category.Category]{
+	return &model.PaginatedResponse[category.CategoryWithStats]{
 		Data:       categories,
 		Page:       *query.Page,
 		Limit:      *query.Limit,
@@ -147,14 +230,191 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string, q
 		TotalPages: (total + *query.Limit - 1) / *query.Limit,
 	}, nil
 }
+
+// GetCategoryTree lists every category of the current workspace the user can see, ordered by position
+// so that siblings keep that order once they are nested. Archived ones are only listed when asked for.
+func (r *CategoryRepository) GetCategoryTree(ctx context.Context, userID string, includeArchived bool) ([]category.Category, error) {
+	stmt := `
+	SELECT c.* FROM todo_categories c
+	WHERE ` + workspaceSQL("c") + ` AND ` + categoryAccessSQL + `
+	AND (@include_archived OR c.archived_at IS NULL)
+	ORDER BY c.position ASC, c.name ASC`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"include_archived": includeArchived,
+	}, userID, share.RoleViewer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get category tree query for user_id=%s: %w", userID, err)
+	}
+
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[category.Category])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect categories for user_id=%s: %w", userID, err)
+	}
+
+	return categories, nil
+}
+
+// MoveCategory puts the category last under another parent, nil for the top level. Like UpdateCategory
+// it is reserved for the owner and organization admins. A parent that is the category itself or one
+// of its subcategories would cut the subtree off into a cycle and is refused.
+func (r *CategoryRepository) MoveCategory(ctx context.Context, userID string, categoryID uuid.UUID, parentID *uuid.UUID) (*category.Category, error) {
+	stmt := `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_category_id FROM todo_categories WHERE id = @parent_category_id
+		UNION
+		SELECT p.id, p.parent_category_id FROM todo_categories p JOIN ancestors a ON p.id = a.parent_category_id
+	)
+	UPDATE todo_categories c
+	SET parent_category_id = @parent_category_id,
+		position = ` + nextCategoryPositionSQL("c.organization_id", "c.user_id") + `
+	WHERE c.id = @id
+	AND NOT EXISTS (SELECT 1 FROM ancestors WHERE ancestors.id = c.id)
+	AND ` + categoryAccessSQL + `
+	RETURNING c.*`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id":                 categoryID,
+		"parent_category_id": parentID,
+	}, userID, share.RoleOwner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute move category query for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+	}
+
+	categoryItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[category.Category])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "CATEGORY_CYCLE"
+			return nil, errs.NewBadRequestError("a category cannot be moved under itself or one of its subcategories", true, &code, nil, nil)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_categories for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+	}
+
+	return &categoryItem, nil
+}
+
+// ReorderCategories numbers the siblings under the parent in the listed order, followed by the ones not
+// listed in their previous order. Every listed category has to be one of those siblings that the user
+// may edit. Like moves it is reserved for the owner and organization admins.
+func (r *CategoryRepository) ReorderCategories(ctx context.Context, userID string, parentID *uuid.UUID, categoryIDs []uuid.UUID) ([]category.Category, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reorder categories transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// moves wait on the same lock, so the siblings stay the same until the commit
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('todo_categories_tree'))`); err != nil {
+		return nil, fmt.Errorf("failed to lock category tree: %w", err)
+	}
+
+	args := withAccess(ctx, pgx.NamedArgs{
+		"parent_category_id": parentID,
+		"category_ids":       categoryIDs,
+	}, userID, share.RoleOwner)
+
+	siblingsSQL := `
+		c.parent_category_id IS NOT DISTINCT FROM @parent_category_id::UUID
+		AND ` + workspaceSQL("c") + `
+		AND ` + categoryAccessSQL
+
+	var listed int
+	if err := tx.QueryRow(ctx, `
+	SELECT COUNT(*) FROM todo_categories c
+	WHERE c.id = ANY(@category_ids) AND `+siblingsSQL, args).Scan(&listed); err != nil {
+		return nil, fmt.Errorf("failed to check categories to reorder for user_id=%s: %w", userID, err)
+	}
+	if listed != len(categoryIDs) {
+		code := "INVALID_CATEGORY_ORDER"
+		return nil, errs.NewBadRequestError("every category to reorder must be a subcategory of the parent that you can edit", true, &code, nil, nil)
+	}
+
+	rows, err := tx.Query(ctx, `
+	WITH
+		listed AS (
+			SELECT id, ord FROM UNNEST(@category_ids::UUID[]) WITH ORDINALITY AS l(id, ord)
+		),
+		ordered AS (
+			SELECT
+				c.id,
+				ROW_NUMBER() OVER (ORDER BY listed.ord ASC NULLS LAST, c.position ASC, c.name ASC) - 1 AS position
+			FROM todo_categories c
+			LEFT JOIN listed ON listed.id = c.id
+			WHERE `+siblingsSQL+`
+		)
+	UPDATE todo_categories c
+	SET position = ordered.position
+	FROM ordered
+	WHERE c.id = ordered.id
+	RETURNING c.*`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute reorder categories query for user_id=%s: %w", userID, err)
+	}
+
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[category.Category])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_categories for user_id=%s: %w", userID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit reorder categories transaction: %w", err)
+	}
+
+	slices.SortFunc(categories, func(a, b category.Category) int {
+		return a.Position - b.Position
+	})
+
+	return categories, nil
+}
+
+// SetCategoryArchived archives or restores the category together with its subcategories. Archiving
+// keeps the time a subcategory was archived on its own, so restoring only brings back the
+// subcategories archived along with the category. It is reserved for the owner and organization admins.
+func (r *CategoryRepository) SetCategoryArchived(ctx context.Context, userID string, categoryID uuid.UUID, archived bool) (*category.Category, error) {
+	stmt := `
+	UPDATE todo_categories c
+	SET archived_at = CASE WHEN @archived THEN COALESCE(c.archived_at, NOW()) END
+	WHERE c.id IN (` + categoryDescendantsSQL + `)
+	AND (
+		@archived
+		OR c.id = @category_id
+		OR c.archived_at = (SELECT root.archived_at FROM todo_categories root WHERE root.id = @category_id)
+	)
+	AND ` + categoryAccessSQL + `
+	RETURNING c.*`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"category_id": categoryID,
+		"archived":    archived,
+	}, userID, share.RoleOwner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute archive category query for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+	}
+
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[category.Category])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_categories for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+	}
+
+	for i := range categories {
+		if categories[i].ID == categoryID {
+			return &categories[i], nil
+		}
+	}
+
+	code := "CATEGORY_NOT_FOUND"
+	return nil, errs.NewNotFoundError("category not found", false, &code)
+}
+
+// UpdateCategory is reserved for the owner and organization admins; shares grant
+// access to the todos, not the category itself
 func (r *CategoryRepository) UpdateCategory(ctx context.Context, userID string,
 	categoryID uuid.UUID, payload *category.UpdateCategoryPayload,
 ) (*category.Category, error) {
-	stmt := `UPDATE todo_categories SET `
-	args := pgx.NamedArgs{
-		"id":      categoryID,
-		"user_id": userID,
-	}
+	stmt := `UPDATE todo_categories c SET `
+	args := withAccess(ctx, pgx.NamedArgs{
+		"id": categoryID,
+	}, userID, share.RoleOwner)
 	setClauses := []string{}
 
 	if payload.Name != nil {
@@ -175,7 +435,7 @@ func (r *CategoryRepository) UpdateCategory(ctx context.Context, userID string,
 	}
 
 	stmt += strings.Join(setClauses, ", ")
-	stmt += ` WHERE id = @id AND user_id = @user_id RETURNING *`
+	stmt += ` WHERE c.id = @id AND ` + categoryAccessSQL + ` RETURNING c.*`
 
 	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
 	if err != nil {
@@ -190,21 +450,179 @@ func (r *CategoryRepository) UpdateCategory(ctx context.Context, userID string,
 	return &categoryItem, nil
 }
 
-func (r *CategoryRepository) DeleteCategory(ctx context.Context, userID string, categroyID uuid.UUID) error {
-	result, err := r.server.DB.Pool.Exec(ctx, `
-		DELETE FROM todo_categories
-		WHERE id=@id AND  user_id=@user_id
-		`, pgx.NamedArgs{
-		"id":      categroyID,
-		"user_id": userID,
-	})
+// DeleteCategory removes the category with its subcategories in one transaction, first moving their
+// todos to the target category, leaving them uncategorized or deleting them with their subtasks. It is
+// reserved for the owner and organization admins like UpdateCategory, so cascading also removes todos of
+// other members filed under the category. The storage keys of deleted attachments are returned for cleanup.
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, userID string, categoryID uuid.UUID,
+	strategy category.DeleteStrategy, targetID *uuid.UUID,
+) (*category.DeleteCategoryResult, []string, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin delete category transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// moves wait on the same lock, so the subtree stays as it is until the commit
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('todo_categories_tree'))`); err != nil {
+		return nil, nil, fmt.Errorf("failed to lock category tree: %w", err)
+	}
 
+	rows, err := tx.Query(ctx, `
+	WITH RECURSIVE descendants AS (
+		SELECT c.id FROM todo_categories c WHERE c.id = @category_id AND `+categoryAccessSQL+`
+		UNION ALL
+		SELECT sub.id FROM todo_categories sub JOIN descendants d ON sub.parent_category_id = d.id
+	)
+	SELECT id FROM descendants`, withAccess(ctx, pgx.NamedArgs{
+		"category_id": categoryID,
+	}, userID, share.RoleOwner))
 	if err != nil {
-		return fmt.Errorf("failed to delete category: %w", err)
+		return nil, nil, fmt.Errorf("failed to get subcategories of category_id=%s: %w", categoryID.String(), err)
 	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("row not found")
+
+	categoryIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect rows from table:todo_categories: %w", err)
+	}
+	if len(categoryIDs) == 0 {
+		code := "CATEGORY_NOT_FOUND"
+		return nil, nil, errs.NewNotFoundError("category not found", false, &code)
+	}
+
+	result := &category.DeleteCategoryResult{
+		CategoryID:        categoryID,
+		Strategy:          strategy,
+		DeletedCategories: len(categoryIDs),
+		TodoIDs:           []uuid.UUID{},
+	}
+	args := pgx.NamedArgs{
+		"category_ids": categoryIDs,
+	}
+
+	var storageKeys []string
+	switch strategy {
+	case category.DeleteStrategyReassign:
+		if targetID == nil || slices.Contains(categoryIDs, *targetID) {
+			code := "INVALID_TARGET_CATEGORY"
+			return nil, nil, errs.NewBadRequestError("the target category is deleted together with the category", true, &code, nil, nil)
+		}
+		result.TargetCategoryID = targetID
+		args["target_id"] = *targetID
+
+		rows, err = tx.Query(ctx, `
+		UPDATE todos
+		SET category_id = @target_id
+		WHERE category_id = ANY(@category_ids)
+		RETURNING id`, args)
+
+	case category.DeleteStrategyUncategorize:
+		rows, err = tx.Query(ctx, `
+		UPDATE todos
+		SET category_id = NULL
+		WHERE category_id = ANY(@category_ids)
+		RETURNING id`, args)
+
+	case category.DeleteStrategyCascade:
+		// the keys are read before the attachments go with their todos
+		storageKeys, err = r.getCascadeStorageKeys(ctx, tx, args)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rows, err = tx.Query(ctx, `
+		DELETE FROM todos t
+		WHERE t.category_id = ANY(@category_ids)
+		OR t.parent_todo_id IN (SELECT id FROM todos WHERE category_id = ANY(@category_ids))
+		RETURNING t.id`, args)
+
+	default:
+		return nil, nil, fmt.Errorf("unknown category delete strategy %q", strategy)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to %s todos of category_id=%s: %w", strategy, categoryID.String(), err)
+	}
+
+	todoIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect rows from table:todos: %w", err)
+	}
+	result.TodoIDs = append(result.TodoIDs, todoIDs...)
+	result.AffectedTodos = len(todoIDs)
+
+	// subcategories and shares of them go with the category
+	if _, err := tx.Exec(ctx, `DELETE FROM todo_categories WHERE id = @category_id`, pgx.NamedArgs{
+		"category_id": categoryID,
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit delete category transaction: %w", err)
+	}
+
+	return result, storageKeys, nil
+}
+
+// getCascadeStorageKeys returns the storage keys of the attachments, their versions and thumbnails
+// on the todos of @category_ids and on their subtasks
+func (r *CategoryRepository) getCascadeStorageKeys(ctx context.Context, tx pgx.Tx, args pgx.NamedArgs) ([]string, error) {
+	stmt := `
+		WITH
+			doomed AS (
+				SELECT
+					t.id
+				FROM
+					todos t
+				WHERE
+					t.category_id = ANY(@category_ids)
+					OR t.parent_todo_id IN (
+						SELECT
+							id
+						FROM
+							todos
+						WHERE
+							category_id = ANY(@category_ids)
+					)
+			)
+		SELECT
+			COALESCE(ARRAY_AGG(k.key), '{}')
+		FROM
+			(
+				SELECT
+					att.download_key,
+					att.thumbnails
+				FROM
+					todo_attachments att
+				WHERE
+					att.todo_id IN (SELECT id FROM doomed)
+				UNION ALL
+				SELECT
+					v.download_key,
+					v.thumbnails
+				FROM
+					todo_attachment_versions v
+					JOIN todo_attachments att ON att.id = v.attachment_id
+				WHERE
+					att.todo_id IN (SELECT id FROM doomed)
+			) f
+			CROSS JOIN LATERAL (
+				SELECT
+					f.download_key AS key
+				UNION ALL
+				SELECT
+					thumb ->> 'key'
+				FROM
+					JSONB_ARRAY_ELEMENTS(f.thumbnails) thumb
+			) k
+		WHERE
+			k.key <> ''
+	`
+
+	var keys []string
+	if err := tx.QueryRow(ctx, stmt, args).Scan(&keys); err != nil {
+		return nil, fmt.Errorf("failed to get storage keys of category todos: %w", err)
 	}
 
-	return nil
+	return keys, nil
 }
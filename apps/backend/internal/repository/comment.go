@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/lib/markdown"
+	"github.com/C0deNe0/go-tasker/internal/model"
 	"github.com/C0deNe0/go-tasker/internal/model/comment"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
 	"github.com/C0deNe0/go-tasker/internal/server"
 )
 
@@ -20,34 +26,53 @@ func NewCommentRepository(server *server.Server) *CommentRepository {
 	}
 }
 
-func (r *CommentRepository) AddComment(ctx context.Context, userID string, todoID uuid.UUID, payload *comment.AddCommentPayload) (*comment.Comment, error) {
+// AddComment only inserts when the user may comment on the todo. Replies are
+// attached to parentCommentID, which the caller resolves to the thread's root.
+func (r *CommentRepository) AddComment(ctx context.Context, userID string, todoID uuid.UUID, parentCommentID *uuid.UUID, payload *comment.AddCommentPayload) (*comment.Comment, error) {
 	stmt := `
 		INSERT INTO 
 			todo_comments (
 				todo_id,
 				user_id,
-				content
+				content,
+				content_html,
+				parent_comment_id
 			)
-			VALUES
-			(
-				@todo_id,
-				@user_id,
-				@content
-			)
-			RETURNING *
+		SELECT
+			t.id,
+			@user_id,
+			@content,
+			@content_html,
+			@parent_comment_id
+		FROM
+			todos t
+		WHERE
+			t.id = @todo_id
+			AND ` + todoAccessSQL + `
+		RETURNING *
 	`
 
-	rows, err := r.Server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"todo_id": todoID,
-		"user_id": userID,
-		"content": payload.Content,
-	})
+	contentHTML, err := markdown.Render(payload.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render comment for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"todo_id":           todoID,
+		"content":           payload.Content,
+		"content_html":      contentHTML,
+		"parent_comment_id": parentCommentID,
+	}, userID, share.RoleCommenter))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute add comment query for todo_id=%s: %w", todoID.String(), err)
 	}
 
 	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TODO_NOT_FOUND"
+			return nil, errs.NewNotFoundError("todo not found", false, &code)
+		}
 		return nil, fmt.Errorf("failed to collect comment row for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
 	}
 
@@ -55,85 +80,237 @@ func (r *CommentRepository) AddComment(ctx context.Context, userID string, todoI
 
 }
 
-func (r *CommentRepository) GetCommentsByTodoID(ctx context.Context, userID string, todoID uuid.UUID) ([]comment.Comment, error) {
-	stmt := `
-		SELECT *
-		FROM todo_comments
-		WHERE todo_id = @todo_id
-		AND user_id = @user_id
-		ORDER BY 
-			created_at ASC
-	`
+// populatedCommentSelect selects the comments aliased com on todos aliased t as
+// @user_id may see them, with their reply count and their reactions grouped by emoji
+const populatedCommentSelect = `
+	SELECT
+		visible.*,
+		(
+			SELECT
+				COUNT(*)
+			FROM
+				todo_comments reply
+			WHERE
+				reply.parent_comment_id = com.id
+		) AS reply_count,
+		COALESCE(
+			(
+				SELECT
+					jsonb_agg(
+						jsonb_build_object(
+							'emoji',
+							grouped.emoji,
+							'count',
+							grouped.total,
+							'reactedByMe',
+							grouped.reacted_by_me
+						)
+						ORDER BY
+							grouped.first_reacted_at ASC
+					)
+				FROM
+					(
+						SELECT
+							r.emoji,
+							COUNT(*) AS total,
+							bool_or(r.user_id = @user_id) AS reacted_by_me,
+							MIN(r.created_at) AS first_reacted_at
+						FROM
+							comment_reactions r
+						WHERE
+							r.comment_id = com.id
+						GROUP BY
+							r.emoji
+					) grouped
+			),
+			'[]'::JSONB
+		) AS reactions
+	FROM
+		todo_comments com
+		JOIN todos t ON t.id = com.todo_id
+		CROSS JOIN LATERAL ` + visibleCommentSQL + ` visible
+`
 
-	rows, err := r.Server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+// GetCommentsByTodoID pages through the top-level comments on a todo the user can
+// view, oldest first, whoever wrote them
+func (r *CommentRepository) GetCommentsByTodoID(ctx context.Context, userID string, todoID uuid.UUID, query *comment.CommentPageQuery) (*model.CursorPage[comment.PopulatedComment], error) {
+	return r.getCommentPage(ctx, userID, "com.todo_id = @todo_id AND com.parent_comment_id IS NULL", pgx.NamedArgs{
 		"todo_id": todoID,
-		"user_id": userID,
-	})
+	}, query)
+}
+
+// GetCommentReplies pages through the replies to a comment, oldest first
+func (r *CommentRepository) GetCommentReplies(ctx context.Context, userID string, commentID uuid.UUID, query *comment.CommentPageQuery) (*model.CursorPage[comment.PopulatedComment], error) {
+	return r.getCommentPage(ctx, userID, "com.parent_comment_id = @parent_comment_id", pgx.NamedArgs{
+		"parent_comment_id": commentID,
+	}, query)
+}
+
+func (r *CommentRepository) getCommentPage(ctx context.Context, userID string, condition string, args pgx.NamedArgs, query *comment.CommentPageQuery) (*model.CursorPage[comment.PopulatedComment], error) {
+	stmt := populatedCommentSelect + `
+		WHERE
+			` + condition + `
+			AND ` + todoAccessSQL
+
+	if query.Cursor != nil {
+		createdAt, id, err := model.DecodeCursor(*query.Cursor)
+		if err != nil {
+			code := "INVALID_CURSOR"
+			return nil, errs.NewBadRequestError("invalid cursor", false, &code, nil, nil)
+		}
+		stmt += " AND (com.created_at, com.id) > (@cursor_created_at, @cursor_id)"
+		args["cursor_created_at"] = createdAt
+		args["cursor_id"] = id
+	}
+
+	// one extra row tells whether another page follows
+	stmt += " ORDER BY com.created_at ASC, com.id ASC LIMIT @limit"
+	args["limit"] = *query.Limit + 1
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, args, userID, share.RoleViewer))
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get comments query for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute get comments query where %s user_id=%s: %w", condition, userID, err)
 	}
 
-	comments, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.Comment])
+	comments, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.PopulatedComment])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect comments rows for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to collect rows from table:todo_comments where %s user_id=%s: %w", condition, userID, err)
+	}
+
+	page := &model.CursorPage[comment.PopulatedComment]{
+		Data: comments,
 	}
+	if len(comments) > *query.Limit {
+		page.Data = comments[:*query.Limit]
+		page.HasMore = true
 
-	return comments, nil
+		last := page.Data[len(page.Data)-1]
+		nextCursor := model.EncodeCursor(last.CreatedAt, last.ID)
+		page.NextCursor = &nextCursor
+	}
 
+	return page, nil
 }
 
+// GetPopulatedCommentByID returns a comment on a todo the user can view with its
+// reply count and reactions
+func (r *CommentRepository) GetPopulatedCommentByID(ctx context.Context, userID string, commentID uuid.UUID) (*comment.PopulatedComment, error) {
+	stmt := populatedCommentSelect + `
+		WHERE
+			com.id = @id
+			AND ` + todoAccessSQL
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": commentID,
+	}, userID, share.RoleViewer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get populated comment query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.PopulatedComment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "COMMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("comment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_comments for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	return &commentItem, nil
+}
+
+// GetCommentByID returns a comment on a todo the user can view
 func (r *CommentRepository) GetCommentByID(ctx context.Context, userID string, commentID uuid.UUID) (*comment.Comment, error) {
 	stmt := `
-		SELECT *
+		SELECT
+			com.*
 		FROM 
-			todo_comments 
+			todo_comments com
+			JOIN todos t ON t.id = com.todo_id
 		WHERE 
-			id=@id
-			AND user_id=@user_id
-
-	`
+			com.id=@id
+			AND ` + todoAccessSQL
 
-	rows, err := r.Server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      commentID,
-		"user_id": userID,
-	})
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": commentID,
+	}, userID, share.RoleViewer))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute get comment by id query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
 	}
 
 	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "COMMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("comment not found", false, &code)
+		}
 		return nil, fmt.Errorf("failed to collect comment row for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
 	}
 
 	return &commentItem, nil
 }
 
+// UpdateComment lets authors edit their own comments while they can still comment on
+// the todo. When the content changes, the version it replaces is kept as a revision.
 func (r *CommentRepository) UpdateComment(ctx context.Context, userID string, commentID uuid.UUID, content string) (*comment.Comment, error) {
 	stmt := `
+		WITH
+			revision AS (
+				INSERT INTO
+					comment_revisions (comment_id, content, content_html, edited_by)
+				SELECT
+					com.id,
+					com.content,
+					com.content_html,
+					@user_id
+				FROM
+					todo_comments com
+					JOIN todos t ON t.id = com.todo_id
+				WHERE
+					com.id = @id
+					AND com.user_id = @user_id
+					AND com.deleted_at IS NULL
+					AND com.content <> @content
+					AND ` + todoAccessSQL + `
+			)
 		UPDATE 
-			todo_comments
+			todo_comments com
 		SET
 			content=@content,
+			content_html=@content_html,
+			edited_at=CASE WHEN com.content <> @content THEN NOW() ELSE com.edited_at END,
 			updated_at=NOW()
+		FROM
+			todos t
 		WHERE
-			id=@id
-			AND user_id=@user_id
-		RETURNING *
-	
+			t.id = com.todo_id
+			AND com.id=@id
+			AND com.user_id=@user_id
+			AND com.deleted_at IS NULL
+			AND ` + todoAccessSQL + `
+		RETURNING com.*
 	`
 
-	rows, err := r.Server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      commentID,
-		"user_id": userID,
-		"content": content,
-	})
+	contentHTML, err := markdown.Render(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render comment for comment_id=%s: %w", commentID.String(), err)
+	}
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id":           commentID,
+		"content":      content,
+		"content_html": contentHTML,
+	}, userID, share.RoleCommenter))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute update comment query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
 	}
 
 	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "COMMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("comment not found", false, &code)
+		}
 		return nil, fmt.Errorf("failed to collect row from table:todo_comments for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
 	}
 
@@ -141,19 +318,419 @@ func (r *CommentRepository) UpdateComment(ctx context.Context, userID string, co
 
 }
 
+// DelelteComment lets authors remove their own comments while they can still comment on the todo
 func (r *CommentRepository) DelelteComment(ctx context.Context, userID string, commentID uuid.UUID) error {
 	results, err := r.Server.DB.Pool.Exec(ctx, `
-			DELETE FROM todo_comments
-			WHERE id=@id ANS user_id=@user_id
-	`, pgx.NamedArgs{
-		"id":      commentID,
-		"user_id": userID,
-	})
+			DELETE FROM todo_comments com
+			USING todos t
+			WHERE
+				t.id = com.todo_id
+				AND com.id=@id
+				AND com.user_id=@user_id
+				AND `+todoAccessSQL, withAccess(ctx, pgx.NamedArgs{
+		"id": commentID,
+	}, userID, share.RoleCommenter))
 	if err != nil {
 		return fmt.Errorf("failed to delete comment: %w", err)
 	}
 	if results.RowsAffected() == 0 {
-		return fmt.Errorf("comment not found")
+		code := "COMMENT_NOT_FOUND"
+		return errs.NewNotFoundError("comment not found", false, &code)
+	}
+
+	return nil
+}
+
+// SyncCommentMentions makes userIDs the current mentions of the comment and returns
+// only the mentions that never existed before. Mentions dropped by an edit are
+// kept as removed, so mentioning the same user again later does not notify twice.
+func (r *CommentRepository) SyncCommentMentions(ctx context.Context, commentItem *comment.Comment, userIDs []string) ([]comment.Mention, error) {
+	stmt := `
+		WITH
+			removed AS (
+				UPDATE comment_mentions
+				SET
+					removed_at = NOW()
+				WHERE
+					comment_id = @comment_id
+					AND removed_at IS NULL
+					AND NOT (user_id = ANY (@user_ids::TEXT[]))
+			),
+			restored AS (
+				UPDATE comment_mentions
+				SET
+					removed_at = NULL
+				WHERE
+					comment_id = @comment_id
+					AND removed_at IS NOT NULL
+					AND user_id = ANY (@user_ids::TEXT[])
+			)
+		INSERT INTO
+			comment_mentions (comment_id, todo_id, user_id, mentioned_by)
+		SELECT
+			@comment_id,
+			@todo_id,
+			mentioned,
+			@mentioned_by
+		FROM
+			unnest(@user_ids::TEXT[]) AS mentioned
+		ON CONFLICT (comment_id, user_id) DO NOTHING
+		RETURNING
+			comment_id,
+			todo_id,
+			user_id,
+			mentioned_by,
+			created_at
+	`
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"comment_id":   commentItem.ID,
+		"todo_id":      commentItem.TodoID,
+		"mentioned_by": commentItem.UserID,
+		"user_ids":     userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute sync comment mentions query for comment_id=%s: %w", commentItem.ID.String(), err)
+	}
+
+	mentions, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.Mention])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:comment_mentions for comment_id=%s: %w", commentItem.ID.String(), err)
+	}
+
+	return mentions, nil
+}
+
+// GetMentionsOfUser returns the comments currently mentioning the user on todos they
+// can still see, newest first. Access is checked against each todo's own organization
+// using the given memberships.
+func (r *CommentRepository) GetMentionsOfUser(
+	ctx context.Context,
+	userID string,
+	memberships []organization.Membership,
+	query *comment.GetMentionsQuery,
+) (*model.PaginatedResponse[comment.PopulatedMention], error) {
+	args := withMemberships(withAccess(ctx, pgx.NamedArgs{
+		"limit":  *query.Limit,
+		"offset": (*query.Page - 1) * (*query.Limit),
+	}, userID, share.RoleViewer), memberships)
+
+	var total int
+	err := r.Server.DB.Pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*)
+		FROM
+			comment_mentions m
+			JOIN todos t ON t.id = m.todo_id
+		WHERE
+			m.user_id = @user_id
+			AND m.removed_at IS NULL
+			AND `+todoAccessSQL, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of mentions user_id=%s: %w", userID, err)
+	}
+
+	stmt := `
+		SELECT
+			m.comment_id,
+			m.todo_id,
+			m.user_id,
+			m.mentioned_by,
+			m.created_at,
+			t.title AS todo_title,
+			to_jsonb(camel (visible)) AS comment
+		FROM
+			comment_mentions m
+			JOIN todo_comments com ON com.id = m.comment_id
+			JOIN todos t ON t.id = m.todo_id
+			CROSS JOIN LATERAL ` + visibleCommentSQL + ` visible
+		WHERE
+			m.user_id = @user_id
+			AND m.removed_at IS NULL
+			AND ` + todoAccessSQL + `
+		ORDER BY
+			m.created_at DESC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get mentions query for user_id=%s: %w", userID, err)
+	}
+
+	mentions, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.PopulatedMention])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:comment_mentions for user_id=%s: %w", userID, err)
+	}
+
+	return &model.PaginatedResponse[comment.PopulatedMention]{
+		Data:       mentions,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+// SetCommentHidden collapses or restores a comment as the todo's owner, recording who hid it
+func (r *CommentRepository) SetCommentHidden(ctx context.Context, moderatorID string, commentID uuid.UUID, hidden bool) (*comment.Comment, error) {
+	stmt := `
+		UPDATE todo_comments com
+		SET
+			hidden_at = CASE WHEN @hidden::BOOLEAN THEN COALESCE(com.hidden_at, NOW()) END,
+			hidden_by = CASE WHEN @hidden::BOOLEAN THEN COALESCE(com.hidden_by, @user_id) END
+		FROM
+			todos t
+		WHERE
+			t.id = com.todo_id
+			AND com.id = @id
+			AND com.deleted_at IS NULL
+			AND ` + todoAccessSQL + `
+		RETURNING
+			com.*
+	`
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id":     commentID,
+		"hidden": hidden,
+	}, moderatorID, share.RoleOwner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute set comment hidden query for comment_id=%s user_id=%s: %w", commentID.String(), moderatorID, err)
+	}
+
+	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "COMMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("comment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_comments for comment_id=%s user_id=%s: %w", commentID.String(), moderatorID, err)
+	}
+
+	return &commentItem, nil
+}
+
+// GetCommentRevisions returns the earlier versions of a comment the user can view, newest first.
+// The revisions of a hidden comment are only shown to the todo's owners.
+func (r *CommentRepository) GetCommentRevisions(ctx context.Context, userID string, commentID uuid.UUID) ([]comment.Revision, error) {
+	stmt := `
+		SELECT
+			rev.*
+		FROM
+			comment_revisions rev
+			JOIN todo_comments com ON com.id = rev.comment_id
+			JOIN todos t ON t.id = com.todo_id
+		WHERE
+			rev.comment_id = @id
+			AND ` + todoAccessSQL + `
+			AND (
+				com.hidden_at IS NULL
+				OR (` + todoAccessRoleSQL + `) = 'owner'
+			)
+		ORDER BY
+			rev.created_at DESC
+	`
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": commentID,
+	}, userID, share.RoleViewer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get comment revisions query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	revisions, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.Revision])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:comment_revisions for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	return revisions, nil
+}
+
+// HasReplies reports whether any reply points at the comment
+func (r *CommentRepository) HasReplies(ctx context.Context, commentID uuid.UUID) (bool, error) {
+	var hasReplies bool
+	err := r.Server.DB.Pool.QueryRow(ctx, `
+		SELECT
+			EXISTS (
+				SELECT
+					1
+				FROM
+					todo_comments
+				WHERE
+					parent_comment_id = @id
+			)
+	`, pgx.NamedArgs{
+		"id": commentID,
+	}).Scan(&hasReplies)
+	if err != nil {
+		return false, fmt.Errorf("failed to check replies of comment_id=%s: %w", commentID.String(), err)
+	}
+
+	return hasReplies, nil
+}
+
+// SoftDeleteComment blanks an author's comment that still has replies, keeping it
+// as a placeholder so the thread stays intact
+func (r *CommentRepository) SoftDeleteComment(ctx context.Context, userID string, commentID uuid.UUID) (*comment.Comment, error) {
+	return r.softDeleteComment(ctx, userID, commentID, "com.user_id = @user_id", share.RoleCommenter)
+}
+
+// ModerateDeleteComment lets the todo's owner remove anyone's comment. It always
+// leaves a placeholder so the thread shows who removed it.
+func (r *CommentRepository) ModerateDeleteComment(ctx context.Context, moderatorID string, commentID uuid.UUID) (*comment.Comment, error) {
+	return r.softDeleteComment(ctx, moderatorID, commentID, "TRUE", share.RoleOwner)
+}
+
+// softDeleteComment blanks the comment and records who deleted it. Its revisions,
+// reactions and mentions go with it.
+func (r *CommentRepository) softDeleteComment(ctx context.Context, userID string, commentID uuid.UUID, condition string, required share.Role) (*comment.Comment, error) {
+	stmt := `
+		WITH
+			deleted AS (
+				UPDATE todo_comments com
+				SET
+					content = '',
+					content_html = '',
+					deleted_at = NOW(),
+					deleted_by = @user_id
+				FROM
+					todos t
+				WHERE
+					t.id = com.todo_id
+					AND com.id = @id
+					AND ` + condition + `
+					AND com.deleted_at IS NULL
+					AND ` + todoAccessSQL + `
+				RETURNING
+					com.*
+			),
+			revisions AS (
+				DELETE FROM comment_revisions rev USING deleted d
+				WHERE
+					rev.comment_id = d.id
+			),
+			reactions AS (
+				DELETE FROM comment_reactions r USING deleted d
+				WHERE
+					r.comment_id = d.id
+			),
+			mentions AS (
+				UPDATE comment_mentions m
+				SET
+					removed_at = NOW()
+				FROM
+					deleted d
+				WHERE
+					m.comment_id = d.id
+					AND m.removed_at IS NULL
+			)
+		SELECT
+			*
+		FROM
+			deleted
+	`
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": commentID,
+	}, userID, required))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute soft delete comment query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "COMMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("comment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_comments for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	return &commentItem, nil
+}
+
+// PruneDeletedComment removes a placeholder its author left once its last reply is
+// gone and reports whether it did. Placeholders left by moderators are kept.
+func (r *CommentRepository) PruneDeletedComment(ctx context.Context, commentID uuid.UUID) (bool, error) {
+	result, err := r.Server.DB.Pool.Exec(ctx, `
+		DELETE FROM todo_comments com
+		WHERE
+			com.id = @id
+			AND com.deleted_at IS NOT NULL
+			AND com.deleted_by = com.user_id
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					todo_comments reply
+				WHERE
+					reply.parent_comment_id = com.id
+			)
+	`, pgx.NamedArgs{
+		"id": commentID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to prune deleted comment_id=%s: %w", commentID.String(), err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// AddReaction records the user's reaction; reacting twice with the same emoji
+// returns the existing reaction
+func (r *CommentRepository) AddReaction(ctx context.Context, userID string, commentID uuid.UUID, emoji string) (*comment.Reaction, error) {
+	stmt := `
+		INSERT INTO
+			comment_reactions (comment_id, user_id, emoji)
+		VALUES
+			(@comment_id, @user_id, @emoji)
+		ON CONFLICT (comment_id, user_id, emoji) DO UPDATE
+		SET
+			emoji = EXCLUDED.emoji
+		RETURNING
+			*
+	`
+
+	rows, err := r.Server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"comment_id": commentID,
+		"user_id":    userID,
+		"emoji":      emoji,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute add reaction query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	reaction, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Reaction])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:comment_reactions for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+	}
+
+	return &reaction, nil
+}
+
+// RemoveReaction takes back one of the user's own reactions
+func (r *CommentRepository) RemoveReaction(ctx context.Context, userID string, commentID uuid.UUID, emoji string) error {
+	result, err := r.Server.DB.Pool.Exec(ctx, `
+		DELETE FROM comment_reactions
+		WHERE
+			comment_id = @comment_id
+			AND user_id = @user_id
+			AND emoji = @emoji
+	`, pgx.NamedArgs{
+		"comment_id": commentID,
+		"user_id":    userID,
+		"emoji":      emoji,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete reaction: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "REACTION_NOT_FOUND"
+		return errs.NewNotFoundError("reaction not found", false, &code)
 	}
 
 	return nil
@@ -6,6 +6,7 @@ type Repositories struct {
 	Todo     *TodoRepository
 	Comment  *CommentRepository
 	Category *CategoryRepository
+	Share    *ShareRepository
 }
 
 func NewRepositories(s *server.Server) *Repositories {
@@ -13,5 +14,6 @@ func NewRepositories(s *server.Server) *Repositories {
 		Todo:     NewTodoRepository(s),
 		Comment:  NewCommentRepository(s),
 		Category: NewCategoryRepository(s),
+		Share:    NewShareRepository(s),
 	}
 }
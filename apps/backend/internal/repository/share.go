@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ShareRepository struct {
+	server *server.Server
+}
+
+func NewShareRepository(server *server.Server) *ShareRepository {
+	return &ShareRepository{
+		server: server,
+	}
+}
+
+// CreateShare invites a grantee to a category or a todo. Re-inviting someone who
+// declined earlier reopens their invitation; any other existing share is a conflict.
+func (r *ShareRepository) CreateShare(ctx context.Context, ownerID string, granteeID string,
+	categoryID *uuid.UUID, todoID *uuid.UUID, role share.Role,
+) (*share.Share, error) {
+	conflictTarget := "(todo_id, grantee_id) WHERE todo_id IS NOT NULL"
+	if categoryID != nil {
+		conflictTarget = "(category_id, grantee_id) WHERE category_id IS NOT NULL"
+	}
+
+	stmt := `
+		INSERT INTO
+			todo_shares (
+				owner_id,
+				grantee_id,
+				category_id,
+				todo_id,
+				role
+			)
+		VALUES
+			(
+				@owner_id,
+				@grantee_id,
+				@category_id,
+				@todo_id,
+				@role
+			)
+		ON CONFLICT ` + conflictTarget + ` DO UPDATE
+		SET
+			role = EXCLUDED.role,
+			status = 'pending',
+			responded_at = NULL
+		WHERE
+			todo_shares.status = 'declined'
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"owner_id":    ownerID,
+		"grantee_id":  granteeID,
+		"category_id": categoryID,
+		"todo_id":     todoID,
+		"role":        role,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create share query for owner_id=%s grantee_id=%s: %w", ownerID, granteeID, err)
+	}
+
+	shareItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[share.Share])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SHARE_ALREADY_EXISTS"
+			return nil, errs.NewBadRequestError("this item is already shared with that user", true, &code, nil, nil)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_shares for owner_id=%s grantee_id=%s: %w", ownerID, granteeID, err)
+	}
+
+	return &shareItem, nil
+}
+
+func (r *ShareRepository) GetShareByID(ctx context.Context, shareID uuid.UUID) (*share.Share, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_shares
+		WHERE
+			id = @id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"id": shareID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get share by id query for share_id=%s: %w", shareID, err)
+	}
+
+	shareItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[share.Share])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SHARE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("share not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_shares for share_id=%s: %w", shareID, err)
+	}
+
+	return &shareItem, nil
+}
+
+// GetSharesForCategory lists every grant on a category owned by ownerID
+func (r *ShareRepository) GetSharesForCategory(ctx context.Context, ownerID string, categoryID uuid.UUID) ([]share.Share, error) {
+	return r.getShares(ctx, "category_id", ownerID, categoryID)
+}
+
+// GetSharesForTodo lists every grant on a todo owned by ownerID
+func (r *ShareRepository) GetSharesForTodo(ctx context.Context, ownerID string, todoID uuid.UUID) ([]share.Share, error) {
+	return r.getShares(ctx, "todo_id", ownerID, todoID)
+}
+
+func (r *ShareRepository) getShares(ctx context.Context, column string, ownerID string, targetID uuid.UUID) ([]share.Share, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_shares
+		WHERE
+			owner_id = @owner_id
+			AND ` + column + ` = @target_id
+		ORDER BY
+			created_at ASC
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"owner_id":  ownerID,
+		"target_id": targetID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get shares query for %s=%s: %w", column, targetID, err)
+	}
+
+	shares, err := pgx.CollectRows(rows, pgx.RowToStructByName[share.Share])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_shares for %s=%s: %w", column, targetID, err)
+	}
+
+	return shares, nil
+}
+
+// GetSharedWithMe lists the grants made to the user in the given status, with the shared item
+func (r *ShareRepository) GetSharedWithMe(ctx context.Context, granteeID string, status share.Status) ([]share.PopulatedShare, error) {
+	stmt := `
+		SELECT
+			s.*,
+			CASE
+				WHEN c.id IS NOT NULL THEN to_jsonb(camel (c))
+				ELSE NULL
+			END AS category,
+			CASE
+				WHEN t.id IS NOT NULL THEN to_jsonb(camel (t))
+				ELSE NULL
+			END AS todo
+		FROM
+			todo_shares s
+			LEFT JOIN todo_categories c ON c.id = s.category_id
+			LEFT JOIN todos t ON t.id = s.todo_id
+		WHERE
+			s.grantee_id = @grantee_id
+			AND s.status = @status
+		ORDER BY
+			s.created_at DESC
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"grantee_id": granteeID,
+		"status":     status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute shared with me query for grantee_id=%s: %w", granteeID, err)
+	}
+
+	shares, err := pgx.CollectRows(rows, pgx.RowToStructByName[share.PopulatedShare])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_shares for grantee_id=%s: %w", granteeID, err)
+	}
+
+	return shares, nil
+}
+
+// UpdateShareRole changes the role of a grant; only the owner may do this
+func (r *ShareRepository) UpdateShareRole(ctx context.Context, ownerID string, shareID uuid.UUID, role share.Role) (*share.Share, error) {
+	stmt := `
+		UPDATE todo_shares
+		SET
+			role = @role
+		WHERE
+			id = @id
+			AND owner_id = @owner_id
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"id":       shareID,
+		"owner_id": ownerID,
+		"role":     role,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute update share query for share_id=%s: %w", shareID, err)
+	}
+
+	shareItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[share.Share])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SHARE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("share not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_shares for share_id=%s: %w", shareID, err)
+	}
+
+	return &shareItem, nil
+}
+
+// RespondToShare accepts or declines a pending invitation addressed to the grantee
+func (r *ShareRepository) RespondToShare(ctx context.Context, granteeID string, shareID uuid.UUID, status share.Status) (*share.Share, error) {
+	stmt := `
+		UPDATE todo_shares
+		SET
+			status = @status,
+			responded_at = NOW()
+		WHERE
+			id = @id
+			AND grantee_id = @grantee_id
+			AND status = 'pending'
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"id":         shareID,
+		"grantee_id": granteeID,
+		"status":     status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute respond to share query for share_id=%s: %w", shareID, err)
+	}
+
+	shareItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[share.Share])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "INVITATION_NOT_FOUND"
+			return nil, errs.NewNotFoundError("pending invitation not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_shares for share_id=%s: %w", shareID, err)
+	}
+
+	return &shareItem, nil
+}
+
+// DeleteShare revokes a grant as its owner, or leaves it as its grantee
+func (r *ShareRepository) DeleteShare(ctx context.Context, userID string, shareID uuid.UUID) error {
+	result, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM todo_shares
+		WHERE
+			id = @id
+			AND (owner_id = @user_id OR grantee_id = @user_id)
+	`, pgx.NamedArgs{
+		"id":      shareID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "SHARE_NOT_FOUND"
+		return errs.NewNotFoundError("share not found", false, &code)
+	}
+
+	return nil
+}
+
+// GetTodoAudience returns everyone who can currently see the todo through it: its owner
+// and every accepted grantee of the todo, its parent, its category or a category above it.
+// Assignees are not included, an assignment grants no access on its own.
+func (r *ShareRepository) GetTodoAudience(ctx context.Context, todoID uuid.UUID) ([]string, error) {
+	stmt := `
+		SELECT
+			t.user_id
+		FROM
+			todos t
+		WHERE
+			t.id = @todo_id
+		UNION
+		SELECT
+			s.grantee_id
+		FROM
+			todos t
+			JOIN todo_shares s ON s.todo_id = t.id
+			OR s.todo_id = t.parent_todo_id
+			OR s.category_id IN (` + todoCategoryLineageSQL + `)
+		WHERE
+			t.id = @todo_id
+			AND s.status = 'accepted'
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id": todoID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute todo audience query for todo_id=%s: %w", todoID, err)
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_shares for todo_id=%s: %w", todoID, err)
+	}
+
+	return userIDs, nil
+}
+
+// GetTodoOrganizationID returns the organization a todo belongs to, or nil for a personal todo
+func (r *ShareRepository) GetTodoOrganizationID(ctx context.Context, todoID uuid.UUID) (*string, error) {
+	var organizationID *string
+	err := r.server.DB.Pool.QueryRow(ctx, `SELECT organization_id FROM todos WHERE id = @todo_id`, pgx.NamedArgs{
+		"todo_id": todoID,
+	}).Scan(&organizationID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get organization of todo_id=%s: %w", todoID, err)
+	}
+
+	return organizationID, nil
+}
+
+// GetCategoryAudience returns the category owner and the accepted grantees of it or a category above it
+func (r *ShareRepository) GetCategoryAudience(ctx context.Context, categoryID uuid.UUID) ([]string, error) {
+	stmt := `
+		SELECT
+			c.user_id
+		FROM
+			todo_categories c
+		WHERE
+			c.id = @category_id
+		UNION
+		SELECT
+			s.grantee_id
+		FROM
+			todo_categories c
+			JOIN todo_shares s ON s.category_id IN (` + categoryLineageSQL + `)
+		WHERE
+			c.id = @category_id
+			AND s.status = 'accepted'
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"category_id": categoryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute category audience query for category_id=%s: %w", categoryID, err)
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_shares for category_id=%s: %w", categoryID, err)
+	}
+
+	return userIDs, nil
+}
+
+// GetCategoryOrganizationID returns the organization a category belongs to, or nil for a personal category
+func (r *ShareRepository) GetCategoryOrganizationID(ctx context.Context, categoryID uuid.UUID) (*string, error) {
+	var organizationID *string
+	err := r.server.DB.Pool.QueryRow(ctx, `SELECT organization_id FROM todo_categories WHERE id = @category_id`, pgx.NamedArgs{
+		"category_id": categoryID,
+	}).Scan(&organizationID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get organization of category_id=%s: %w", categoryID, err)
+	}
+
+	return organizationID, nil
+}
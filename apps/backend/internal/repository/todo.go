@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/lib/markdown"
 	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
 	"github.com/C0deNe0/go-tasker/internal/model/todo"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/google/uuid"
@@ -30,8 +33,10 @@ func (r *TodoRepository) CreateTodo(ctx context.Context, userID string, payload
 		INSERT INTO
 		todos (
 			user_id,
+			organization_id,
 			title,
 			description,
+			description_html,
 			priority,
 			due_date,
 			parent_todo_id,
@@ -41,8 +46,10 @@ func (r *TodoRepository) CreateTodo(ctx context.Context, userID string, payload
 		VALUES 
 		(
 			@user_id,
+			@organization_id,
 			@title,
 			@description,
+			@description_html,
 			@priority,
 			@due_date,
 			@parent_todo_id,
@@ -58,15 +65,22 @@ func (r *TodoRepository) CreateTodo(ctx context.Context, userID string, payload
 		priority = *payload.Priority
 	}
 
+	descriptionHTML, err := markdown.RenderPtr(payload.Description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render todo description for user_id=%s: %w", userID, err)
+	}
+
 	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id":        userID,
-		"title":          payload.Title,
-		"description":    payload.Description,
-		"priority":       priority,
-		"due_date":       payload.DueDate,
-		"parent_todo_id": payload.ParentTodoID,
-		"category_id":    payload.CategoryID,
-		"metadata":       payload.MetaData,
+		"user_id":          userID,
+		"organization_id":  organization.WorkspaceID(ctx),
+		"title":            payload.Title,
+		"description":      payload.Description,
+		"description_html": descriptionHTML,
+		"priority":         priority,
+		"due_date":         payload.DueDate,
+		"parent_todo_id":   payload.ParentTodoID,
+		"category_id":      payload.CategoryID,
+		"metadata":         payload.MetaData,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute todo query for user_id=%s title=%s:%w", userID, payload.Title, err)
@@ -84,73 +98,101 @@ func (r *TodoRepository) CreateTodo(ctx context.Context, userID string, payload
 	return &todoItem, nil
 }
 
-func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID uuid.UUID) (*todo.PopulatedTodo, error) {
-	stmt := `
-		
-			SELECT 
-			t.*,
-			CASE
-				WHEN c.id IS NOT NULL THEN to_jsonb(camel (c))
-				ELSE NULL
-			END AS category,
-			COALESCE(
-				jsonb_agg(
-					to_jsonb(camel (child))
-					ORDER BY
-						child.sort_order ASC,
-						child.created_at ASC
-
-					) FILTER (
-					 WHERE child.id IS NOT NULL
-					),
-					 '[]'::JSONB
-			) AS children,
-			 COALESCE(
-				 jsonb_agg(
-					 to_jsonb(camel (com))
-					 ORDER BY
-						 com.created_at ASC,
-				 ) FILTER (
-				  WHERE 
-				  	com.id IS NOT NULL
-				),
-				 '[]'::JSONB
-			 ) AS comments,
-			COALESCE(
-				jsonb_agg(
-					to_jsonb(camel (att))
-					ORDER BY
-						att.created_at DESC
-				) FILTER (
-					WHERE att.id IS NOT NULL
-				),
-				 '[]'::JSONB
-			) AS attachments
+// populatedTodoSelect selects the todos aliased t together with their category,
+// subtasks, comments as the caller may see them, attachments, assignees and the
+// caller's access role
+const populatedTodoSelect = `
+	SELECT
+		t.*,
+		CASE
+			WHEN c.id IS NOT NULL THEN to_jsonb(camel (c))
+			ELSE NULL
+		END AS category,
+		COALESCE(
+			(
+				SELECT
+					jsonb_agg(
+						to_jsonb(camel (child))
+						ORDER BY
+							child.sort_order ASC,
+							child.created_at ASC
+					)
+				FROM
+					todos child
+				WHERE
+					child.parent_todo_id = t.id
+			),
+			'[]'::JSONB
+		) AS children,
+		COALESCE(
+			(
+				SELECT
+					jsonb_agg(
+						to_jsonb(camel (visible))
+						ORDER BY
+							com.created_at ASC
+					)
+				FROM
+					todo_comments com
+					CROSS JOIN LATERAL ` + visibleCommentSQL + ` visible
+				WHERE
+					com.todo_id = t.id
+			),
+			'[]'::JSONB
+		) AS comments,
+		COALESCE(
+			(
+				SELECT
+					jsonb_agg(
+						to_jsonb(camel (att)) - 'contentText'
+						ORDER BY
+							att.created_at DESC
+					)
+				FROM
+					todo_attachments att
+				WHERE
+					att.todo_id = t.id
+			),
+			'[]'::JSONB
+		) AS attachments,
+		COALESCE(
+			(
+				SELECT
+					jsonb_agg(
+						to_jsonb(camel (asg))
+						ORDER BY
+							asg.created_at ASC
+					)
+				FROM
+					todo_assignees asg
+				WHERE
+					asg.todo_id = t.id
+			),
+			'[]'::JSONB
+		) AS assignees,
+		` + todoAccessRoleSQL + ` AS access_role
+	FROM
+		todos t
+		LEFT JOIN todo_categories c ON c.id = t.category_id
+`
+
+// accessibleTodo is a todo together with the role the caller holds on it
+type accessibleTodo struct {
+	todo.Todo
+	AccessRole *share.Role `db:"access_role"`
+}
 
-			 	FROM 
-					todos t
-					LEFT JOIN todo_categories c ON c.id=t.category_id
-					AND c.user_id=@user_id
-					LEFT JOIN todos child ON child.parent_todo_id=t.id
-					AND	child.user_id=@user_id
-					LEFT JOIN todo_comments com ON com.todo_id=t.id
-					AND com.user_id=@user_id
-					LEFT JOIN todo_attachments att ON att.todo_id=t.id
-				WHERE 
-					t.id=@id
-					AND t.user_id=@id
-				GROUP BY
-				 	t.id,
-					c.id
-		`
+func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID uuid.UUID) (*todo.PopulatedTodo, error) {
+	stmt := populatedTodoSelect + `
+		WHERE
+			t.id = @id
+			AND ` + todoAccessSQL
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      todoID,
-		"user_id": userID,
-	})
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": todoID,
+	}, userID, share.RoleViewer))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute get todo by id query for user_id=%s todo_id=%s: %w", userID, todoID, err)
-
 	}
 
 	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.PopulatedTodo])
@@ -161,86 +203,47 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID
 	return &todoItem, nil
 }
 
-func (r *TodoRepository) CheckTodoExists(ctx context.Context, userID string, todoID uuid.UUID) (*todo.Todo, error) {
+// CheckTodoExists returns the todo if the user holds at least the required role on it
+func (r *TodoRepository) CheckTodoExists(ctx context.Context, userID string, todoID uuid.UUID, required share.Role) (*todo.Todo, error) {
 	stmt := `
-		SELECT * FROM todos WHERE id=@id AND user_id=@user_id
+		SELECT
+			t.*,
+			` + todoAccessRoleSQL + ` AS access_role
+		FROM
+			todos t
+		WHERE
+			t.id = @id
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      todoID,
-		"user_id": userID,
-	})
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"id": todoID,
+	}, userID, required))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute check todo exists query for user_id=%s todo_id=%s: %w", userID, todoID, err)
 	}
 
-	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[accessibleTodo])
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, checkAccess(nil, required, "TODO_NOT_FOUND", "todo")
+		}
 		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s user_id=%s: %w", todoID, userID, err)
 	}
 
-	return &todoItem, nil
+	if err := checkAccess(todoItem.AccessRole, required, "TODO_NOT_FOUND", "todo"); err != nil {
+		return nil, err
+	}
+
+	return &todoItem.Todo, nil
 }
 
+// GetTodos lists the todos of the current workspace the user can see
 func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *todo.GetTodosQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
-	stmt := `
-			SELECT 
-				t.*,
-				CASE 
-					WHEN c.id IS NOT NULL to_jsonb(camel (c))
-					ELSE NULL
-				END AS category,
-				COALESCE(
-					jsonb_agg(
-						to_jsonb(camel (child))
-						ORDER BY
-							child.sort_order ASC,
-							child.created_at ASC					
-					) FILTER (
-						WHERE
-							child.id IS NOT NULL 
-					),
-					'[]'::JSONB
-				) AS children,
-				 COALESCE(
-				 	jsonb_agg(
-						to_jsonb(camel (com))
-						ORDER BY
-							com.created_at ASC
-					) FILTER (
-						WHERE
-							com.id IS NOT NULL  
-					),
-					'[]'::JOSNB
-				 ) AS comments,
-				  COALESCE(
-				 	jsonb_agg(
-						to_jsonb(camel (att))
-						ORDER BY
-							att.created_at DESC
-					) FILTER (
-						WHERE
-							att.id IS NOT NULL  
-					),
-					'[]'::JOSNB
-				 ) AS attachments
+	stmt := populatedTodoSelect
 
-				FROM 
-					todos t
-					LEFT JOIN todo_categories c ON c.id=t.category_id
-					AND c.user_id=@user_id
-					LEFT JOIN todos child ON child.parent_todo_id=t.id
-					AND child.user_id=@user_id
-					LEFT JOIN todo_comments com ON com.todo_id=t.id
-					AND com.user_id=@user_id
-					LEFT JOIN todo_attachments att ON att.todo_id=t.id
-		`
-
-	args := pgx.NamedArgs{
-		"user_id": userID,
-	}
+	args := withAccess(ctx, pgx.NamedArgs{}, userID, share.RoleViewer)
 
-	conditions := []string{"t.user_id=@user_id"}
+	conditions := []string{workspaceSQL("t"), todoAccessSQL}
 
 	if query.Status != nil {
 		conditions = append(conditions, "t.status=@status")
@@ -251,10 +254,19 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 		args["priority"] = *query.Priority
 	}
 	if query.CategoryID != nil {
-		conditions = append(conditions, "t.category_id=@category_id")
+		if query.IncludeSubcategories != nil && *query.IncludeSubcategories {
+			conditions = append(conditions, "t.category_id IN ("+categoryDescendantsSQL+")")
+		} else {
+			conditions = append(conditions, "t.category_id=@category_id")
+		}
 		args["category_id"] = *query.CategoryID
 	}
 
+	// filtering on a category shows its todos even when it is archived
+	if query.CategoryID == nil && (query.IncludeArchivedCategories == nil || !*query.IncludeArchivedCategories) {
+		conditions = append(conditions, unarchivedCategorySQL)
+	}
+
 	if query.ParentTodoID != nil {
 		conditions = append(conditions, "t.parent_todo_id=@parent_todo_id")
 		args["parent_todo_id"] = *query.ParentTodoID
@@ -268,11 +280,11 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 	}
 
 	if query.DueTo != nil {
-		conditions = append(conditions, "t.due_to <= @due_to")
+		conditions = append(conditions, "t.due_date <= @due_to")
 		args["due_to"] = *query.DueTo
 	}
 	if query.OverDue != nil && *query.OverDue {
-		conditions = append(conditions, "t.due_date >NOW() AND t.status !='completed'")
+		conditions = append(conditions, "t.due_date < NOW() AND t.status != 'completed'")
 	}
 
 	if query.Completed != nil {
@@ -284,18 +296,35 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 	}
 
 	if query.Search != nil {
-		conditions = append(conditions, "(t.title ILIKE @search OR t.description ILIKE @search)")
+		conditions = append(conditions, "(t.title ILIKE @search OR t.description ILIKE @search OR EXISTS ("+attachmentTextMatchSQL+"))")
 		args["search"] = "%" + *query.Search + "%"
 	}
 
+	// "me" lets clients filter on the caller without knowing their user ID
+	if query.Assignee != nil {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM todo_assignees a WHERE a.todo_id = t.id AND a.user_id = @assignee)")
+		if *query.Assignee == "me" {
+			args["assignee"] = userID
+		} else {
+			args["assignee"] = *query.Assignee
+		}
+	}
+
+	if query.Unassigned != nil {
+		if *query.Unassigned {
+			conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM todo_assignees a WHERE a.todo_id = t.id)")
+		} else {
+			conditions = append(conditions, "EXISTS (SELECT 1 FROM todo_assignees a WHERE a.todo_id = t.id)")
+		}
+	}
+
 	if len(conditions) > 0 {
 		stmt += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	countStmt := "SELECT COUNT(*) FROM todos t"
 	if len(conditions) > 0 {
-		countStmt += " WHERE " + strings.Join(conditions, " AND")
-
+		countStmt += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	var total int
@@ -304,8 +333,6 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 		return nil, fmt.Errorf("failed to get total count of todos user_id=%s: %w", userID, err)
 	}
 
-	stmt += " GROUP BY t.id, c.id"
-
 	if query.Sort != nil {
 		stmt += " ORDER BY t." + *query.Sort
 		if query.Order != nil && *query.Order == "desc" {
@@ -340,6 +367,12 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 		return nil, fmt.Errorf("failed to collect rows from table: todos for user_id=%s: %w", userID, err)
 	}
 
+	if query.Search != nil && len(todos) > 0 {
+		if err := r.setAttachmentMatches(ctx, todos, *query.Search); err != nil {
+			return nil, err
+		}
+	}
+
 	return &model.PaginatedResponse[todo.PopulatedTodo]{
 		Data:       todos,
 		Page:       *query.Page,
@@ -349,12 +382,77 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 	}, nil
 }
 
+// attachmentTextMatchSQL selects the attachments of todo t whose extracted text matches @search
+const attachmentTextMatchSQL = `
+	SELECT
+		1
+	FROM
+		todo_attachments att
+	WHERE
+		att.todo_id = t.id
+		AND att.content_text ILIKE @search
+`
+
+// attachmentSnippetContext is how many characters around a match are shown of an attachment's text
+const attachmentSnippetContext = 80
+
+// setAttachmentMatches fills in which attachments of the todos matched the search, with a snippet of their text
+func (r *TodoRepository) setAttachmentMatches(ctx context.Context, todos []todo.PopulatedTodo, search string) error {
+	stmt := `
+		SELECT
+			att.todo_id,
+			att.id AS attachment_id,
+			att.name,
+			SUBSTRING(
+				att.content_text
+				FROM
+					GREATEST(STRPOS(LOWER(att.content_text), LOWER(@term)) - @context, 1) FOR LENGTH(@term) + 2 * @context
+			) AS snippet
+		FROM
+			todo_attachments att
+		WHERE
+			att.todo_id = ANY(@todo_ids)
+			AND att.content_text ILIKE @search
+		ORDER BY
+			att.created_at DESC
+	`
+
+	todoIDs := make([]uuid.UUID, 0, len(todos))
+	for i := range todos {
+		todoIDs = append(todoIDs, todos[i].ID)
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"todo_ids": todoIDs,
+		"term":     search,
+		"search":   "%" + search + "%",
+		"context":  attachmentSnippetContext,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get attachment matches: %w", err)
+	}
+
+	matches, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentMatch])
+	if err != nil {
+		return fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	for i := range todos {
+		for _, match := range matches {
+			if match.TodoID == todos[i].ID {
+				todos[i].MatchedAttachments = append(todos[i].MatchedAttachments, match)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
-	stmt := "UPDATE todos SET "
-	args := pgx.NamedArgs{
+	stmt := "UPDATE todos t SET "
+	args := withAccess(ctx, pgx.NamedArgs{
 		"todo_id": payload.ID,
-		"user_id": userID,
-	}
+	}, userID, share.RoleEditor)
 	setClauses := []string{}
 
 	if payload.Title != nil {
@@ -363,8 +461,14 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload
 	}
 
 	if payload.Description != nil {
-		setClauses = append(setClauses, "description = @description")
+		descriptionHTML, err := markdown.Render(*payload.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render todo description for todo_id=%s: %w", payload.ID, err)
+		}
+
+		setClauses = append(setClauses, "description = @description", "description_html = @description_html")
 		args["description"] = *payload.Description
+		args["description_html"] = descriptionHTML
 	}
 
 	if payload.Status != nil {
@@ -410,7 +514,7 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload
 	}
 
 	stmt += strings.Join(setClauses, ", ")
-	stmt += " WHERE id = @todo_id AND user_id = @user_id RETURNING *"
+	stmt += " WHERE t.id = @todo_id AND " + todoAccessSQL + " RETURNING t.*"
 
 	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
 	if err != nil {
@@ -425,28 +529,122 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload
 	return &updatedTodo, nil
 }
 
-func (r *TodoRepository) DeleteTodo(ctx context.Context, userID string, todoID uuid.UUID) error {
+// ReplaceTodoDescription swaps the description only if it still reads previous, so
+// an edit made in the meantime is never overwritten
+func (r *TodoRepository) ReplaceTodoDescription(ctx context.Context, userID string, todoID uuid.UUID, previous string, description string) (*todo.Todo, error) {
+	descriptionHTML, err := markdown.Render(description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render todo description for todo_id=%s: %w", todoID, err)
+	}
+
 	stmt := `
-		DELETE FROM todos
+		UPDATE todos t
+		SET
+			description = @description,
+			description_html = @description_html
 		WHERE
-			id=@todo_id
-			AND user_id=@user_id
+			t.id = @todo_id
+			AND t.description = @previous
+			AND ` + todoAccessSQL + `
+		RETURNING
+			t.*
 	`
 
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"todo_id":          todoID,
+		"previous":         previous,
+		"description":      description,
+		"description_html": descriptionHTML,
+	}, userID, share.RoleEditor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute replace todo description query for todo_id=%s: %w", todoID, err)
+	}
+
+	updatedTodo, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "DESCRIPTION_CHANGED"
+			return nil, errs.NewConflictError("the description was changed by someone else, reload and try again", true, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s: %w", todoID, err)
+	}
+
+	return &updatedTodo, nil
+}
+
+// DeleteTodo removes a todo the user owns and returns the storage keys of the attachments
+// that were cascaded with it, so their objects can be deleted too
+func (r *TodoRepository) DeleteTodo(ctx context.Context, userID string, todoID uuid.UUID) ([]string, error) {
+	// every part of the statement sees the attachments as they were before the cascade
+	stmt := `
+		WITH
+			deleted AS (
+				DELETE FROM todos t
+				WHERE
+					t.id=@todo_id
+					AND ` + todoAccessSQL + `
+				RETURNING
+					t.id
+			)
+		SELECT
+			COALESCE(
+				(
+					SELECT
+						ARRAY_AGG(k.key)
+					FROM
+						(
+							SELECT
+								att.download_key,
+								att.thumbnails
+							FROM
+								todo_attachments att
+							WHERE
+								att.todo_id = d.id
+							UNION ALL
+							SELECT
+								v.download_key,
+								v.thumbnails
+							FROM
+								todo_attachment_versions v
+								JOIN todo_attachments att ON att.id = v.attachment_id
+							WHERE
+								att.todo_id = d.id
+						) f
+						CROSS JOIN LATERAL (
+							SELECT
+								f.download_key AS key
+							UNION ALL
+							SELECT
+								thumb ->> 'key'
+							FROM
+								JSONB_ARRAY_ELEMENTS(f.thumbnails) thumb
+						) k
+					WHERE
+						k.key <> ''
+				),
+				'{}'
+			) AS storage_keys
+		FROM
+			deleted d
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
 		"todo_id": todoID,
-		"user_id": userID,
-	})
+	}, userID, share.RoleOwner))
 	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		code := "TODO_NOT_FOUND"
-		return errs.NewNotFoundError("todo not found", false, &code)
+	keys, err := pgx.CollectOneRow(rows, pgx.RowTo[[]string])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TODO_NOT_FOUND"
+			return nil, errs.NewNotFoundError("todo not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to delete todo: %w", err)
 	}
 
-	return nil
+	return keys, nil
 }
 
 func (r *TodoRepository) GetTodoStats(ctx context.Context, userID string) (*todo.TodoStats, error) {
@@ -463,14 +661,13 @@ func (r *TodoRepository) GetTodoStats(ctx context.Context, userID string) (*todo
 			COUNT(CASE WHEN status='archived' THEN 1 END) AS archived,
 			COUNT(CASE WHEN due_date<NOW() AND status!='completed' THEN 1 END) AS overdue
 		FROM
-			todos
+			todos t
 		WHERE
-			user_id=@user_id
-	`
+			` + workspaceSQL("t") + `
+			AND ` + unarchivedCategorySQL + `
+			AND ` + todoAccessSQL
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id": userID,
-	})
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{}, userID, share.RoleViewer))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -484,16 +681,23 @@ func (r *TodoRepository) GetTodoStats(ctx context.Context, userID string) (*todo
 
 }
 
-func (r *TodoRepository) GetTodoAttachment(ctx context.Context, todoID uuid.UUID, attachmentID uuid.UUID) (*todo.TodoAttachment, error) {
+// GetTodoAttachment returns an attachment of a todo the user can at least view
+func (r *TodoRepository) GetTodoAttachment(ctx context.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) (*todo.TodoAttachment, error) {
 	stmt := `
-		SELECT * FROM todo_attachments
-		WHERE todo_id = @todo_id AND
-		id = @attachment_id
-	`
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		SELECT
+			att.*
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
+		WHERE
+			att.todo_id = @todo_id
+			AND att.id = @attachment_id
+			AND ` + todoAccessSQL
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
 		"todo_id":       todoID,
 		"attachment_id": attachmentID,
-	})
+	}, userID, share.RoleViewer))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todo attachments: %w", err)
 	}
@@ -512,22 +716,25 @@ func (r *TodoRepository) GetTodoAttachment(ctx context.Context, todoID uuid.UUID
 
 func (r *TodoRepository) GetTodoAttachments(
 	ctx context.Context,
+	userID string,
 	todoID uuid.UUID,
 ) ([]todo.TodoAttachment, error) {
 	stmt := `
 		SELECT
-			*
+			att.*
 		FROM
-			todo_attachments
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
 		WHERE
-			todo_id = @todo_id
+			att.todo_id = @todo_id
+			AND ` + todoAccessSQL + `
 		ORDER BY
-			created_at DESC
+			att.created_at DESC
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
 		"todo_id": todoID,
-	})
+	}, userID, share.RoleViewer))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todo attachments: %w", err)
 	}
@@ -543,76 +750,223 @@ func (r *TodoRepository) GetTodoAttachments(
 	return attachments, nil
 }
 
+// DeleteTodoAttachment removes an attachment from a todo the user can edit and returns the
+// storage keys of every version, which the caller has to delete
 func (r *TodoRepository) DeleteTodoAttachment(
 	ctx context.Context,
+	userID string,
 	todoID uuid.UUID,
 	attachmentID uuid.UUID,
-) error {
+) ([]string, error) {
+	// the versions are read as they were before the cascade
 	stmt := `
-		DELETE FROM todo_attachments
-		WHERE
-			todo_id = @todo_id
-			AND id = @attachment_id
+		WITH
+			deleted AS (
+				DELETE FROM todo_attachments att
+				USING
+					todos t
+				WHERE
+					t.id = att.todo_id
+					AND att.todo_id = @todo_id
+					AND att.id = @attachment_id
+					AND ` + todoAccessSQL + `
+				RETURNING
+					att.id,
+					att.download_key,
+					att.thumbnails
+			)
+		SELECT
+			COALESCE(
+				(
+					SELECT
+						ARRAY_AGG(k.key)
+					FROM
+						(
+							SELECT
+								d.download_key,
+								d.thumbnails
+							UNION ALL
+							SELECT
+								v.download_key,
+								v.thumbnails
+							FROM
+								todo_attachment_versions v
+							WHERE
+								v.attachment_id = d.id
+						) f
+						CROSS JOIN LATERAL (
+							SELECT
+								f.download_key AS key
+							UNION ALL
+							SELECT
+								thumb ->> 'key'
+							FROM
+								JSONB_ARRAY_ELEMENTS(f.thumbnails) thumb
+						) k
+					WHERE
+						k.key <> ''
+				),
+				'{}'
+			) AS storage_keys
+		FROM
+			deleted d
 	`
 
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
 		"todo_id":       todoID,
 		"attachment_id": attachmentID,
-	})
+	}, userID, share.RoleEditor))
 	if err != nil {
-		return fmt.Errorf("failed to delete todo attachment: %w", err)
+		return nil, fmt.Errorf("failed to delete todo attachment: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		code := "ATTACHMENT_NOT_FOUND"
-		return errs.NewNotFoundError("attachment not found", false, &code)
+	keys, err := pgx.CollectOneRow(rows, pgx.RowTo[[]string])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to delete todo attachment: %w", err)
 	}
 
-	return nil
+	return keys, nil
+}
+
+// attachmentBlobSQL records the blob of an uploaded file as the blob CTE. When one with the same
+// checksum exists its object is reused, otherwise the candidate key becomes the blob's object.
+// The statement's attachment rows take the blob's storage key and their triggers count the reference.
+const attachmentBlobSQL = `
+	blob AS (
+		INSERT INTO
+			attachment_blobs (checksum, storage_key, file_size, mime_type)
+		VALUES
+			(@checksum, @blob_key, @file_size, @mime_type)
+		ON CONFLICT (checksum) DO UPDATE
+		SET
+			checksum = EXCLUDED.checksum
+		RETURNING
+			storage_key
+	)`
+
+// blobArgs adds the named arguments of attachmentBlobSQL to args
+func blobArgs(args pgx.NamedArgs, blob *todo.AttachmentBlob) pgx.NamedArgs {
+	args["checksum"] = blob.Checksum
+	args["blob_key"] = blob.StorageKey
+	args["file_size"] = blob.FileSize
+	args["mime_type"] = blob.MimeType
+	return args
 }
 
+// UploadTodoAttachment records a new attachment stored as blob, blob.StorageKey is only used
+// when no blob with the same checksum exists yet
 func (r *TodoRepository) UploadTodoAttachment(
 	ctx context.Context,
+	attachmentID uuid.UUID,
 	todoID uuid.UUID,
 	userID string,
-	s3Key string,
 	fileName string,
-	fileSize int64,
-	mimeType string,
+	blob *todo.AttachmentBlob,
 ) (*todo.TodoAttachment, error) {
 	stmt := `
+		WITH
+			` + attachmentBlobSQL + `
 		INSERT INTO
 			todo_attachments (
+				id,
+				version_id,
 				todo_id,
 				name,
 				uploaded_by,
 				download_key,
+				checksum,
 				file_size,
 				mime_type
 			)
+		SELECT
+			@attachment_id,
+			@attachment_id,
+			@todo_id,
+			@name,
+			@uploaded_by,
+			blob.storage_key,
+			@checksum,
+			@file_size,
+			@mime_type
+		FROM
+			blob
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, blobArgs(pgx.NamedArgs{
+		"attachment_id": attachmentID,
+		"todo_id":       todoID,
+		"name":          fileName,
+		"uploaded_by":   userID,
+	}, blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create todo attachment for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// CreateAttachmentLink records a link attachment, which has no object in storage and nothing to scan
+func (r *TodoRepository) CreateAttachmentLink(
+	ctx context.Context,
+	attachmentID uuid.UUID,
+	todoID uuid.UUID,
+	userID string,
+	name string,
+	url string,
+	description *string,
+) (*todo.TodoAttachment, error) {
+	stmt := `
+		INSERT INTO
+			todo_attachments (
+				id,
+				version_id,
+				todo_id,
+				kind,
+				name,
+				url,
+				description,
+				uploaded_by,
+				download_key,
+				scan_status
+			)
 		VALUES
 			(
+				@attachment_id,
+				@attachment_id,
 				@todo_id,
+				'link',
 				@name,
+				@url,
+				@description,
 				@uploaded_by,
-				@download_key,
-				@file_size,
-				@mime_type
+				'',
+				'clean'
 			)
 		RETURNING
 			*
 	`
 
 	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"todo_id":      todoID,
-		"name":         fileName,
-		"uploaded_by":  userID,
-		"download_key": s3Key,
-		"file_size":    fileSize,
-		"mime_type":    mimeType,
+		"attachment_id": attachmentID,
+		"todo_id":       todoID,
+		"name":          name,
+		"url":           url,
+		"description":   description,
+		"uploaded_by":   userID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create todo attachment for todo_id=%s: %w", todoID.String(), err)
+		return nil, fmt.Errorf("failed to create link attachment for todo_id=%s: %w", todoID.String(), err)
 	}
 
 	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
@@ -623,15 +977,663 @@ func (r *TodoRepository) UploadTodoAttachment(
 	return &attachment, nil
 }
 
-//CRON REQUIREMENTS
+// currentAttachmentSQL selects an attachment the user can edit as the latest CTE and locks it,
+// so concurrent uploads of new versions queue up instead of numbering the same version twice
+const currentAttachmentSQL = `
+	latest AS (
+		SELECT
+			att.*
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
+		WHERE
+			att.todo_id = @todo_id
+			AND att.id = @attachment_id
+			AND ` + todoAccessSQL + `
+		FOR UPDATE OF
+			att
+	)`
+
+// archiveAttachmentVersionSQL copies the attachment in the latest CTE into its version history
+const archiveAttachmentVersionSQL = `
+	archived AS (
+		INSERT INTO
+			todo_attachment_versions (
+				id,
+				attachment_id,
+				version,
+				created_at,
+				name,
+				uploaded_by,
+				download_key,
+				checksum,
+				file_size,
+				mime_type,
+				scan_status,
+				scan_signature,
+				scanned_at,
+				width,
+				height,
+				page_count,
+				thumbnails,
+				processed_at
+			)
+		SELECT
+			version_id,
+			id,
+			version,
+			versioned_at,
+			name,
+			uploaded_by,
+			download_key,
+			checksum,
+			file_size,
+			mime_type,
+			scan_status,
+			scan_signature,
+			scanned_at,
+			width,
+			height,
+			page_count,
+			thumbnails,
+			processed_at
+		FROM
+			latest
+	)`
 
-func (r *TodoRepository) GetTodosDueInHours(ctx context.Context, hours int, limit int) ([]todo.Todo, error) {
-	stmt := `SELECT * FROM todos WHERE due_date IS NOT NULL AND due_date > NOW() AND due_date <= NOW() + INTERVAL '%d hours' AND status NOT IN ('completed','arvchived')
-	ORDER BY
-		due_date ASC LIMIT %d`
+// attachmentVersionsSQL lists every version of the attachment CTE, the current one included
+const attachmentVersionsSQL = `
+	SELECT
+		*
+	FROM
+		(
+			SELECT
+				a.version_id AS id,
+				a.id AS attachment_id,
+				a.version,
+				TRUE AS current,
+				a.versioned_at AS created_at,
+				NULL::TIMESTAMPTZ AS archived_at,
+				a.name,
+				a.uploaded_by,
+				a.download_key,
+				a.checksum,
+				a.file_size,
+				a.mime_type,
+				a.scan_status,
+				a.scan_signature,
+				a.scanned_at,
+				a.width,
+				a.height,
+				a.page_count,
+				a.thumbnails,
+				a.processed_at
+			FROM
+				attachment a
+			UNION ALL
+			SELECT
+				v.id,
+				v.attachment_id,
+				v.version,
+				FALSE AS current,
+				v.created_at,
+				v.archived_at,
+				v.name,
+				v.uploaded_by,
+				v.download_key,
+				v.checksum,
+				v.file_size,
+				v.mime_type,
+				v.scan_status,
+				v.scan_signature,
+				v.scanned_at,
+				v.width,
+				v.height,
+				v.page_count,
+				v.thumbnails,
+				v.processed_at
+			FROM
+				todo_attachment_versions v
+				JOIN attachment a ON a.id = v.attachment_id
+		) versions
+`
+
+// AddAttachmentVersion makes a newly stored file the current version of an attachment the user
+// can edit, moving the previous version into the history. Scanning and processing start over.
+func (r *TodoRepository) AddAttachmentVersion(
+	ctx context.Context,
+	userID string,
+	todoID uuid.UUID,
+	attachmentID uuid.UUID,
+	versionID uuid.UUID,
+	fileName string,
+	blob *todo.AttachmentBlob,
+) (*todo.TodoAttachment, error) {
+	stmt := `
+		WITH
+			` + currentAttachmentSQL + `,
+			` + archiveAttachmentVersionSQL + `,
+			` + attachmentBlobSQL + `
+		UPDATE todo_attachments att
+		SET
+			version = latest.version + 1,
+			version_id = @version_id,
+			versioned_at = CURRENT_TIMESTAMP,
+			name = @name,
+			uploaded_by = @uploaded_by,
+			download_key = blob.storage_key,
+			checksum = @checksum,
+			file_size = @file_size,
+			mime_type = @mime_type,
+			scan_status = 'pending',
+			scan_signature = NULL,
+			scanned_at = NULL,
+			width = NULL,
+			height = NULL,
+			page_count = NULL,
+			thumbnails = '[]',
+			processed_at = NULL,
+			content_text = NULL,
+			extracted_at = NULL
+		FROM
+			latest,
+			blob
+		WHERE
+			att.id = latest.id
+		RETURNING
+			att.*
+	`
 
-	query := fmt.Sprintf(stmt, hours, limit)
-	rows, err := r.server.DB.Pool.Query(ctx, query)
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, blobArgs(pgx.NamedArgs{
+		"todo_id":       todoID,
+		"attachment_id": attachmentID,
+		"version_id":    versionID,
+		"name":          fileName,
+		"uploaded_by":   userID,
+	}, blob), userID, share.RoleEditor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add version to attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// RestoreAttachmentVersion makes a copy of an earlier version the new current version. The copy
+// shares the earlier version's blob and thumbnails and keeps its scan verdict and media.
+func (r *TodoRepository) RestoreAttachmentVersion(
+	ctx context.Context,
+	userID string,
+	todoID uuid.UUID,
+	attachmentID uuid.UUID,
+	restoredID uuid.UUID,
+	versionID uuid.UUID,
+) (*todo.TodoAttachment, error) {
+	// the current version is only archived when the restored one still exists
+	stmt := `
+		WITH
+			latest AS (
+				SELECT
+					att.*
+				FROM
+					todo_attachments att
+					JOIN todos t ON t.id = att.todo_id
+				WHERE
+					att.todo_id = @todo_id
+					AND att.id = @attachment_id
+					AND EXISTS (
+						SELECT
+							1
+						FROM
+							todo_attachment_versions v
+						WHERE
+							v.id = @restored_id
+							AND v.attachment_id = att.id
+					)
+					AND ` + todoAccessSQL + `
+				FOR UPDATE OF
+					att
+			),
+			restored AS (
+				SELECT
+					v.*
+				FROM
+					todo_attachment_versions v
+					JOIN latest ON latest.id = v.attachment_id
+				WHERE
+					v.id = @restored_id
+			),
+			` + archiveAttachmentVersionSQL + `
+		UPDATE todo_attachments att
+		SET
+			version = latest.version + 1,
+			version_id = @version_id,
+			versioned_at = CURRENT_TIMESTAMP,
+			name = restored.name,
+			uploaded_by = @uploaded_by,
+			download_key = restored.download_key,
+			checksum = restored.checksum,
+			file_size = restored.file_size,
+			mime_type = restored.mime_type,
+			scan_status = restored.scan_status,
+			scan_signature = restored.scan_signature,
+			scanned_at = restored.scanned_at,
+			width = restored.width,
+			height = restored.height,
+			page_count = restored.page_count,
+			thumbnails = restored.thumbnails,
+			processed_at = restored.processed_at,
+			content_text = NULL,
+			extracted_at = NULL
+		FROM
+			latest,
+			restored
+		WHERE
+			att.id = latest.id
+		RETURNING
+			att.*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"todo_id":       todoID,
+		"attachment_id": attachmentID,
+		"restored_id":   restoredID,
+		"version_id":    versionID,
+		"uploaded_by":   userID,
+	}, userID, share.RoleEditor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore version_id=%s: %w", restoredID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_VERSION_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment version not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// ConfirmPendingVersionUpload turns a pending upload into the new current version of the attachment it
+// was requested for, stored as blob with the size and type storage reported
+func (r *TodoRepository) ConfirmPendingVersionUpload(
+	ctx context.Context,
+	uploadID uuid.UUID,
+	blob *todo.AttachmentBlob,
+) (*todo.TodoAttachment, error) {
+	stmt := `
+		WITH
+			confirmed AS (
+				DELETE FROM attachment_uploads
+				WHERE
+					id = @upload_id
+					AND todo_id IS NOT NULL
+					AND attachment_id IS NOT NULL
+				RETURNING
+					*
+			),
+			latest AS (
+				SELECT
+					att.*
+				FROM
+					todo_attachments att
+					JOIN confirmed c ON c.attachment_id = att.id
+					AND c.todo_id = att.todo_id
+				FOR UPDATE OF
+					att
+			),
+			` + archiveAttachmentVersionSQL + `,
+			` + attachmentBlobSQL + `
+		UPDATE todo_attachments att
+		SET
+			version = latest.version + 1,
+			version_id = confirmed.id,
+			versioned_at = CURRENT_TIMESTAMP,
+			name = confirmed.name,
+			uploaded_by = confirmed.uploaded_by,
+			download_key = blob.storage_key,
+			checksum = @checksum,
+			file_size = @file_size,
+			mime_type = @mime_type,
+			scan_status = 'pending',
+			scan_signature = NULL,
+			scanned_at = NULL,
+			width = NULL,
+			height = NULL,
+			page_count = NULL,
+			thumbnails = '[]',
+			processed_at = NULL,
+			content_text = NULL,
+			extracted_at = NULL
+		FROM
+			latest,
+			confirmed,
+			blob
+		WHERE
+			att.id = latest.id
+		RETURNING
+			att.*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, blobArgs(pgx.NamedArgs{
+		"upload_id": uploadID,
+	}, blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm pending version upload for upload_id=%s: %w", uploadID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// GetAttachmentVersions lists the versions of a file attachment the user can read, newest first. Links have none.
+func (r *TodoRepository) GetAttachmentVersions(ctx context.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) ([]todo.AttachmentVersion, error) {
+	stmt := `
+		WITH
+			attachment AS (
+				SELECT
+					att.*
+				FROM
+					todo_attachments att
+					JOIN todos t ON t.id = att.todo_id
+				WHERE
+					att.todo_id = @todo_id
+					AND att.id = @attachment_id
+					AND att.kind = 'file'
+					AND ` + todoAccessSQL + `
+			)
+		` + attachmentVersionsSQL + `
+		ORDER BY
+			version DESC
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"todo_id":       todoID,
+		"attachment_id": attachmentID,
+	}, userID, share.RoleViewer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions of attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	versions, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentVersion])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachment_versions: %w", err)
+	}
+
+	// the current version is always listed, so nothing at all means no attachment
+	if len(versions) == 0 {
+		code := "ATTACHMENT_NOT_FOUND"
+		return nil, errs.NewNotFoundError("attachment not found", false, &code)
+	}
+
+	return versions, nil
+}
+
+// GetAttachmentVersion returns one version of a file attachment the user can read, current or earlier
+func (r *TodoRepository) GetAttachmentVersion(
+	ctx context.Context,
+	userID string,
+	todoID uuid.UUID,
+	attachmentID uuid.UUID,
+	versionID uuid.UUID,
+) (*todo.AttachmentVersion, error) {
+	stmt := `
+		WITH
+			attachment AS (
+				SELECT
+					att.*
+				FROM
+					todo_attachments att
+					JOIN todos t ON t.id = att.todo_id
+				WHERE
+					att.todo_id = @todo_id
+					AND att.id = @attachment_id
+					AND att.kind = 'file'
+					AND ` + todoAccessSQL + `
+			)
+		` + attachmentVersionsSQL + `
+		WHERE
+			id = @version_id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"todo_id":       todoID,
+		"attachment_id": attachmentID,
+		"version_id":    versionID,
+	}, userID, share.RoleViewer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version_id=%s: %w", versionID.String(), err)
+	}
+
+	version, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentVersion])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_VERSION_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment version not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachment_versions: %w", err)
+	}
+
+	return &version, nil
+}
+
+// PruneAttachmentVersions drops the oldest earlier versions of an attachment beyond keep and
+// returns them, the caller deletes their objects
+func (r *TodoRepository) PruneAttachmentVersions(ctx context.Context, attachmentID uuid.UUID, keep int) ([]todo.AttachmentVersion, error) {
+	stmt := `
+		DELETE FROM todo_attachment_versions
+		WHERE
+			id IN (
+				SELECT
+					id
+				FROM
+					todo_attachment_versions
+				WHERE
+					attachment_id = @attachment_id
+				ORDER BY
+					version DESC
+				OFFSET
+					@keep
+			)
+		RETURNING
+			*,
+			FALSE AS current
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": attachmentID,
+		"keep":          keep,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune versions of attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	pruned, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentVersion])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachment_versions: %w", err)
+	}
+
+	return pruned, nil
+}
+
+// GetAssignedTodos lists the todos assigned to the user across every workspace.
+// Access is checked against each todo's own organization using the given
+// memberships, so an assignment whose share or membership is gone is left out.
+func (r *TodoRepository) GetAssignedTodos(
+	ctx context.Context,
+	userID string,
+	memberships []organization.Membership,
+	query *todo.GetInboxQuery,
+) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+	args := withMemberships(withAccess(ctx, pgx.NamedArgs{}, userID, share.RoleViewer), memberships)
+
+	conditions := []string{
+		"EXISTS (SELECT 1 FROM todo_assignees a WHERE a.todo_id = t.id AND a.user_id = @user_id)",
+		unarchivedCategorySQL,
+		todoAccessSQL,
+	}
+
+	if query.Status != nil {
+		conditions = append(conditions, "t.status = @status")
+		args["status"] = *query.Status
+	}
+
+	if query.Completed != nil {
+		if *query.Completed {
+			conditions = append(conditions, "t.status = 'completed'")
+		} else {
+			conditions = append(conditions, "t.status != 'completed'")
+		}
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	err := r.server.DB.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM todos t"+where, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of assigned todos user_id=%s: %w", userID, err)
+	}
+
+	stmt := populatedTodoSelect + where + " ORDER BY t." + *query.Sort
+	if *query.Order == "desc" {
+		stmt += " DESC NULLS LAST"
+	} else {
+		stmt += " ASC NULLS LAST"
+	}
+	stmt += ", t.created_at DESC LIMIT @limit OFFSET @offset"
+	args["limit"] = *query.Limit
+	args["offset"] = (*query.Page - 1) * (*query.Limit)
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get assigned todos query for user_id=%s: %w", userID, err)
+	}
+
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.PopulatedTodo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for user_id=%s: %w", userID, err)
+	}
+
+	return &model.PaginatedResponse[todo.PopulatedTodo]{
+		Data:       todos,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+func (r *TodoRepository) GetTodoAssignees(ctx context.Context, todoID uuid.UUID) ([]todo.TodoAssignee, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_assignees
+		WHERE
+			todo_id = @todo_id
+		ORDER BY
+			created_at ASC
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id": todoID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get todo assignees query for todo_id=%s: %w", todoID, err)
+	}
+
+	assignees, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.TodoAssignee])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_assignees for todo_id=%s: %w", todoID, err)
+	}
+
+	return assignees, nil
+}
+
+// AddTodoAssignees assigns the users to the todo and returns only the assignments
+// that did not exist yet, so callers notify each assignee once
+func (r *TodoRepository) AddTodoAssignees(ctx context.Context, todoID uuid.UUID, assignedBy string, userIDs []string) ([]todo.TodoAssignee, error) {
+	stmt := `
+		INSERT INTO
+			todo_assignees (todo_id, user_id, assigned_by)
+		SELECT
+			@todo_id,
+			assignee,
+			@assigned_by
+		FROM
+			unnest(@user_ids::TEXT[]) AS assignee
+		ON CONFLICT (todo_id, user_id) DO NOTHING
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id":     todoID,
+		"assigned_by": assignedBy,
+		"user_ids":    userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute add todo assignees query for todo_id=%s: %w", todoID, err)
+	}
+
+	assignees, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.TodoAssignee])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_assignees for todo_id=%s: %w", todoID, err)
+	}
+
+	return assignees, nil
+}
+
+func (r *TodoRepository) RemoveTodoAssignee(ctx context.Context, todoID uuid.UUID, userID string) error {
+	result, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM todo_assignees
+		WHERE
+			todo_id = @todo_id
+			AND user_id = @user_id
+	`, pgx.NamedArgs{
+		"todo_id": todoID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove todo assignee: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "ASSIGNEE_NOT_FOUND"
+		return errs.NewNotFoundError("user is not assigned to this todo", false, &code)
+	}
+
+	return nil
+}
+
+//CRON REQUIREMENTS
+
+func (r *TodoRepository) GetTodosDueInHours(ctx context.Context, hours int, limit int) ([]todo.Todo, error) {
+	stmt := `SELECT * FROM todos WHERE due_date IS NOT NULL AND due_date > NOW() AND due_date <= NOW() + INTERVAL '%d hours' AND status NOT IN ('completed','arvchived')
+	ORDER BY
+		due_date ASC LIMIT %d`
+
+	query := fmt.Sprintf(stmt, hours, limit)
+	rows, err := r.server.DB.Pool.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute get todos due in %d hours query: %w", hours, err)
 
@@ -741,3 +1743,1186 @@ func (r *TodoRepository) GetWeeklyTodoStatsForUsers(ctx context.Context, startDa
 
 	return stats, nil	
 }
+
+// CreatePendingUpload records an upload handed out to a client so it can be confirmed or cleaned up later
+func (r *TodoRepository) CreatePendingUpload(ctx context.Context, upload *todo.PendingUpload) (*todo.PendingUpload, error) {
+	stmt := `
+		INSERT INTO
+			attachment_uploads (
+				id,
+				todo_id,
+				uploaded_by,
+				upload_key,
+				name,
+				mime_type,
+				file_size,
+				method,
+				expires_at,
+				attachment_id
+			)
+		VALUES
+			(
+				@id,
+				@todo_id,
+				@uploaded_by,
+				@upload_key,
+				@name,
+				@mime_type,
+				@file_size,
+				@method,
+				@expires_at,
+				@attachment_id
+			)
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"id":            upload.ID,
+		"todo_id":       upload.TodoID,
+		"uploaded_by":   upload.UploadedBy,
+		"upload_key":    upload.UploadKey,
+		"name":          upload.Name,
+		"mime_type":     upload.MimeType,
+		"file_size":     upload.FileSize,
+		"method":        upload.Method,
+		"expires_at":    upload.ExpiresAt,
+		"attachment_id": upload.AttachmentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pending upload for todo_id=%s: %w", upload.TodoID, err)
+	}
+
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.PendingUpload])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:attachment_uploads: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetPendingUpload returns an unconfirmed upload the user requested on a todo they can still edit
+func (r *TodoRepository) GetPendingUpload(ctx context.Context, userID string, todoID uuid.UUID, uploadID uuid.UUID) (*todo.PendingUpload, error) {
+	stmt := `
+		SELECT
+			up.*
+		FROM
+			attachment_uploads up
+			JOIN todos t ON t.id = up.todo_id
+		WHERE
+			up.id = @upload_id
+			AND up.todo_id = @todo_id
+			AND up.uploaded_by = @user_id
+			AND ` + todoAccessSQL
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"upload_id": uploadID,
+		"todo_id":   todoID,
+	}, userID, share.RoleEditor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending upload for upload_id=%s: %w", uploadID.String(), err)
+	}
+
+	upload, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.PendingUpload])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "UPLOAD_NOT_FOUND"
+			return nil, errs.NewNotFoundError("upload not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_uploads: %w", err)
+	}
+
+	return &upload, nil
+}
+
+// ConfirmPendingUpload turns a pending upload into an attachment stored as blob, with the size and type storage reported
+func (r *TodoRepository) ConfirmPendingUpload(
+	ctx context.Context,
+	uploadID uuid.UUID,
+	blob *todo.AttachmentBlob,
+) (*todo.TodoAttachment, error) {
+	stmt := `
+		WITH
+			confirmed AS (
+				DELETE FROM attachment_uploads
+				WHERE
+					id = @upload_id
+					AND todo_id IS NOT NULL
+					AND attachment_id IS NULL
+				RETURNING
+					*
+			),
+			` + attachmentBlobSQL + `
+		INSERT INTO
+			todo_attachments (
+				id,
+				version_id,
+				todo_id,
+				name,
+				uploaded_by,
+				download_key,
+				checksum,
+				file_size,
+				mime_type
+			)
+		SELECT
+			confirmed.id,
+			confirmed.id,
+			confirmed.todo_id,
+			confirmed.name,
+			confirmed.uploaded_by,
+			blob.storage_key,
+			@checksum,
+			@file_size,
+			@mime_type
+		FROM
+			confirmed,
+			blob
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, blobArgs(pgx.NamedArgs{
+		"upload_id": uploadID,
+	}, blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm pending upload for upload_id=%s: %w", uploadID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "UPLOAD_NOT_FOUND"
+			return nil, errs.NewNotFoundError("upload not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// DeletePendingUpload forgets an upload whose object has been removed from storage
+func (r *TodoRepository) DeletePendingUpload(ctx context.Context, uploadID uuid.UUID) error {
+	stmt := `
+		DELETE FROM attachment_uploads
+		WHERE
+			id = @upload_id
+	`
+
+	_, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+		"upload_id": uploadID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete pending upload for upload_id=%s: %w", uploadID.String(), err)
+	}
+
+	return nil
+}
+
+// GetExpiredPendingUploads returns the oldest uploads that were never confirmed in time
+func (r *TodoRepository) GetExpiredPendingUploads(ctx context.Context, limit int) ([]todo.PendingUpload, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			attachment_uploads
+		WHERE
+			expires_at < NOW()
+		ORDER BY
+			expires_at ASC
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired pending uploads: %w", err)
+	}
+
+	uploads, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.PendingUpload])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:attachment_uploads: %w", err)
+	}
+
+	return uploads, nil
+}
+
+// GetStorageUsage totals the attachments a user uploaded and the direct uploads they still have reserved
+func (r *TodoRepository) GetStorageUsage(ctx context.Context, userID string) (*todo.StorageUsage, error) {
+	stmt := `
+		WITH
+			versions AS (
+				SELECT
+					COALESCE(SUM(file_size), 0)::BIGINT AS bytes
+				FROM
+					todo_attachment_versions
+				WHERE
+					uploaded_by = @user_id
+			)
+		SELECT
+			(
+				COALESCE(
+					(
+						SELECT
+							SUM(file_size)
+						FROM
+							todo_attachments
+						WHERE
+							uploaded_by = @user_id
+					),
+					0
+				) + versions.bytes
+			)::BIGINT AS used_bytes,
+			versions.bytes AS version_bytes,
+			COALESCE(
+				(
+					SELECT
+						SUM(file_size)
+					FROM
+						attachment_uploads
+					WHERE
+						uploaded_by = @user_id
+						AND todo_id IS NOT NULL
+						AND expires_at > NOW()
+				),
+				0
+			)::BIGINT AS reserved_bytes,
+			(
+				SELECT
+					COUNT(*)
+				FROM
+					todo_attachments
+				WHERE
+					uploaded_by = @user_id
+					AND kind = 'file'
+			) AS file_count
+		FROM
+			versions
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage for user_id=%s: %w", userID, err)
+	}
+
+	usage, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.StorageUsage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// GetStorageUsageByMimeType breaks a user's usage down by the media type of their attachments
+func (r *TodoRepository) GetStorageUsageByMimeType(ctx context.Context, userID string) ([]todo.StorageUsageByType, error) {
+	stmt := `
+		SELECT
+			COALESCE(mime_type, '') AS mime_type,
+			COUNT(*) AS file_count,
+			COALESCE(SUM(file_size), 0)::BIGINT AS bytes
+		FROM
+			todo_attachments
+		WHERE
+			uploaded_by = @user_id
+			AND kind = 'file'
+		GROUP BY
+			COALESCE(mime_type, '')
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage by mime type for user_id=%s: %w", userID, err)
+	}
+
+	usage, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.StorageUsageByType])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetStorageUsageByCategory breaks a user's usage down by the category of the todos they attached to
+func (r *TodoRepository) GetStorageUsageByCategory(ctx context.Context, userID string) ([]todo.StorageUsageByCategory, error) {
+	stmt := `
+		SELECT
+			c.id AS category_id,
+			c.name AS category_name,
+			COUNT(*) AS file_count,
+			COALESCE(SUM(att.file_size), 0)::BIGINT AS bytes
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
+			LEFT JOIN todo_categories c ON c.id = t.category_id
+		WHERE
+			att.uploaded_by = @user_id
+			AND att.kind = 'file'
+		GROUP BY
+			c.id,
+			c.name
+		ORDER BY
+			bytes DESC
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage by category for user_id=%s: %w", userID, err)
+	}
+
+	usage, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.StorageUsageByCategory])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetAttachmentForJob loads an attachment for background jobs, which act without a user
+func (r *TodoRepository) GetAttachmentForJob(ctx context.Context, attachmentID uuid.UUID) (*todo.AttachmentWithTodoTitle, error) {
+	stmt := `
+		SELECT
+			att.*,
+			t.title AS todo_title
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
+		WHERE
+			att.id = @attachment_id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": attachmentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentWithTodoTitle])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// SetAttachmentScanResult records the scanner's verdict on a pending attachment
+func (r *TodoRepository) SetAttachmentScanResult(
+	ctx context.Context,
+	attachmentID uuid.UUID,
+	downloadKey string,
+	status todo.ScanStatus,
+	signature *string,
+) (*todo.TodoAttachment, error) {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			scan_status = @scan_status,
+			scan_signature = @scan_signature,
+			scanned_at = NOW()
+		WHERE
+			id = @attachment_id
+			AND download_key = @download_key
+			AND scan_status = 'pending'
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id":  attachmentID,
+		"download_key":   downloadKey,
+		"scan_status":    status,
+		"scan_signature": signature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set scan result for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("pending attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// SetAttachmentBlob points an attachment at a blob, unless a new version replaced its object
+// meanwhile. It returns the storage key the attachment ended up with.
+func (r *TodoRepository) SetAttachmentBlob(
+	ctx context.Context,
+	attachmentID uuid.UUID,
+	oldKey string,
+	name string,
+	blob *todo.AttachmentBlob,
+) (string, error) {
+	stmt := `
+		WITH
+			` + attachmentBlobSQL + `
+		UPDATE todo_attachments
+		SET
+			download_key = blob.storage_key,
+			checksum = @checksum,
+			file_size = @file_size,
+			name = @name
+		FROM
+			blob
+		WHERE
+			id = @attachment_id
+			AND download_key = @old_key
+		RETURNING
+			download_key
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, blobArgs(pgx.NamedArgs{
+		"attachment_id": attachmentID,
+		"old_key":       oldKey,
+		"name":          name,
+	}, blob))
+	if err != nil {
+		return "", fmt.Errorf("failed to set blob for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	key, err := pgx.CollectOneRow(rows, pgx.RowTo[string])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return "", errs.NewNotFoundError("attachment not found", false, &code)
+		}
+		return "", fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return key, nil
+}
+
+// SetAttachmentVersionBlob points an earlier version at a blob, unless it was pruned or moved meanwhile.
+// It returns the storage key the version ended up with.
+func (r *TodoRepository) SetAttachmentVersionBlob(
+	ctx context.Context,
+	versionID uuid.UUID,
+	oldKey string,
+	name string,
+	blob *todo.AttachmentBlob,
+) (string, error) {
+	stmt := `
+		WITH
+			` + attachmentBlobSQL + `
+		UPDATE todo_attachment_versions
+		SET
+			download_key = blob.storage_key,
+			checksum = @checksum,
+			file_size = @file_size,
+			name = @name
+		FROM
+			blob
+		WHERE
+			id = @version_id
+			AND download_key = @old_key
+		RETURNING
+			download_key
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, blobArgs(pgx.NamedArgs{
+		"version_id": versionID,
+		"old_key":    oldKey,
+		"name":       name,
+	}, blob))
+	if err != nil {
+		return "", fmt.Errorf("failed to set blob for version_id=%s: %w", versionID.String(), err)
+	}
+
+	key, err := pgx.CollectOneRow(rows, pgx.RowTo[string])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_VERSION_NOT_FOUND"
+			return "", errs.NewNotFoundError("attachment version not found", false, &code)
+		}
+		return "", fmt.Errorf("failed to collect row from table:todo_attachment_versions: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAttachmentBlob looks up the blob stored for a checksum
+func (r *TodoRepository) GetAttachmentBlob(ctx context.Context, checksum string) (*todo.AttachmentBlob, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			attachment_blobs
+		WHERE
+			checksum = @checksum
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"checksum": checksum,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment blob: %w", err)
+	}
+
+	blob, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentBlob])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_BLOB_NOT_FOUND"
+			return nil, errs.NewNotFoundError("attachment blob not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_blobs: %w", err)
+	}
+
+	return &blob, nil
+}
+
+// ReleaseAttachmentBlobs forgets the blobs stored under any of the keys that nothing references any
+// more, so their objects can be deleted. A blob that was reused meanwhile keeps its count and stays.
+func (r *TodoRepository) ReleaseAttachmentBlobs(ctx context.Context, keys []string) error {
+	stmt := `
+		DELETE FROM attachment_blobs
+		WHERE
+			storage_key = ANY (@keys::TEXT[])
+			AND ref_count = 0
+	`
+
+	_, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+		"keys": keys,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release attachment blobs: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseUnusedAttachmentBlobs forgets every blob left without references since before a cutoff,
+// for blobs whose release was never queued. It returns how many were released.
+func (r *TodoRepository) ReleaseUnusedAttachmentBlobs(ctx context.Context, releasedBefore time.Time) (int64, error) {
+	stmt := `
+		DELETE FROM attachment_blobs
+		WHERE
+			ref_count = 0
+			AND updated_at < @released_before
+	`
+
+	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+		"released_before": releasedBefore,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to release unused attachment blobs: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// SetAttachmentMedia records the dimensions, page count and thumbnails of an attachment that was not processed yet.
+// The download key makes sure they are not recorded on a version uploaded while processing.
+func (r *TodoRepository) SetAttachmentMedia(ctx context.Context, attachmentID uuid.UUID, downloadKey string, media *todo.AttachmentMedia) (*todo.TodoAttachment, error) {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			width = @width,
+			height = @height,
+			page_count = @page_count,
+			thumbnails = @thumbnails,
+			processed_at = NOW()
+		WHERE
+			id = @attachment_id
+			AND download_key = @download_key
+			AND processed_at IS NULL
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": attachmentID,
+		"download_key":  downloadKey,
+		"width":         media.Width,
+		"height":        media.Height,
+		"page_count":    media.PageCount,
+		"thumbnails":    media.Thumbnails,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set media for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("unprocessed attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// SetAttachmentText records the text extracted from an attachment, nil when it has none that can be read.
+// The download key makes sure it is not recorded on a version uploaded while extracting.
+func (r *TodoRepository) SetAttachmentText(ctx context.Context, attachmentID uuid.UUID, downloadKey string, text *string) (*todo.TodoAttachment, error) {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			content_text = @content_text,
+			extracted_at = NOW()
+		WHERE
+			id = @attachment_id
+			AND download_key = @download_key
+			AND extracted_at IS NULL
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": attachmentID,
+		"download_key":  downloadKey,
+		"content_text":  text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set text for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("unextracted attachment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// GetUnextractedAttachmentIDs pages through clean file attachments whose text was never extracted
+func (r *TodoRepository) GetUnextractedAttachmentIDs(ctx context.Context, afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	stmt := `
+		SELECT
+			id
+		FROM
+			todo_attachments
+		WHERE
+			kind = 'file'
+			AND scan_status = 'clean'
+			AND extracted_at IS NULL
+			AND id > @after_id
+		ORDER BY
+			id
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"after_id": afterID,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unextracted attachments: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return ids, nil
+}
+
+// FindUnreferencedStorageKeys returns the keys no attachment, thumbnail, blob or pending upload points at.
+// Thumbnails extend their file's key, so those of a live blob are kept for every attachment sharing it.
+func (r *TodoRepository) FindUnreferencedStorageKeys(ctx context.Context, keys []string) ([]string, error) {
+	stmt := `
+		SELECT
+			k.key
+		FROM
+			UNNEST(@keys::TEXT[]) AS k (key)
+		WHERE
+			NOT EXISTS (
+				SELECT
+					1
+				FROM
+					todo_attachments att
+				WHERE
+					att.download_key = k.key
+			)
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					todo_attachments att
+				WHERE
+					att.thumbnails @> JSONB_BUILD_ARRAY(JSONB_BUILD_OBJECT('key', k.key))
+			)
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					todo_attachment_versions v
+				WHERE
+					v.download_key = k.key
+			)
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					todo_attachment_versions v
+				WHERE
+					v.thumbnails @> JSONB_BUILD_ARRAY(JSONB_BUILD_OBJECT('key', k.key))
+			)
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					attachment_blobs b
+				WHERE
+					b.storage_key = SPLIT_PART(k.key, '_thumb_', 1)
+			)
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					attachment_uploads u
+				WHERE
+					u.upload_key = k.key
+			)
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"keys": keys,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unreferenced storage keys: %w", err)
+	}
+
+	unreferenced, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return unreferenced, nil
+}
+
+// GetAttachmentDownloadKeys lists the storage keys under a prefix of attachment versions created before a cutoff
+func (r *TodoRepository) GetAttachmentDownloadKeys(ctx context.Context, prefix string, createdBefore time.Time) ([]string, error) {
+	stmt := `
+		SELECT
+			download_key
+		FROM
+			todo_attachments
+		WHERE
+			STARTS_WITH(download_key, @prefix)
+			AND versioned_at < @created_before
+		UNION ALL
+		SELECT
+			download_key
+		FROM
+			todo_attachment_versions
+		WHERE
+			STARTS_WITH(download_key, @prefix)
+			AND created_at < @created_before
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"prefix":         prefix,
+		"created_before": createdBefore,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment download keys: %w", err)
+	}
+
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetUnhashedAttachments pages through attachments stored before deduplication, which have no blob yet
+func (r *TodoRepository) GetUnhashedAttachments(ctx context.Context, afterID uuid.UUID, limit int) ([]todo.TodoAttachment, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_attachments
+		WHERE
+			checksum IS NULL
+			AND kind = 'file'
+			AND id > @after_id
+		ORDER BY
+			id
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"after_id": afterID,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unhashed attachments: %w", err)
+	}
+
+	attachments, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// GetUnhashedAttachmentVersions pages through earlier versions stored before deduplication
+func (r *TodoRepository) GetUnhashedAttachmentVersions(ctx context.Context, afterID uuid.UUID, limit int) ([]todo.AttachmentVersion, error) {
+	stmt := `
+		SELECT
+			*,
+			FALSE AS current
+		FROM
+			todo_attachment_versions
+		WHERE
+			checksum IS NULL
+			AND id > @after_id
+		ORDER BY
+			id
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"after_id": afterID,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unhashed attachment versions: %w", err)
+	}
+
+	versions, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentVersion])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachment_versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetArchiveAttachments lists the attachments of a todo, and optionally of its subtasks, the user
+// can read, in the order they go into an archive: the todo's own first, then each subtask's
+func (r *TodoRepository) GetArchiveAttachments(ctx context.Context, userID string, todoID uuid.UUID, includeSubtasks bool) ([]todo.AttachmentWithTodoTitle, error) {
+	stmt := `
+		SELECT
+			att.*,
+			t.title AS todo_title
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
+		WHERE
+			(
+				t.id = @todo_id
+				OR (
+					@include_subtasks::BOOLEAN
+					AND t.parent_todo_id = @todo_id
+				)
+			)
+			AND ` + todoAccessSQL + `
+		ORDER BY
+			t.id <> @todo_id,
+			t.title,
+			t.id,
+			att.created_at,
+			att.id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, withAccess(ctx, pgx.NamedArgs{
+		"todo_id":          todoID,
+		"include_subtasks": includeSubtasks,
+	}, userID, share.RoleViewer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive attachments for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	attachments, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentWithTodoTitle])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentsForArchive loads the attachments an archive was requested with, in archive order.
+// Access was checked when the archive was requested, attachments deleted since are left out.
+func (r *TodoRepository) GetAttachmentsForArchive(ctx context.Context, todoID uuid.UUID, attachmentIDs []uuid.UUID) ([]todo.AttachmentWithTodoTitle, error) {
+	stmt := `
+		SELECT
+			att.*,
+			t.title AS todo_title
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id = att.todo_id
+		WHERE
+			att.id = ANY (@attachment_ids::UUID[])
+		ORDER BY
+			t.id <> @todo_id,
+			t.title,
+			t.id,
+			att.created_at,
+			att.id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id":        todoID,
+		"attachment_ids": attachmentIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments for archive of todo_id=%s: %w", todoID.String(), err)
+	}
+
+	attachments, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentWithTodoTitle])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// CreateAttachmentArchive records an archive to be built by a job
+func (r *TodoRepository) CreateAttachmentArchive(ctx context.Context, archive *todo.AttachmentArchive) (*todo.AttachmentArchive, error) {
+	stmt := `
+		INSERT INTO
+			attachment_archives (
+				todo_id,
+				requested_by,
+				name,
+				attachment_ids,
+				file_count,
+				expires_at
+			)
+		VALUES
+			(
+				@todo_id,
+				@requested_by,
+				@name,
+				@attachment_ids,
+				@file_count,
+				@expires_at
+			)
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id":        archive.TodoID,
+		"requested_by":   archive.RequestedBy,
+		"name":           archive.Name,
+		"attachment_ids": archive.AttachmentIDs,
+		"file_count":     archive.FileCount,
+		"expires_at":     archive.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment archive: %w", err)
+	}
+
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentArchive])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:attachment_archives: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetAttachmentArchive loads an archive of a todo, only the user who requested it may see it
+func (r *TodoRepository) GetAttachmentArchive(ctx context.Context, userID string, todoID uuid.UUID, archiveID uuid.UUID) (*todo.AttachmentArchive, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			attachment_archives
+		WHERE
+			id = @archive_id
+			AND todo_id = @todo_id
+			AND requested_by = @user_id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"archive_id": archiveID,
+		"todo_id":    todoID,
+		"user_id":    userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment archive for archive_id=%s: %w", archiveID.String(), err)
+	}
+
+	archive, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentArchive])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ARCHIVE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("archive not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_archives: %w", err)
+	}
+
+	return &archive, nil
+}
+
+// GetAttachmentArchiveForJob loads an archive outside a request
+func (r *TodoRepository) GetAttachmentArchiveForJob(ctx context.Context, archiveID uuid.UUID) (*todo.AttachmentArchive, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			attachment_archives
+		WHERE
+			id = @archive_id
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"archive_id": archiveID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment archive for archive_id=%s: %w", archiveID.String(), err)
+	}
+
+	archive, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentArchive])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ARCHIVE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("archive not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_archives: %w", err)
+	}
+
+	return &archive, nil
+}
+
+// CompleteAttachmentArchive records the stored ZIP of an archive that is still pending
+func (r *TodoRepository) CompleteAttachmentArchive(ctx context.Context, archiveID uuid.UUID, storageKey string, fileSize int64, fileCount int) (*todo.AttachmentArchive, error) {
+	stmt := `
+		UPDATE attachment_archives
+		SET
+			status = 'ready',
+			storage_key = @storage_key,
+			file_size = @file_size,
+			file_count = @file_count,
+			completed_at = NOW()
+		WHERE
+			id = @archive_id
+			AND status = 'pending'
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"archive_id":  archiveID,
+		"storage_key": storageKey,
+		"file_size":   fileSize,
+		"file_count":  fileCount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete attachment archive for archive_id=%s: %w", archiveID.String(), err)
+	}
+
+	archive, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentArchive])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ARCHIVE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("pending archive not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_archives: %w", err)
+	}
+
+	return &archive, nil
+}
+
+// FailAttachmentArchive gives up on an archive that is still pending
+func (r *TodoRepository) FailAttachmentArchive(ctx context.Context, archiveID uuid.UUID, message string) (*todo.AttachmentArchive, error) {
+	stmt := `
+		UPDATE attachment_archives
+		SET
+			status = 'failed',
+			error = @error,
+			completed_at = NOW()
+		WHERE
+			id = @archive_id
+			AND status = 'pending'
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"archive_id": archiveID,
+		"error":      message,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail attachment archive for archive_id=%s: %w", archiveID.String(), err)
+	}
+
+	archive, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentArchive])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ARCHIVE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("pending archive not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_archives: %w", err)
+	}
+
+	return &archive, nil
+}
+
+// GetExpiredAttachmentArchives returns up to limit archives past their expiry
+func (r *TodoRepository) GetExpiredAttachmentArchives(ctx context.Context, limit int) ([]todo.AttachmentArchive, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			attachment_archives
+		WHERE
+			expires_at < NOW()
+		ORDER BY
+			expires_at
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired attachment archives: %w", err)
+	}
+
+	archives, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentArchive])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:attachment_archives: %w", err)
+	}
+
+	return archives, nil
+}
+
+// DeleteAttachmentArchive forgets an archive whose object has been removed from storage
+func (r *TodoRepository) DeleteAttachmentArchive(ctx context.Context, archiveID uuid.UUID) error {
+	stmt := `
+		DELETE FROM attachment_archives
+		WHERE
+			id = @archive_id
+	`
+
+	_, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+		"archive_id": archiveID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment archive for archive_id=%s: %w", archiveID.String(), err)
+	}
+
+	return nil
+}
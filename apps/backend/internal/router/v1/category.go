@@ -3,18 +3,33 @@ package v1
 import (
 	"github.com/C0deNe0/go-tasker/internal/handler"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
 	"github.com/labstack/echo/v4"
 )
 
-func registerCategoryRoutes(r *echo.Group, h *handler.CategoryHandler, auth *middleware.AuthMiddleware) {
+func registerCategoryRoutes(r *echo.Group, h *handler.CategoryHandler, sh *handler.ShareHandler, auth *middleware.AuthMiddleware) {
 
 	categories := r.Group("/categories")
 	categories.Use(auth.RequireAuth)
 
-	categories.POST("", h.CreateCategory)
-	categories.GET("", h.GetCategories)
+	//organization permissions, only checked inside an organization workspace
+	canRead := auth.RequirePermission(organization.PermissionCategoriesRead)
+	canWrite := auth.RequirePermission(organization.PermissionCategoriesWrite)
+
+	categories.POST("", h.CreateCategory, canWrite)
+	categories.GET("", h.GetCategories, canRead)
+	categories.GET("/tree", h.GetCategoryTree, canRead)
+	categories.POST("/reorder", h.ReorderCategories, canWrite)
 
 	dynamicCategory := categories.Group("/:id")
-	dynamicCategory.PATCH("", h.UpdateCategory)
-	dynamicCategory.DELETE("", h.DeleteCategory)
+	dynamicCategory.PATCH("", h.UpdateCategory, canWrite)
+	dynamicCategory.POST("/move", h.MoveCategory, canWrite)
+	dynamicCategory.POST("/archive", h.ArchiveCategory, canWrite)
+	dynamicCategory.POST("/unarchive", h.UnarchiveCategory, canWrite)
+	dynamicCategory.DELETE("", h.DeleteCategory, canWrite)
+
+	//sharing
+	categoryShares := dynamicCategory.Group("/shares")
+	categoryShares.POST("", sh.ShareCategory, canWrite)
+	categoryShares.GET("", sh.GetCategoryShares, canRead)
 }
@@ -3,6 +3,7 @@ package v1
 import (
 	"github.com/C0deNe0/go-tasker/internal/handler"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
 	"github.com/labstack/echo/v4"
 )
 
@@ -10,7 +11,25 @@ func registerCommentRoutes(r *echo.Group, h *handler.CommentHandler, auth *middl
 	comments := r.Group("/comments")
 	comments.Use(auth.RequireAuth)
 
+	//mentions of the caller in any workspace, so not tied to the active organization
+	comments.GET("/mentions", h.GetMentions)
+
+	//organization permissions, only checked inside an organization workspace
+	canRead := auth.RequirePermission(organization.PermissionTodosRead)
+	canWrite := auth.RequirePermission(organization.PermissionTodosWrite)
+
 	dynamicComment := comments.Group("/:id")
-	dynamicComment.PATCH("",h.UpdateComment)
-	dynamicComment.DELETE("",h.DeleteComment)
+	dynamicComment.PATCH("",h.UpdateComment, canWrite)
+	dynamicComment.DELETE("",h.DeleteComment, canWrite)
+	dynamicComment.GET("/replies", h.GetCommentReplies, canRead)
+	dynamicComment.GET("/revisions", h.GetCommentRevisions, canRead)
+
+	//moderation by the todo owner
+	dynamicComment.POST("/hide", h.HideComment, canWrite)
+	dynamicComment.POST("/unhide", h.UnhideComment, canWrite)
+
+	//reactions
+	commentReactions := dynamicComment.Group("/reactions")
+	commentReactions.POST("", h.AddReaction, canWrite)
+	commentReactions.DELETE("/:emoji", h.RemoveReaction, canWrite)
 }
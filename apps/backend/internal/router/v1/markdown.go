@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"github.com/C0deNe0/go-tasker/internal/handler"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerMarkdownRoutes(r *echo.Group, h *handler.MarkdownHandler, auth *middleware.AuthMiddleware) {
+	markdown := r.Group("/markdown")
+	markdown.Use(auth.RequireAuth)
+
+	markdown.POST("/preview", h.Preview)
+}
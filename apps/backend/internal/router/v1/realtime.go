@@ -0,0 +1,15 @@
+package v1
+
+import (
+	"github.com/C0deNe0/go-tasker/internal/handler"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerRealtimeRoutes(r *echo.Group, h *handler.RealtimeHandler, auth *middleware.AuthMiddleware) {
+	realtime := r.Group("/realtime")
+	realtime.Use(auth.AllowQueryToken, auth.RequireAuth)
+
+	realtime.GET("/events", h.StreamEvents)
+	realtime.GET("/ws", h.StreamEventsWebSocket)
+}
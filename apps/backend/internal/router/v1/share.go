@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"github.com/C0deNe0/go-tasker/internal/handler"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerShareRoutes(r *echo.Group, h *handler.ShareHandler, auth *middleware.AuthMiddleware) {
+	shares := r.Group("/shares")
+	shares.Use(auth.RequireAuth)
+
+	shares.GET("/shared-with-me", h.GetSharedWithMe)
+
+	dynamicShare := shares.Group("/:id")
+	dynamicShare.PATCH("", h.UpdateShare)
+	dynamicShare.DELETE("", h.DeleteShare)
+	dynamicShare.POST("/accept", h.AcceptShare)
+	dynamicShare.POST("/decline", h.DeclineShare)
+}
@@ -0,0 +1,16 @@
+package v1
+
+import (
+	"github.com/C0deNe0/go-tasker/internal/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// registerStorageRoutes exposes signed storage URLs, they carry their own authorization
+func registerStorageRoutes(r *echo.Group, h *handler.StorageHandler) {
+	objects := r.Group("/storage/objects")
+
+	objects.GET("", h.Download)
+	objects.HEAD("", h.Download)
+	objects.PUT("", h.Upload)
+	objects.POST("", h.UploadForm)
+}
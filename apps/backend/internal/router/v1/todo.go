@@ -3,33 +3,65 @@ package v1
 import (
 	"github.com/C0deNe0/go-tasker/internal/handler"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
 	"github.com/labstack/echo/v4"
 )
 
-func registerTodoRoutes(r *echo.Group, h *handler.TodoHandler, ch *handler.CommentHandler, auth *middleware.AuthMiddleware) {
+func registerTodoRoutes(r *echo.Group, h *handler.TodoHandler, ch *handler.CommentHandler, sh *handler.ShareHandler, auth *middleware.AuthMiddleware) {
 
 	//todo opertn
 	todos := r.Group("/todos")
 	todos.Use(auth.RequireAuth)
 
+	//organization permissions, only checked inside an organization workspace
+	canRead := auth.RequirePermission(organization.PermissionTodosRead)
+	canWrite := auth.RequirePermission(organization.PermissionTodosWrite)
+
 	//collection operations
-	todos.POST("", h.CreateTodo)
-	todos.GET("", h.GetTodos)
-	todos.GET("/stats", h.GetTodoStats)
+	todos.POST("", h.CreateTodo, canWrite)
+	todos.GET("", h.GetTodos, canRead)
+	todos.GET("/stats", h.GetTodoStats, canRead)
+	//assigned to the caller in any workspace, so not tied to the active organization
+	todos.GET("/inbox", h.GetInbox)
+	//the caller's own uploads across every workspace count against one quota
+	todos.GET("/attachments/usage", h.GetStorageUsage)
 
 	dynamicTodo := todos.Group("/:id")
-	dynamicTodo.GET("", h.GetTodoByID)
-	dynamicTodo.PATCH("", h.UpdateTodo)
-	dynamicTodo.DELETE("", h.DeleteTodo)
+	dynamicTodo.GET("", h.GetTodoByID, canRead)
+	dynamicTodo.PATCH("", h.UpdateTodo, canWrite)
+	dynamicTodo.DELETE("", h.DeleteTodo, canWrite)
+	dynamicTodo.PATCH("/description/tasks/:index", h.ToggleDescriptionTask, canWrite)
 
 	//commetns
 	todoComments := dynamicTodo.Group("/comments")
-	todoComments.PUT("", ch.AddComment)
-	todoComments.GET("", ch.GetCommentsByTodoID)
+	todoComments.PUT("", ch.AddComment, canWrite)
+	todoComments.GET("", ch.GetCommentsByTodoID, canRead)
+
+	//assignees
+	todoAssignees := dynamicTodo.Group("/assignees")
+	todoAssignees.POST("", h.AssignTodo, canWrite)
+	todoAssignees.DELETE("/:userId", h.UnassignTodo, canWrite)
+
+	//sharing
+	todoShares := dynamicTodo.Group("/shares")
+	todoShares.POST("", sh.ShareTodo, canWrite)
+	todoShares.GET("", sh.GetTodoShares, canRead)
 
 	//attachments
 	todoAttachment := dynamicTodo.Group("/attachments")
-	todoAttachment.POST("", h.UploadTodoAttachment)
-	todoAttachment.GET("/:attachmentId/download", h.GetAttachmentPresignedURL)
-	todoAttachment.DELETE("/:attachmentId", h.DeleteTodoAttachment)
+	todoAttachment.GET("", h.GetTodoAttachments, canRead)
+	todoAttachment.POST("", h.UploadTodoAttachment, canWrite)
+	todoAttachment.POST("/links", h.CreateAttachmentLink, canWrite)
+	todoAttachment.POST("/uploads", h.RequestAttachmentUpload, canWrite)
+	todoAttachment.POST("/uploads/:uploadId/confirm", h.ConfirmAttachmentUpload, canWrite)
+	todoAttachment.GET("/archive", h.GetAttachmentArchive, canRead)
+	todoAttachment.GET("/archive/:archiveId", h.GetAttachmentArchiveStatus, canRead)
+	todoAttachment.GET("/:attachmentId", h.GetTodoAttachment, canRead)
+	todoAttachment.GET("/:attachmentId/download", h.GetAttachmentPresignedURL, canRead)
+	todoAttachment.GET("/:attachmentId/content", h.GetAttachmentContent, canRead)
+	todoAttachment.GET("/:attachmentId/versions", h.GetAttachmentVersions, canRead)
+	todoAttachment.POST("/:attachmentId/versions", h.UploadAttachmentVersion, canWrite)
+	todoAttachment.GET("/:attachmentId/versions/:versionId/download", h.GetAttachmentVersionPresignedURL, canRead)
+	todoAttachment.POST("/:attachmentId/versions/:versionId/restore", h.RestoreAttachmentVersion, canWrite)
+	todoAttachment.DELETE("/:attachmentId", h.DeleteTodoAttachment, canWrite)
 }
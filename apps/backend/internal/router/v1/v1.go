@@ -8,9 +8,17 @@ import (
 
 func RegisterV1Routes(routes *echo.Group, handlers *handler.Handlers, middleware *middleware.Middlewares) {
 	//register todo route
-	registerTodoRoutes(routes, handlers.Todo, handlers.Comment, middleware.Auth)
+	registerTodoRoutes(routes, handlers.Todo, handlers.Comment, handlers.Share, middleware.Auth)
 	//category
-	registerCategoryRoutes(routes, handlers.Category, middleware.Auth)
+	registerCategoryRoutes(routes, handlers.Category, handlers.Share, middleware.Auth)
 	//comments
 	registerCommentRoutes(routes, handlers.Comment, middleware.Auth)
+	//sharing
+	registerShareRoutes(routes, handlers.Share, middleware.Auth)
+	//realtime
+	registerRealtimeRoutes(routes, handlers.Realtime, middleware.Auth)
+	//markdown
+	registerMarkdownRoutes(routes, handlers.Markdown, middleware.Auth)
+	//signed storage urls
+	registerStorageRoutes(routes, handlers.Storage)
 }
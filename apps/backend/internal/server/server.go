@@ -13,6 +13,7 @@ import (
 	"github.com/C0deNe0/go-tasker/internal/config"
 	"github.com/C0deNe0/go-tasker/internal/database"
 	"github.com/C0deNe0/go-tasker/internal/lib/job"
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
 	loggerPkg "github.com/C0deNe0/go-tasker/internal/logger"
 )
 
@@ -24,6 +25,7 @@ type Server struct {
 	Redis         *redis.Client
 	httpServer    *http.Server
 	Job           *job.JobService
+	Realtime      *realtime.Broker
 }
 
 func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.LoggerService) (*Server, error) {
@@ -60,6 +62,10 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.Lo
 		return nil, err
 	}
 
+	// realtime change stream
+	realtimeBroker := realtime.NewBroker(redisClient, logger, cfg.Realtime)
+	realtimeBroker.Start()
+
 	server := &Server{
 		Config:        cfg,
 		Logger:        logger,
@@ -67,6 +73,7 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.Lo
 		DB:            db,
 		Redis:         redisClient,
 		Job:           jobService,
+		Realtime:      realtimeBroker,
 	}
 
 	// Start metrics collection
@@ -99,6 +106,11 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	// close realtime subscriptions first so open streams don't hold up the HTTP shutdown
+	if s.Realtime != nil {
+		s.Realtime.Stop()
+	}
+
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
 	}
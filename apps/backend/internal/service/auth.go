@@ -1,18 +1,265 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/model/comment"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
 	"github.com/C0deNe0/go-tasker/internal/server"
 
 	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/organizationmembership"
+	"github.com/clerk/clerk-sdk-go/v2/user"
 )
 
 type AuthService struct {
-	server *server.Server
+	server  *server.Server
+	members *memberCache
 }
 
 func NewAuthService(s *server.Server) *AuthService {
 	clerk.SetKey(s.Config.Auth.SecretKey)
 	return &AuthService{
-		server: s,
+		server:  s,
+		members: newMemberCache(s.Config.Auth.MembershipCacheTTL),
+	}
+}
+
+// organizationMember is a Clerk organization membership together with its user
+type organizationMember struct {
+	UserID     string
+	Membership organization.Membership
+}
+
+// memberCache keeps the member lists fetched from Clerk for a short while, so working
+// out who can see an organization item does not call Clerk on every write
+type memberCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]memberCacheEntry
+}
+
+type memberCacheEntry struct {
+	members   []organizationMember
+	expiresAt time.Time
+}
+
+func newMemberCache(ttl time.Duration) *memberCache {
+	return &memberCache{
+		ttl:     ttl,
+		entries: map[string]memberCacheEntry{},
+	}
+}
+
+func (c *memberCache) get(organizationID string, now time.Time) ([]organizationMember, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[organizationID]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.members, true
+}
+
+// set stores a member list, dropping the lists that have expired in the meantime
+func (c *memberCache) set(organizationID string, members []organizationMember, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[organizationID] = memberCacheEntry{
+		members:   members,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// GetUser looks a user up in Clerk by ID
+func (s *AuthService) GetUser(ctx context.Context, userID string) (*clerk.User, error) {
+	clerkUser, err := user.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clerk user %s: %w", userID, err)
+	}
+
+	return clerkUser, nil
+}
+
+// GetUserIDByEmail resolves the Clerk user registered with the given email address
+func (s *AuthService) GetUserIDByEmail(ctx context.Context, email string) (string, error) {
+	users, err := user.List(ctx, &user.ListParams{
+		EmailAddresses: []string{email},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up clerk user by email: %w", err)
+	}
+
+	if len(users.Users) == 0 {
+		code := "USER_NOT_FOUND"
+		return "", errs.NewNotFoundError("no user is registered with that email", false, &code)
+	}
+
+	return users.Users[0].ID, nil
+}
+
+// ResolveMentionHandles maps @mention handles to Clerk user IDs. Handles containing
+// an @ are matched against email addresses, the rest against usernames; handles
+// that match nobody are left out.
+func (s *AuthService) ResolveMentionHandles(ctx context.Context, handles []string) (map[string]string, error) {
+	usernames, emails := []string{}, []string{}
+	for _, handle := range handles {
+		if comment.IsEmailHandle(handle) {
+			emails = append(emails, handle)
+		} else {
+			usernames = append(usernames, handle)
+		}
+	}
+
+	resolved := map[string]string{}
+	if len(usernames) > 0 {
+		users, err := user.List(ctx, &user.ListParams{
+			Usernames: usernames,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up clerk users by username: %w", err)
+		}
+		for _, clerkUser := range users.Users {
+			if clerkUser.Username != nil {
+				resolved[strings.ToLower(*clerkUser.Username)] = clerkUser.ID
+			}
+		}
+	}
+
+	if len(emails) > 0 {
+		users, err := user.List(ctx, &user.ListParams{
+			EmailAddresses: emails,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up clerk users by email: %w", err)
+		}
+		for _, clerkUser := range users.Users {
+			for _, address := range clerkUser.EmailAddresses {
+				resolved[strings.ToLower(address.EmailAddress)] = clerkUser.ID
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// HasOrganizationPermission reports whether the user belongs to the Clerk organization
+// with a role that grants the permission
+func (s *AuthService) HasOrganizationPermission(ctx context.Context, organizationID string, userID string, permission string) (bool, error) {
+	members, err := s.organizationMembers(ctx, organizationID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, member := range members {
+		if member.UserID == userID && member.Membership.HasPermission(permission) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ListOrganizationMemberIDs returns the members of the Clerk organization whose role
+// grants the permission
+func (s *AuthService) ListOrganizationMemberIDs(ctx context.Context, organizationID string, permission string) ([]string, error) {
+	members, err := s.organizationMembers(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []string{}
+	for _, member := range members {
+		if member.Membership.HasPermission(permission) {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+
+	return userIDs, nil
+}
+
+// organizationMembers returns every member of the Clerk organization, paging through
+// the whole membership list unless a recent copy is cached
+func (s *AuthService) organizationMembers(ctx context.Context, organizationID string) ([]organizationMember, error) {
+	if members, ok := s.members.get(organizationID, time.Now()); ok {
+		return members, nil
+	}
+
+	members := []organizationMember{}
+	limit, offset := int64(100), int64(0)
+	for {
+		params := &organizationmembership.ListParams{OrganizationID: organizationID}
+		params.Limit = clerk.Int64(limit)
+		params.Offset = clerk.Int64(offset)
+
+		memberships, err := organizationmembership.List(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list clerk memberships of organization %s: %w", organizationID, err)
+		}
+
+		for _, membership := range memberships.OrganizationMemberships {
+			if membership.PublicUserData == nil {
+				continue
+			}
+			members = append(members, organizationMember{
+				UserID: membership.PublicUserData.UserID,
+				Membership: organization.Membership{
+					OrganizationID: organizationID,
+					Role:           membership.Role,
+					Permissions:    membership.Permissions,
+				},
+			})
+		}
+
+		offset += int64(len(memberships.OrganizationMemberships))
+		if len(memberships.OrganizationMemberships) == 0 || offset >= memberships.TotalCount {
+			s.members.set(organizationID, members, time.Now())
+			return members, nil
+		}
+	}
+}
+
+// ListUserMemberships returns every Clerk organization membership of the user,
+// paging through the whole list
+func (s *AuthService) ListUserMemberships(ctx context.Context, userID string) ([]organization.Membership, error) {
+	result := []organization.Membership{}
+	limit, offset := int64(100), int64(0)
+	for {
+		params := &user.ListOrganizationMembershipsParams{}
+		params.Limit = clerk.Int64(limit)
+		params.Offset = clerk.Int64(offset)
+
+		memberships, err := user.ListOrganizationMemberships(ctx, userID, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list clerk memberships of user %s: %w", userID, err)
+		}
+
+		for _, membership := range memberships.OrganizationMemberships {
+			if membership.Organization == nil {
+				continue
+			}
+			result = append(result, organization.Membership{
+				OrganizationID: membership.Organization.ID,
+				Role:           membership.Role,
+				Permissions:    membership.Permissions,
+			})
+		}
+
+		offset += int64(len(memberships.OrganizationMemberships))
+		if len(memberships.OrganizationMemberships) == 0 || offset >= memberships.TotalCount {
+			return result, nil
+		}
 	}
 }
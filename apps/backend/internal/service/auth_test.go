@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemberCache(t *testing.T) {
+	storedAt := time.Now()
+	members := []organizationMember{{UserID: "user_1"}}
+
+	tests := []struct {
+		name           string
+		organizationID string
+		at             time.Time
+		wantOk         bool
+	}{
+		{name: "fresh entry", organizationID: "org_1", at: storedAt.Add(10 * time.Second), wantOk: true},
+		{name: "expired entry", organizationID: "org_1", at: storedAt.Add(30 * time.Second)},
+		{name: "unknown organization", organizationID: "org_2", at: storedAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newMemberCache(30 * time.Second)
+			cache.set("org_1", members, storedAt)
+
+			got, ok := cache.get(tt.organizationID, tt.at)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, members, got)
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+}
+
+func TestMemberCacheDropsExpiredEntries(t *testing.T) {
+	cache := newMemberCache(30 * time.Second)
+	storedAt := time.Now()
+
+	cache.set("org_1", nil, storedAt)
+	cache.set("org_2", nil, storedAt.Add(time.Minute))
+
+	assert.NotContains(t, cache.entries, "org_1")
+	assert.Contains(t, cache.entries, "org_2")
+}
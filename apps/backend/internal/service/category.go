@@ -1,9 +1,12 @@
 package service
 
 import (
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
 	"github.com/C0deNe0/go-tasker/internal/model"
 	"github.com/C0deNe0/go-tasker/internal/model/category"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
 	"github.com/C0deNe0/go-tasker/internal/repository"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/google/uuid"
@@ -13,12 +16,20 @@ import (
 type CategoryService struct {
 	server       *server.Server
 	categoryRepo *repository.CategoryRepository
+	shareRepo    *repository.ShareRepository
+	authService  *AuthService
+	todoService  *TodoService
 }
 
-func NewCategoryService(server *server.Server, categoryRepo *repository.CategoryRepository) *CategoryService {
+func NewCategoryService(server *server.Server, categoryRepo *repository.CategoryRepository,
+	shareRepo *repository.ShareRepository, authService *AuthService, todoService *TodoService,
+) *CategoryService {
 	return &CategoryService{
 		server:       server,
 		categoryRepo: categoryRepo,
+		shareRepo:    shareRepo,
+		authService:  authService,
+		todoService:  todoService,
 	}
 }
 
@@ -27,6 +38,13 @@ func (s *CategoryService) CreateCategory(ctx echo.Context, userID string,
 ) (*category.Category, error) {
 	logger := middleware.GetLogger(ctx)
 
+	if payload.ParentCategoryID != nil {
+		if err := s.checkParentCategory(ctx, userID, *payload.ParentCategoryID); err != nil {
+			logger.Warn().Err(err).Msg("parent category validation failed")
+			return nil, err
+		}
+	}
+
 	categoryItem, err := s.categoryRepo.CreateCategory(ctx.Request().Context(), userID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create category")
@@ -42,12 +60,14 @@ func (s *CategoryService) CreateCategory(ctx echo.Context, userID string,
 		Str("color", categoryItem.Color).
 		Msg("Category created successfully")
 
+	publishEvent(ctx, s.server, []string{userID}, realtime.EventCategoryCreated, categoryItem)
+
 	return categoryItem, nil
 }
 
 func (s *CategoryService) GetCategories(ctx echo.Context, userID string,
 	query *category.GetCategoriesQuery,
-) (*model.PaginatedResponse[category.Category], error) {
+) (*model.PaginatedResponse[category.CategoryWithStats], error) {
 	logger := middleware.GetLogger(ctx)
 
 	categories, err := s.categoryRepo.GetCategories(ctx.Request().Context(), userID, query)
@@ -59,10 +79,24 @@ func (s *CategoryService) GetCategories(ctx echo.Context, userID string,
 	return categories, nil
 }
 
+// GetCategoryTree returns the categories of the workspace nested under their parents
+func (s *CategoryService) GetCategoryTree(ctx echo.Context, userID string, payload *category.GetCategoryTreePayload) ([]category.CategoryNode, error) {
+	logger := middleware.GetLogger(ctx)
+
+	includeArchived := payload.IncludeArchived != nil && *payload.IncludeArchived
+	categories, err := s.categoryRepo.GetCategoryTree(ctx.Request().Context(), userID, includeArchived)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch category tree")
+		return nil, err
+	}
+
+	return category.BuildTree(categories), nil
+}
+
 func (s *CategoryService) GetCategoryByID(ctx echo.Context, userID string, categoryID uuid.UUID) (*category.Category, error) {
 	logger := middleware.GetLogger(ctx)
 
-	categoryItem, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, categoryID)
+	categoryItem, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, categoryID, share.RoleViewer)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch category by ID")
 		return nil, err
@@ -90,24 +124,174 @@ func (s *CategoryService) UpdateCategory(ctx echo.Context, userID string, catego
 		Str("name", categoryItem.Name).
 		Msg("Category updated successfully")
 
+	publishEvent(ctx, s.server, categoryAudience(ctx, s.shareRepo, s.authService, categoryItem.ID, userID), realtime.EventCategoryUpdated, categoryItem)
+
 	return categoryItem, nil
 }
 
-func (s *CategoryService) DeleteCategory(ctx echo.Context, userID string, categoryID uuid.UUID) error {
+// MoveCategory puts a category and its subcategories under another parent, or at the top level
+func (s *CategoryService) MoveCategory(ctx echo.Context, userID string, payload *category.MoveCategoryPayload) (*category.Category, error) {
 	logger := middleware.GetLogger(ctx)
 
-	err := s.categoryRepo.DeleteCategory(ctx.Request().Context(), userID, categoryID)
+	if _, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, payload.ID, share.RoleOwner); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch category to move")
+		return nil, err
+	}
+
+	if payload.ParentCategoryID != nil {
+		if err := s.checkParentCategory(ctx, userID, *payload.ParentCategoryID); err != nil {
+			logger.Warn().Err(err).Msg("parent category validation failed")
+			return nil, err
+		}
+	}
+
+	categoryItem, err := s.categoryRepo.MoveCategory(ctx.Request().Context(), userID, payload.ID, payload.ParentCategoryID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to move category")
+		return nil, err
+	}
+
+	parentID := ""
+	if categoryItem.ParentCategoryID != nil {
+		parentID = categoryItem.ParentCategoryID.String()
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "category_moved").
+		Str("category_id", categoryItem.ID.String()).
+		Str("parent_category_id", parentID).
+		Msg("Category moved successfully")
+
+	publishEvent(ctx, s.server, categoryAudience(ctx, s.shareRepo, s.authService, categoryItem.ID, userID), realtime.EventCategoryUpdated, categoryItem)
+
+	return categoryItem, nil
+}
+
+// ReorderCategories sets the order of the categories under a parent, or of the top level ones
+func (s *CategoryService) ReorderCategories(ctx echo.Context, userID string, payload *category.ReorderCategoriesPayload) ([]category.Category, error) {
+	logger := middleware.GetLogger(ctx)
+
+	categories, err := s.categoryRepo.ReorderCategories(ctx.Request().Context(), userID, payload.ParentCategoryID, payload.CategoryIDs)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to reorder categories")
+		return nil, err
+	}
+
+	parentID := ""
+	if payload.ParentCategoryID != nil {
+		parentID = payload.ParentCategoryID.String()
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "categories_reordered").
+		Str("parent_category_id", parentID).
+		Int("count", len(categories)).
+		Msg("Categories reordered successfully")
+
+	for i := range categories {
+		publishEvent(ctx, s.server, categoryAudience(ctx, s.shareRepo, s.authService, categories[i].ID, userID), realtime.EventCategoryUpdated, &categories[i])
+	}
+
+	return categories, nil
+}
+
+// SetCategoryArchived archives a category with its subcategories, which hides them and their todos from
+// default listings, or restores them
+func (s *CategoryService) SetCategoryArchived(ctx echo.Context, userID string, categoryID uuid.UUID, archived bool) (*category.Category, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if _, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, categoryID, share.RoleOwner); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch category to archive")
+		return nil, err
+	}
+
+	categoryItem, err := s.categoryRepo.SetCategoryArchived(ctx.Request().Context(), userID, categoryID, archived)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to set category archived")
+		return nil, err
+	}
+
+	event := "category_archived"
+	if !archived {
+		event = "category_unarchived"
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", event).
+		Str("category_id", categoryItem.ID.String()).
+		Msg("Category archive state updated successfully")
+
+	publishEvent(ctx, s.server, categoryAudience(ctx, s.shareRepo, s.authService, categoryItem.ID, userID), realtime.EventCategoryUpdated, categoryItem)
+
+	return categoryItem, nil
+}
+
+// checkParentCategory makes sure a category may be nested under the parent. Like editing a category,
+// shaping the tree is reserved for the owner and organization admins, and it stays within one workspace.
+func (s *CategoryService) checkParentCategory(ctx echo.Context, userID string, parentID uuid.UUID) error {
+	parent, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, parentID, share.RoleOwner)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to delete category")
 		return err
 	}
 
+	if !organization.InWorkspace(ctx.Request().Context(), parent.OrganizationID) {
+		return errWorkspaceMismatch("parent category")
+	}
+
+	return nil
+}
+
+// DeleteCategory removes a category with its subcategories, applying the payload's strategy to their todos
+func (s *CategoryService) DeleteCategory(ctx echo.Context, userID string, payload *category.DeleteCategoryPayload) (*category.DeleteCategoryResult, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if _, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, payload.ID, share.RoleOwner); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch category to delete")
+		return nil, err
+	}
+
+	if *payload.Strategy == category.DeleteStrategyReassign {
+		// moving todos into a category needs the same role as filing a todo under it
+		target, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.Target, share.RoleEditor)
+		if err != nil {
+			logger.Error().Err(err).Msg("target category validation failed")
+			return nil, err
+		}
+
+		if !organization.InWorkspace(ctx.Request().Context(), target.OrganizationID) {
+			logger.Warn().Msg("target category belongs to another workspace")
+			return nil, errWorkspaceMismatch("target category")
+		}
+	}
+
+	// resolve who can see the category while the shares still exist
+	audience := categoryAudience(ctx, s.shareRepo, s.authService, payload.ID, userID)
+
+	result, storageKeys, err := s.categoryRepo.DeleteCategory(ctx.Request().Context(), userID, payload.ID, *payload.Strategy, payload.Target)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete category")
+		return nil, err
+	}
+
+	s.todoService.enqueueStorageDeletion(ctx.Request().Context(), storageKeys)
+
 	// Business event log
 	eventLogger := middleware.GetLogger(ctx)
 	eventLogger.Info().
 		Str("event", "category_deleted").
-		Str("category_id", categoryID.String()).
+		Str("category_id", payload.ID.String()).
+		Str("strategy", string(result.Strategy)).
+		Int("deleted_categories", result.DeletedCategories).
+		Int("affected_todos", result.AffectedTodos).
 		Msg("Category deleted successfully")
 
-	return nil
+	publishEvent(ctx, s.server, audience, realtime.EventCategoryDeleted, realtime.Deleted{ID: payload.ID})
+
+	return result, nil
 }
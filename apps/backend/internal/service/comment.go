@@ -1,8 +1,18 @@
 package service
 
 import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/lib/job"
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model"
 	"github.com/C0deNe0/go-tasker/internal/model/comment"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
+	"github.com/C0deNe0/go-tasker/internal/model/todo"
 	"github.com/C0deNe0/go-tasker/internal/repository"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/google/uuid"
@@ -13,13 +23,19 @@ type CommentService struct {
 	Server      *server.Server
 	commentRepo *repository.CommentRepository
 	todoRepo    *repository.TodoRepository
+	shareRepo   *repository.ShareRepository
+	authService *AuthService
 }
 
-func NewCommentService(server *server.Server, commentRepo *repository.CommentRepository, todoRepo *repository.TodoRepository) *CommentService {
+func NewCommentService(server *server.Server, commentRepo *repository.CommentRepository, todoRepo *repository.TodoRepository,
+	shareRepo *repository.ShareRepository, authService *AuthService,
+) *CommentService {
 	return &CommentService{
 		Server:      server,
 		commentRepo: commentRepo,
 		todoRepo:    todoRepo,
+		shareRepo:   shareRepo,
+		authService: authService,
 	}
 }
 func (s *CommentService) AddComment(ctx echo.Context, userID string, todoID uuid.UUID,
@@ -27,14 +43,44 @@ func (s *CommentService) AddComment(ctx echo.Context, userID string, todoID uuid
 ) (*comment.Comment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	// Validate todo exists and the user may comment on it
+	todoItem, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleCommenter)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return nil, err
 	}
 
-	commentItem, err := s.AddComment(ctx, userID, todoID, payload)
+	mentionedIDs, err := s.resolveMentions(ctx, todoItem, userID, payload.Content)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to resolve mentions")
+		return nil, err
+	}
+
+	// Replies to a reply join the thread of its top-level comment
+	var parentCommentID *uuid.UUID
+	if payload.ParentCommentID != nil {
+		parent, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, *payload.ParentCommentID)
+		if err != nil {
+			logger.Error().Err(err).Msg("parent comment validation failed")
+			return nil, err
+		}
+
+		if parent.TodoID != todoID {
+			code := "PARENT_COMMENT_MISMATCH"
+			return nil, errs.NewBadRequestError("replies must be on the same todo as their parent comment", true, &code, nil, nil)
+		}
+
+		if parent.IsDeleted() {
+			return nil, errCommentDeleted()
+		}
+
+		parentCommentID = &parent.ID
+		if parent.ParentCommentID != nil {
+			parentCommentID = parent.ParentCommentID
+		}
+	}
+
+	commentItem, err := s.commentRepo.AddComment(ctx.Request().Context(), userID, todoID, parentCommentID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to add comment")
 		return nil, err
@@ -48,20 +94,24 @@ func (s *CommentService) AddComment(ctx echo.Context, userID string, todoID uuid
 		Str("todo_id", todoID.String()).
 		Msg("Comment added successfully")
 
+	publishEvent(ctx, s.Server, todoAudience(ctx, s.shareRepo, s.authService, todoID, userID), realtime.EventCommentCreated, commentItem)
+
+	s.syncMentions(ctx, todoItem, commentItem, mentionedIDs)
+
 	return commentItem, nil
 }
 
-func (s *CommentService) GetCommentsByTodoID(ctx echo.Context, userID string, todoID uuid.UUID) ([]comment.Comment, error) {
+func (s *CommentService) GetCommentsByTodoID(ctx echo.Context, userID string, payload *comment.GetCommentByTodoIDPayload) (*model.CursorPage[comment.PopulatedComment], error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	// Validate todo exists and the user can view it
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleViewer)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return nil, err
 	}
 
-	comments, err := s.commentRepo.GetCommentsByTodoID(ctx.Request().Context(), userID, todoID)
+	comments, err := s.commentRepo.GetCommentsByTodoID(ctx.Request().Context(), userID, payload.TodoID, &payload.CommentPageQuery)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch comments by todo ID")
 		return nil, err
@@ -70,16 +120,56 @@ func (s *CommentService) GetCommentsByTodoID(ctx echo.Context, userID string, to
 	return comments, nil
 }
 
+func (s *CommentService) GetCommentReplies(ctx echo.Context, userID string, payload *comment.GetCommentRepliesPayload) (*model.CursorPage[comment.PopulatedComment], error) {
+	logger := middleware.GetLogger(ctx)
+
+	// Validate comment exists and the user can view it
+	_, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return nil, err
+	}
+
+	replies, err := s.commentRepo.GetCommentReplies(ctx.Request().Context(), userID, payload.ID, &payload.CommentPageQuery)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch comment replies")
+		return nil, err
+	}
+
+	return replies, nil
+}
+
 func (s *CommentService) UpdateComment(ctx echo.Context, userID string, commentID uuid.UUID, content string) (*comment.Comment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate comment exists and belongs to user
-	_, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
+	// Validate comment exists and was written by the user
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
 	if err != nil {
 		logger.Error().Err(err).Msg("comment validation failed")
 		return nil, err
 	}
 
+	if existing.UserID != userID {
+		logger.Warn().Msg("user tried to edit someone else's comment")
+		return nil, errs.NewForbiddenError("you can only edit your own comments", false)
+	}
+
+	if existing.IsDeleted() {
+		return nil, errCommentDeleted()
+	}
+
+	todoItem, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, existing.TodoID, share.RoleCommenter)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	mentionedIDs, err := s.resolveMentions(ctx, todoItem, userID, content)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to resolve mentions")
+		return nil, err
+	}
+
 	commentItem, err := s.commentRepo.UpdateComment(ctx.Request().Context(), userID, commentID, content)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update comment")
@@ -93,19 +183,73 @@ func (s *CommentService) UpdateComment(ctx echo.Context, userID string, commentI
 		Str("comment_id", commentItem.ID.String()).
 		Msg("Comment updated successfully")
 
+	publishEvent(ctx, s.Server, todoAudience(ctx, s.shareRepo, s.authService, commentItem.TodoID, userID), realtime.EventCommentUpdated, commentItem.Redacted())
+
+	s.syncMentions(ctx, todoItem, commentItem, mentionedIDs)
+
 	return commentItem, nil
 }
 
 func (s *CommentService) DeleteComment(ctx echo.Context, userID string, commentID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate comment exists and belongs to user
-	_, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
+	// Validate comment exists and was written by the user
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
 	if err != nil {
 		logger.Error().Err(err).Msg("comment validation failed")
 		return err
 	}
 
+	if existing.IsDeleted() {
+		code := "COMMENT_NOT_FOUND"
+		return errs.NewNotFoundError("comment not found", false, &code)
+	}
+
+	// The todo's owner may remove other people's comments as a moderator
+	if existing.UserID != userID {
+		isOwner, err := s.isTodoOwner(ctx, userID, existing.TodoID)
+		if err != nil {
+			logger.Error().Err(err).Msg("todo validation failed")
+			return err
+		}
+
+		if !isOwner {
+			logger.Warn().Msg("user tried to delete someone else's comment")
+			return errs.NewForbiddenError("you can only delete your own comments", false)
+		}
+
+		return s.moderateDeleteComment(ctx, userID, existing)
+	}
+
+	hasReplies, err := s.commentRepo.HasReplies(ctx.Request().Context(), commentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check comment replies")
+		return err
+	}
+
+	audience := todoAudience(ctx, s.shareRepo, s.authService, existing.TodoID, userID)
+
+	// A comment with replies stays behind as a placeholder so the thread survives
+	if hasReplies {
+		placeholder, err := s.commentRepo.SoftDeleteComment(ctx.Request().Context(), userID, commentID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to delete comment")
+			return err
+		}
+
+		// Business event log
+		eventLogger := middleware.GetLogger(ctx)
+		eventLogger.Info().
+			Str("event", "comment_deleted").
+			Str("comment_id", commentID.String()).
+			Bool("placeholder", true).
+			Msg("Comment deleted successfully")
+
+		publishEvent(ctx, s.Server, audience, realtime.EventCommentUpdated, placeholder)
+
+		return nil
+	}
+
 	err = s.commentRepo.DelelteComment(ctx.Request().Context(), userID, commentID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete comment")
@@ -119,5 +263,311 @@ func (s *CommentService) DeleteComment(ctx echo.Context, userID string, commentI
 		Str("comment_id", commentID.String()).
 		Msg("Comment deleted successfully")
 
+	publishEvent(ctx, s.Server, audience, realtime.EventCommentDeleted, realtime.Deleted{
+		ID:     commentID,
+		TodoID: &existing.TodoID,
+	})
+
+	// The last reply of a deleted comment takes the placeholder with it
+	if existing.ParentCommentID != nil {
+		pruned, err := s.commentRepo.PruneDeletedComment(ctx.Request().Context(), *existing.ParentCommentID)
+		if err != nil {
+			logger.Warn().Err(err).Msg("failed to prune deleted parent comment")
+		} else if pruned {
+			publishEvent(ctx, s.Server, audience, realtime.EventCommentDeleted, realtime.Deleted{
+				ID:     *existing.ParentCommentID,
+				TodoID: &existing.TodoID,
+			})
+		}
+	}
+
 	return nil
 }
+
+func (s *CommentService) moderateDeleteComment(ctx echo.Context, moderatorID string, existing *comment.Comment) error {
+	logger := middleware.GetLogger(ctx)
+
+	placeholder, err := s.commentRepo.ModerateDeleteComment(ctx.Request().Context(), moderatorID, existing.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete comment as moderator")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_moderated").
+		Str("action", "delete").
+		Str("comment_id", existing.ID.String()).
+		Str("author_id", existing.UserID).
+		Msg("Comment deleted by moderator")
+
+	publishEvent(ctx, s.Server, todoAudience(ctx, s.shareRepo, s.authService, existing.TodoID, moderatorID), realtime.EventCommentUpdated, placeholder)
+
+	return nil
+}
+
+// SetCommentHidden lets the todo's owner collapse a comment, or restore it
+func (s *CommentService) SetCommentHidden(ctx echo.Context, userID string, commentID uuid.UUID, hidden bool) (*comment.Comment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return nil, err
+	}
+
+	if existing.IsDeleted() {
+		return nil, errCommentDeleted()
+	}
+
+	isOwner, err := s.isTodoOwner(ctx, userID, existing.TodoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	if !isOwner {
+		logger.Warn().Msg("user tried to moderate a comment without owning the todo")
+		return nil, errs.NewForbiddenError("only the owner of the todo can moderate its comments", false)
+	}
+
+	commentItem, err := s.commentRepo.SetCommentHidden(ctx.Request().Context(), userID, commentID, hidden)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to moderate comment")
+		return nil, err
+	}
+
+	action := "unhide"
+	if hidden {
+		action = "hide"
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_moderated").
+		Str("action", action).
+		Str("comment_id", commentID.String()).
+		Str("author_id", existing.UserID).
+		Msg("Comment moderated successfully")
+
+	publishEvent(ctx, s.Server, todoAudience(ctx, s.shareRepo, s.authService, existing.TodoID, userID), realtime.EventCommentUpdated, commentItem.Redacted())
+
+	return commentItem, nil
+}
+
+func (s *CommentService) GetCommentRevisions(ctx echo.Context, userID string, commentID uuid.UUID) ([]comment.Revision, error) {
+	logger := middleware.GetLogger(ctx)
+
+	// Validate comment exists and the user can view it
+	_, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return nil, err
+	}
+
+	revisions, err := s.commentRepo.GetCommentRevisions(ctx.Request().Context(), userID, commentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch comment revisions")
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// isTodoOwner reports whether the user holds the owner role on the todo, which
+// org admins do for their organization's todos as well
+func (s *CommentService) isTodoOwner(ctx echo.Context, userID string, todoID uuid.UUID) (bool, error) {
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleOwner)
+	if err == nil {
+		return true, nil
+	}
+
+	var httpErr *errs.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Status == http.StatusForbidden {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// AddReaction reacts to a comment as someone who may comment on its todo
+func (s *CommentService) AddReaction(ctx echo.Context, userID string, payload *comment.AddReactionPayload) (*comment.PopulatedComment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return nil, err
+	}
+
+	if existing.IsDeleted() {
+		return nil, errCommentDeleted()
+	}
+
+	_, err = s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, existing.TodoID, share.RoleCommenter)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	reaction, err := s.commentRepo.AddReaction(ctx.Request().Context(), userID, payload.ID, payload.Emoji)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to add reaction")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_reaction_added").
+		Str("comment_id", payload.ID.String()).
+		Str("emoji", payload.Emoji).
+		Msg("Reaction added successfully")
+
+	publishEvent(ctx, s.Server, todoAudience(ctx, s.shareRepo, s.authService, existing.TodoID, userID), realtime.EventCommentReactionAdded, reaction)
+
+	return s.commentRepo.GetPopulatedCommentByID(ctx.Request().Context(), userID, payload.ID)
+}
+
+func (s *CommentService) RemoveReaction(ctx echo.Context, userID string, payload *comment.RemoveReactionPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return err
+	}
+
+	err = s.commentRepo.RemoveReaction(ctx.Request().Context(), userID, payload.ID, payload.Emoji)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to remove reaction")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_reaction_removed").
+		Str("comment_id", payload.ID.String()).
+		Str("emoji", payload.Emoji).
+		Msg("Reaction removed successfully")
+
+	publishEvent(ctx, s.Server, todoAudience(ctx, s.shareRepo, s.authService, existing.TodoID, userID), realtime.EventCommentReactionRemoved, comment.Reaction{
+		CommentID: payload.ID,
+		UserID:    userID,
+		Emoji:     payload.Emoji,
+	})
+
+	return nil
+}
+
+func (s *CommentService) GetMentions(ctx echo.Context, userID string, query *comment.GetMentionsQuery) (*model.PaginatedResponse[comment.PopulatedMention], error) {
+	logger := middleware.GetLogger(ctx)
+
+	memberships, err := s.authService.ListUserMemberships(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch organization memberships")
+		return nil, err
+	}
+
+	mentions, err := s.commentRepo.GetMentionsOfUser(ctx.Request().Context(), userID, memberships, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch mentions")
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+// resolveMentions turns the @handles in the content into the IDs of the users who
+// can see the todo. Unknown handles, users without access and the author are skipped.
+func (s *CommentService) resolveMentions(ctx echo.Context, todoItem *todo.Todo, authorID string, content string) ([]string, error) {
+	mentionedIDs := []string{}
+
+	handles := comment.ParseMentions(content)
+	if len(handles) == 0 {
+		return mentionedIDs, nil
+	}
+
+	resolved, err := s.authService.ResolveMentionHandles(ctx.Request().Context(), handles)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, handle := range handles {
+		mentionedID, ok := resolved[handle]
+		if !ok || mentionedID == authorID || slices.Contains(mentionedIDs, mentionedID) {
+			continue
+		}
+
+		hasAccess, err := canAccessTodo(ctx, s.shareRepo, s.authService, todoItem, mentionedID)
+		if err != nil {
+			return nil, err
+		}
+		if hasAccess {
+			mentionedIDs = append(mentionedIDs, mentionedID)
+		}
+	}
+
+	return mentionedIDs, nil
+}
+
+// syncMentions stores the comment's current mentions and notifies only the users
+// mentioned for the first time. The comment is already saved, so failures are logged.
+func (s *CommentService) syncMentions(ctx echo.Context, todoItem *todo.Todo, commentItem *comment.Comment, mentionedIDs []string) {
+	logger := middleware.GetLogger(ctx)
+
+	mentions, err := s.commentRepo.SyncCommentMentions(ctx.Request().Context(), commentItem, mentionedIDs)
+	if err != nil {
+		logger.Error().Err(err).Str("comment_id", commentItem.ID.String()).Msg("failed to store comment mentions")
+		return
+	}
+
+	for _, mention := range mentions {
+		publishEvent(ctx, s.Server, []string{mention.UserID}, realtime.EventCommentMentioned, comment.PopulatedMention{
+			Mention:   mention,
+			TodoTitle: todoItem.Title,
+			Comment:   *commentItem,
+		})
+
+		task, err := job.NewCommentMentionEmailTask(mention.UserID, commentItem.UserID, todoItem.ID.String(), todoItem.Title, mentionExcerpt(commentItem.Content))
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create comment mention email task")
+			continue
+		}
+
+		if _, err := s.Server.Job.Client.Enqueue(task); err != nil {
+			logger.Error().Err(err).Str("mentioned_user_id", mention.UserID).Msg("failed to enqueue comment mention email")
+		}
+	}
+
+	if len(mentions) > 0 {
+		// Business event log
+		eventLogger := middleware.GetLogger(ctx)
+		eventLogger.Info().
+			Str("event", "comment_mentions_notified").
+			Str("comment_id", commentItem.ID.String()).
+			Int("mentioned", len(mentions)).
+			Msg("Comment mentions notified successfully")
+	}
+}
+
+// mentionExcerpt shortens a comment for notification emails
+func mentionExcerpt(content string) string {
+	const maxRunes = 200
+
+	runes := []rune(content)
+	if len(runes) <= maxRunes {
+		return content
+	}
+
+	return string(runes[:maxRunes]) + "…"
+}
+
+// errCommentDeleted rejects changes to a comment that only remains as a placeholder
+func errCommentDeleted() error {
+	code := "COMMENT_DELETED"
+	return errs.NewBadRequestError("this comment has been deleted", true, &code, nil, nil)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/repository"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// publishEvent pushes a change to the realtime stream of every user in the audience.
+// The change is already committed, so a failure is logged and never fails the request.
+func publishEvent(ctx echo.Context, s *server.Server, audience []string, eventType realtime.EventType, data any) {
+	if s.Realtime == nil {
+		return
+	}
+
+	for _, userID := range audience {
+		if err := s.Realtime.Publish(ctx.Request().Context(), userID, eventType, data); err != nil {
+			middleware.GetLogger(ctx).Warn().
+				Err(err).
+				Str("event_type", string(eventType)).
+				Str("recipient_id", userID).
+				Msg("failed to publish realtime event")
+		}
+	}
+}
+
+// todoAudience resolves everyone who can see a todo, always including the acting user
+func todoAudience(ctx echo.Context, shareRepo *repository.ShareRepository, authService *AuthService,
+	todoID uuid.UUID, actorID string,
+) []string {
+	audience, err := resolveTodoAudience(ctx.Request().Context(), shareRepo, authService, todoID)
+	if err != nil {
+		middleware.GetLogger(ctx).Warn().Err(err).Str("todo_id", todoID.String()).Msg("failed to resolve todo audience")
+	}
+
+	return withActor(audience, actorID)
+}
+
+// resolveTodoAudience returns the todo's owner and grantees and, for an
+// organization todo, the members whose org role lets them read todos. What was
+// resolved before a failure is returned with the error.
+func resolveTodoAudience(ctx context.Context, shareRepo *repository.ShareRepository, authService *AuthService,
+	todoID uuid.UUID,
+) ([]string, error) {
+	audience, err := shareRepo.GetTodoAudience(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationID, err := shareRepo.GetTodoOrganizationID(ctx, todoID)
+	if err != nil || organizationID == nil {
+		return audience, err
+	}
+
+	members, err := authService.ListOrganizationMemberIDs(ctx, *organizationID, organization.PermissionTodosRead)
+	return withMembers(audience, members), err
+}
+
+// categoryAudience resolves everyone who can see a category, always including the acting user
+func categoryAudience(ctx echo.Context, shareRepo *repository.ShareRepository, authService *AuthService,
+	categoryID uuid.UUID, actorID string,
+) []string {
+	audience, err := resolveCategoryAudience(ctx.Request().Context(), shareRepo, authService, categoryID)
+	if err != nil {
+		middleware.GetLogger(ctx).Warn().Err(err).Str("category_id", categoryID.String()).Msg("failed to resolve category audience")
+	}
+
+	return withActor(audience, actorID)
+}
+
+// resolveCategoryAudience returns the category's owner and grantees and, for an
+// organization category, the members whose org role lets them read categories
+func resolveCategoryAudience(ctx context.Context, shareRepo *repository.ShareRepository, authService *AuthService,
+	categoryID uuid.UUID,
+) ([]string, error) {
+	audience, err := shareRepo.GetCategoryAudience(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationID, err := shareRepo.GetCategoryOrganizationID(ctx, categoryID)
+	if err != nil || organizationID == nil {
+		return audience, err
+	}
+
+	members, err := authService.ListOrganizationMemberIDs(ctx, *organizationID, organization.PermissionCategoriesRead)
+	return withMembers(audience, members), err
+}
+
+func withMembers(audience []string, members []string) []string {
+	for _, member := range members {
+		if !slices.Contains(audience, member) {
+			audience = append(audience, member)
+		}
+	}
+	return audience
+}
+
+func withActor(audience []string, actorID string) []string {
+	if slices.Contains(audience, actorID) {
+		return audience
+	}
+	return append(slices.Clip(audience), actorID)
+}
+
+// publishBackgroundEvent is publishEvent for work that runs outside a request, such as background jobs
+func publishBackgroundEvent(ctx context.Context, s *server.Server, audience []string, eventType realtime.EventType, data any) {
+	if s.Realtime == nil {
+		return
+	}
+
+	for _, userID := range audience {
+		if err := s.Realtime.Publish(ctx, userID, eventType, data); err != nil {
+			s.Logger.Warn().
+				Err(err).
+				Str("event_type", string(eventType)).
+				Str("recipient_id", userID).
+				Msg("failed to publish realtime event")
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 
-	"github.com/C0deNe0/go-tasker/internal/lib/aws"
+	"github.com/C0deNe0/go-tasker/internal/lib/extractor"
 	"github.com/C0deNe0/go-tasker/internal/lib/job"
+	"github.com/C0deNe0/go-tasker/internal/lib/scanner"
+	"github.com/C0deNe0/go-tasker/internal/lib/storage"
 	"github.com/C0deNe0/go-tasker/internal/repository"
 	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/hibiken/asynq"
 )
 
 type Services struct {
@@ -15,21 +19,84 @@ type Services struct {
 	Todo     *TodoService
 	Comment  *CommentService
 	Category *CategoryService
+	Share    *ShareService
+	Storage  storage.Storage
 }
 
 func NewServices(s *server.Server, repos *repository.Repositories) (*Services, error) {
 	authService := NewAuthService(s)
 
-	awsClient, err := aws.NewAWS(s)
+	blobStorage, err := storage.New(s)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AWS client: %w", err)
+		return nil, fmt.Errorf("failed to create %s storage: %w", s.Config.Storage.Driver, err)
+	}
+
+	attachmentScanner, err := scanner.New(s.Config.Scanner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment scanner: %w", err)
+	}
+
+	textExtractor, err := extractor.New(s.Config.Extractor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment text extractor: %w", err)
+	}
+
+	todoService := NewTodoService(s, repos.Todo, repos.Category, repos.Share, authService, blobStorage, attachmentScanner, textExtractor)
+	s.Job.Handle(job.TaskScanAttachment, todoService.handleScanAttachmentTask)
+	s.Job.Handle(job.TaskProcessAttachment, todoService.handleProcessAttachmentTask)
+	s.Job.Handle(job.TaskExtractAttachmentText, todoService.handleExtractAttachmentTextTask)
+
+	// sweep direct uploads that were never confirmed
+	cleanupInterval := s.Config.Attachment.CleanupInterval
+	s.Job.Handle(job.TaskCleanupPendingUploads, todoService.handleCleanupPendingUploadsTask)
+	if err := s.Job.Schedule("@every "+cleanupInterval.String(), job.NewCleanupPendingUploadsTask(cleanupInterval)); err != nil {
+		return nil, fmt.Errorf("failed to schedule pending upload cleanup: %w", err)
+	}
+
+	// build large attachment archives in the background and sweep them once expired
+	s.Job.Handle(job.TaskBuildAttachmentArchive, todoService.handleBuildAttachmentArchiveTask)
+	s.Job.Handle(job.TaskCleanupArchives, todoService.handleCleanupArchivesTask)
+	if err := s.Job.Schedule("@every "+cleanupInterval.String(), job.NewCleanupArchivesTask(cleanupInterval)); err != nil {
+		return nil, fmt.Errorf("failed to schedule attachment archive cleanup: %w", err)
+	}
+
+	// delete objects whose records are gone and sweep for any the deletes missed
+	reconcileInterval := s.Config.Attachment.ReconcileInterval
+	s.Job.Handle(job.TaskDeleteStorageObjects, todoService.handleDeleteStorageObjectsTask)
+	s.Job.Handle(job.TaskReconcileStorage, todoService.handleReconcileStorageTask)
+	reconcileTask, err := job.NewReconcileStorageTask(reconcileInterval, s.Config.Attachment.ReconcileDryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage reconciliation task: %w", err)
+	}
+	if err := s.Job.Schedule("@every "+reconcileInterval.String(), reconcileTask); err != nil {
+		return nil, fmt.Errorf("failed to schedule storage reconciliation: %w", err)
+	}
+
+	// move attachments stored before deduplication onto blobs, usually a no-op
+	s.Job.Handle(job.TaskBackfillAttachmentBlobs, todoService.handleBackfillAttachmentBlobsTask)
+	if err := s.Job.Schedule("@every "+reconcileInterval.String(), job.NewBackfillAttachmentBlobsTask()); err != nil {
+		return nil, fmt.Errorf("failed to schedule attachment blob backfill: %w", err)
+	}
+	if _, err := s.Job.Client.Enqueue(job.NewBackfillAttachmentBlobsTask()); err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		s.Logger.Warn().Err(err).Msg("failed to enqueue attachment blob backfill")
+	}
+
+	// read the text of attachments uploaded before extraction or whose extraction was never queued
+	s.Job.Handle(job.TaskBackfillAttachmentText, todoService.handleBackfillAttachmentTextTask)
+	if err := s.Job.Schedule("@every "+reconcileInterval.String(), job.NewBackfillAttachmentTextTask()); err != nil {
+		return nil, fmt.Errorf("failed to schedule attachment text backfill: %w", err)
+	}
+	if _, err := s.Job.Client.Enqueue(job.NewBackfillAttachmentTextTask()); err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		s.Logger.Warn().Err(err).Msg("failed to enqueue attachment text backfill")
 	}
 
 	return &Services{
 		Job:      s.Job,
 		Auth:     authService,
-		Todo:     NewTodoService(s, repos.Todo, repos.Category, awsClient),
-		Comment:  NewCommentService(s, repos.Comment, repos.Todo),
-		Category: NewCategoryService(s, repos.Category),
+		Todo:     todoService,
+		Comment:  NewCommentService(s, repos.Comment, repos.Todo, repos.Share, authService),
+		Category: NewCategoryService(s, repos.Category, repos.Share, authService, todoService),
+		Share:    NewShareService(s, repos.Share, repos.Todo, repos.Category, authService),
+		Storage:  blobStorage,
 	}, nil
 }
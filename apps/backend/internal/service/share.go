@@ -0,0 +1,230 @@
+package service
+
+import (
+	"github.com/C0deNe0/go-tasker/internal/errs"
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
+	"github.com/C0deNe0/go-tasker/internal/middleware"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
+	"github.com/C0deNe0/go-tasker/internal/repository"
+	"github.com/C0deNe0/go-tasker/internal/server"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ShareService struct {
+	server       *server.Server
+	shareRepo    *repository.ShareRepository
+	todoRepo     *repository.TodoRepository
+	categoryRepo *repository.CategoryRepository
+	authService  *AuthService
+}
+
+func NewShareService(server *server.Server, shareRepo *repository.ShareRepository, todoRepo *repository.TodoRepository,
+	categoryRepo *repository.CategoryRepository, authService *AuthService,
+) *ShareService {
+	return &ShareService{
+		server:       server,
+		shareRepo:    shareRepo,
+		todoRepo:     todoRepo,
+		categoryRepo: categoryRepo,
+		authService:  authService,
+	}
+}
+
+func (s *ShareService) ShareCategory(ctx echo.Context, userID string, payload *share.ShareCategoryPayload) (*share.Share, error) {
+	logger := middleware.GetLogger(ctx)
+
+	// Only the owner can hand out access to a category
+	_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, payload.CategoryID, share.RoleOwner)
+	if err != nil {
+		logger.Error().Err(err).Msg("category validation failed")
+		return nil, err
+	}
+
+	granteeID, err := s.resolveGrantee(ctx, userID, &payload.CreateShareBody)
+	if err != nil {
+		return nil, err
+	}
+
+	shareItem, err := s.shareRepo.CreateShare(ctx.Request().Context(), userID, granteeID, &payload.CategoryID, nil, payload.Role)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to share category")
+		return nil, err
+	}
+
+	s.logShareEvent(ctx, "category_shared", shareItem)
+	publishEvent(ctx, s.server, []string{granteeID}, realtime.EventShareInvited, shareItem)
+
+	return shareItem, nil
+}
+
+func (s *ShareService) ShareTodo(ctx echo.Context, userID string, payload *share.ShareTodoPayload) (*share.Share, error) {
+	logger := middleware.GetLogger(ctx)
+
+	// Only the owner can hand out access to a todo
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleOwner)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	granteeID, err := s.resolveGrantee(ctx, userID, &payload.CreateShareBody)
+	if err != nil {
+		return nil, err
+	}
+
+	shareItem, err := s.shareRepo.CreateShare(ctx.Request().Context(), userID, granteeID, nil, &payload.TodoID, payload.Role)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to share todo")
+		return nil, err
+	}
+
+	s.logShareEvent(ctx, "todo_shared", shareItem)
+	publishEvent(ctx, s.server, []string{granteeID}, realtime.EventShareInvited, shareItem)
+
+	return shareItem, nil
+}
+
+// resolveGrantee turns the invitation target into a user ID, looking emails up in Clerk
+func (s *ShareService) resolveGrantee(ctx echo.Context, userID string, body *share.CreateShareBody) (string, error) {
+	logger := middleware.GetLogger(ctx)
+
+	granteeID := ""
+	if body.GranteeID != nil {
+		granteeID = *body.GranteeID
+	} else {
+		resolvedID, err := s.authService.GetUserIDByEmail(ctx.Request().Context(), *body.Email)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to resolve grantee by email")
+			return "", err
+		}
+		granteeID = resolvedID
+	}
+
+	if granteeID == userID {
+		code := "CANNOT_SHARE_WITH_SELF"
+		return "", errs.NewBadRequestError("you cannot share an item with yourself", true, &code, nil, nil)
+	}
+
+	return granteeID, nil
+}
+
+func (s *ShareService) GetCategoryShares(ctx echo.Context, userID string, categoryID uuid.UUID) ([]share.Share, error) {
+	logger := middleware.GetLogger(ctx)
+
+	_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, categoryID, share.RoleOwner)
+	if err != nil {
+		logger.Error().Err(err).Msg("category validation failed")
+		return nil, err
+	}
+
+	shares, err := s.shareRepo.GetSharesForCategory(ctx.Request().Context(), userID, categoryID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch category shares")
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (s *ShareService) GetTodoShares(ctx echo.Context, userID string, todoID uuid.UUID) ([]share.Share, error) {
+	logger := middleware.GetLogger(ctx)
+
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleOwner)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	shares, err := s.shareRepo.GetSharesForTodo(ctx.Request().Context(), userID, todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch todo shares")
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (s *ShareService) GetSharedWithMe(ctx echo.Context, userID string, query *share.GetSharedWithMeQuery) ([]share.PopulatedShare, error) {
+	logger := middleware.GetLogger(ctx)
+
+	shares, err := s.shareRepo.GetSharedWithMe(ctx.Request().Context(), userID, *query.Status)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch shared items")
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (s *ShareService) UpdateShare(ctx echo.Context, userID string, payload *share.UpdateSharePayload) (*share.Share, error) {
+	logger := middleware.GetLogger(ctx)
+
+	shareItem, err := s.shareRepo.UpdateShareRole(ctx.Request().Context(), userID, payload.ID, payload.Role)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update share")
+		return nil, err
+	}
+
+	s.logShareEvent(ctx, "share_updated", shareItem)
+	publishEvent(ctx, s.server, []string{shareItem.GranteeID}, realtime.EventShareUpdated, shareItem)
+
+	return shareItem, nil
+}
+
+func (s *ShareService) AcceptShare(ctx echo.Context, userID string, shareID uuid.UUID) (*share.Share, error) {
+	return s.respondToShare(ctx, userID, shareID, share.StatusAccepted)
+}
+
+func (s *ShareService) DeclineShare(ctx echo.Context, userID string, shareID uuid.UUID) (*share.Share, error) {
+	return s.respondToShare(ctx, userID, shareID, share.StatusDeclined)
+}
+
+func (s *ShareService) respondToShare(ctx echo.Context, userID string, shareID uuid.UUID, status share.Status) (*share.Share, error) {
+	logger := middleware.GetLogger(ctx)
+
+	shareItem, err := s.shareRepo.RespondToShare(ctx.Request().Context(), userID, shareID, status)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to respond to share")
+		return nil, err
+	}
+
+	s.logShareEvent(ctx, "share_"+string(status), shareItem)
+	publishEvent(ctx, s.server, []string{shareItem.OwnerID, shareItem.GranteeID}, realtime.EventShareUpdated, shareItem)
+
+	return shareItem, nil
+}
+
+// DeleteShare revokes a grant when called by its owner and leaves it when called by its grantee
+func (s *ShareService) DeleteShare(ctx echo.Context, userID string, shareID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	shareItem, err := s.shareRepo.GetShareByID(ctx.Request().Context(), shareID)
+	if err != nil {
+		logger.Error().Err(err).Msg("share validation failed")
+		return err
+	}
+
+	err = s.shareRepo.DeleteShare(ctx.Request().Context(), userID, shareID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete share")
+		return err
+	}
+
+	s.logShareEvent(ctx, "share_deleted", shareItem)
+	publishEvent(ctx, s.server, []string{shareItem.OwnerID, shareItem.GranteeID}, realtime.EventShareDeleted, realtime.Deleted{ID: shareID})
+
+	return nil
+}
+
+func (s *ShareService) logShareEvent(ctx echo.Context, event string, shareItem *share.Share) {
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", event).
+		Str("share_id", shareItem.ID.String()).
+		Str("grantee_id", shareItem.GranteeID).
+		Str("role", string(shareItem.Role)).
+		Str("status", string(shareItem.Status)).
+		Msg("Share changed")
+}
@@ -1,18 +1,41 @@
 package service
 
 import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
 	"context"
-	"mime/multipart"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/C0deNe0/go-tasker/internal/errs"
-	"github.com/C0deNe0/go-tasker/internal/lib/aws"
+	"github.com/C0deNe0/go-tasker/internal/lib/extractor"
+	"github.com/C0deNe0/go-tasker/internal/lib/filename"
+	"github.com/C0deNe0/go-tasker/internal/lib/job"
+	"github.com/C0deNe0/go-tasker/internal/lib/markdown"
+	"github.com/C0deNe0/go-tasker/internal/lib/media"
+	"github.com/C0deNe0/go-tasker/internal/lib/realtime"
+	"github.com/C0deNe0/go-tasker/internal/lib/scanner"
+	"github.com/C0deNe0/go-tasker/internal/lib/storage"
 	"github.com/C0deNe0/go-tasker/internal/middleware"
 	"github.com/C0deNe0/go-tasker/internal/model"
+	"github.com/C0deNe0/go-tasker/internal/model/organization"
+	"github.com/C0deNe0/go-tasker/internal/model/share"
 	"github.com/C0deNe0/go-tasker/internal/model/todo"
 	"github.com/C0deNe0/go-tasker/internal/repository"
 	"github.com/C0deNe0/go-tasker/internal/server"
 	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 )
@@ -21,15 +44,26 @@ type TodoService struct {
 	server       *server.Server
 	todoRepo     *repository.TodoRepository
 	categoryRepo *repository.CategoryRepository
-	awsClient    *aws.AWS
+	shareRepo    *repository.ShareRepository
+	authService  *AuthService
+	storage      storage.Storage
+	scanner      scanner.Scanner
+	extractor    extractor.Extractor
 }
 
-func NewTodoService(server *server.Server, todoRepo *repository.TodoRepository, categroyRepo *repository.CategoryRepository, awsClient *aws.AWS) *TodoService {
+func NewTodoService(server *server.Server, todoRepo *repository.TodoRepository, categroyRepo *repository.CategoryRepository,
+	shareRepo *repository.ShareRepository, authService *AuthService, blobStorage storage.Storage, attachmentScanner scanner.Scanner,
+	textExtractor extractor.Extractor,
+) *TodoService {
 	return &TodoService{
 		server:       server,
 		todoRepo:     todoRepo,
 		categoryRepo: categroyRepo,
-		awsClient:    awsClient,
+		shareRepo:    shareRepo,
+		authService:  authService,
+		storage:      blobStorage,
+		scanner:      attachmentScanner,
+		extractor:    textExtractor,
 	}
 }
 
@@ -37,7 +71,7 @@ func (s *TodoService) CreateTodo(ctx echo.Context, userID string, payload *todo.
 	logger := middleware.GetLogger(ctx)
 
 	if payload.ParentTodoID != nil {
-		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, *payload.ParentTodoID)
+		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, *payload.ParentTodoID, share.RoleEditor)
 		if err != nil {
 			logger.Error().Err(err).Msg("parent todo validation failed ")
 			return nil, err
@@ -48,18 +82,28 @@ func (s *TodoService) CreateTodo(ctx echo.Context, userID string, payload *todo.
 			logger.Warn().Msg("parent todo cannot have children")
 			return nil, err
 		}
+
+		if !organization.InWorkspace(ctx.Request().Context(), parentTodo.OrganizationID) {
+			logger.Warn().Msg("parent todo belongs to another workspace")
+			return nil, errWorkspaceMismatch("parent todo")
+		}
 	}
 
 	if payload.CategoryID != nil {
-		_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.CategoryID)
+		categoryItem, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.CategoryID, share.RoleEditor)
 		if err != nil {
 			logger.Error().Err(err).Msg("category validation failed")
 			return nil, err
 
 		}
+
+		if !organization.InWorkspace(ctx.Request().Context(), categoryItem.OrganizationID) {
+			logger.Warn().Msg("category belongs to another workspace")
+			return nil, errWorkspaceMismatch("category")
+		}
 	}
 
-	todoItem, err := s.todoRepo.CreateTodo(context.Background(), userID, payload)
+	todoItem, err := s.todoRepo.CreateTodo(ctx.Request().Context(), userID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create todo")
 		return nil, err
@@ -81,6 +125,8 @@ func (s *TodoService) CreateTodo(ctx echo.Context, userID string, payload *todo.
 		Str("priority", string(todoItem.Priority)).
 		Msg("Todo creted successfullyt")
 
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, todoItem.ID, userID), realtime.EventTodoCreated, todoItem)
+
 	return todoItem, nil
 }
 
@@ -93,6 +139,9 @@ func (s *TodoService) GetTodoByID(ctx echo.Context, userID string, todoID uuid.U
 		return nil, err
 
 	}
+
+	s.presignTodoThumbnails(ctx.Request().Context(), todoItem)
+
 	return todoItem, nil
 }
 
@@ -106,15 +155,25 @@ func (s *TodoService) GetTodos(ctx echo.Context, userID string, query *todo.GetT
 
 	}
 
+	for i := range result.Data {
+		s.presignTodoThumbnails(ctx.Request().Context(), &result.Data[i])
+	}
+
 	return result, nil
 }
 
 func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
 	logger := middleware.GetLogger(ctx)
 
+	existing, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.ID, share.RoleEditor)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
 	// Validate parent todo exists and belongs to user (if provided)
 	if payload.ParentTodoID != nil {
-		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, *payload.ParentTodoID)
+		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, *payload.ParentTodoID, share.RoleEditor)
 		if err != nil {
 			logger.Error().Err(err).Msg("parent todo validation failed")
 			return nil, err
@@ -132,17 +191,27 @@ func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.
 			return nil, err
 		}
 
+		if !organization.SameWorkspace(existing.OrganizationID, parentTodo.OrganizationID) {
+			logger.Warn().Msg("parent todo belongs to another workspace")
+			return nil, errWorkspaceMismatch("parent todo")
+		}
+
 		logger.Debug().Msg("parent todo validation passed")
 	}
 
 	// Validate category exists and belongs to user (if provided)
 	if payload.CategoryID != nil {
-		_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.CategoryID)
+		categoryItem, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.CategoryID, share.RoleEditor)
 		if err != nil {
 			logger.Error().Err(err).Msg("category validation failed")
 			return nil, err
 		}
 
+		if !organization.SameWorkspace(existing.OrganizationID, categoryItem.OrganizationID) {
+			logger.Warn().Msg("category belongs to another workspace")
+			return nil, errWorkspaceMismatch("category")
+		}
+
 		logger.Debug().Msg("category validation passed")
 	}
 
@@ -168,25 +237,85 @@ func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.
 		Str("status", string(updatedTodo.Status)).
 		Msg("Todo updated successfully")
 
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, updatedTodo.ID, userID), realtime.EventTodoUpdated, updatedTodo)
+
+	return updatedTodo, nil
+}
+
+// ToggleDescriptionTask flips a single task list checkbox in the description,
+// leaving the rest of the markdown untouched
+func (s *TodoService) ToggleDescriptionTask(ctx echo.Context, userID string, payload *todo.ToggleDescriptionTaskPayload) (*todo.Todo, error) {
+	logger := middleware.GetLogger(ctx)
+
+	existing, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleEditor)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	code := "TASK_NOT_FOUND"
+	if existing.Description == nil {
+		return nil, errs.NewNotFoundError("task list item not found", false, &code)
+	}
+
+	description, err := markdown.ToggleTask(*existing.Description, payload.Index, *payload.Checked)
+	if err != nil {
+		if errors.Is(err, markdown.ErrTaskNotFound) {
+			return nil, errs.NewNotFoundError("task list item not found", false, &code)
+		}
+		logger.Error().Err(err).Msg("failed to toggle task list item")
+		return nil, err
+	}
+
+	updatedTodo, err := s.todoRepo.ReplaceTodoDescription(ctx.Request().Context(), userID, payload.TodoID, *existing.Description, description)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update todo description")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_task_toggled").
+		Str("todo_id", updatedTodo.ID.String()).
+		Int("index", payload.Index).
+		Bool("checked", *payload.Checked).
+		Msg("Todo task list item toggled successfully")
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, updatedTodo.ID, userID), realtime.EventTodoUpdated, updatedTodo)
+
 	return updatedTodo, nil
 }
 
+// DeleteTodo permanently removes a todo with its attachments; only its owners may do so
 func (s *TodoService) DeleteTodo(ctx echo.Context, userID string, todoID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	err := s.todoRepo.DeleteTodo(ctx.Request().Context(), userID, todoID)
+	if _, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleOwner); err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return err
+	}
+
+	// resolve who can see the todo while the shares still exist
+	audience := todoAudience(ctx, s.shareRepo, s.authService, todoID, userID)
+
+	storageKeys, err := s.todoRepo.DeleteTodo(ctx.Request().Context(), userID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete todo")
 		return err
 
 	}
 
+	s.enqueueStorageDeletion(ctx.Request().Context(), storageKeys)
+
 	eventLogger := middleware.GetLogger(ctx)
 	eventLogger.Info().
 		Str("event ", "todo_deleted").
 		Str("todo_id", todoID.String()).
 		Msg("todo deleted successfully")
 
+	publishEvent(ctx, s.server, audience, realtime.EventTodoDeleted, realtime.Deleted{ID: todoID})
+
 	return nil
 
 }
@@ -204,159 +333,2467 @@ func (s *TodoService) GetTodoStats(ctx echo.Context, userID string) (*todo.TodoS
 
 }
 
-func (s *TodoService) UploadTodoAttachment(ctx echo.Context, userID string, todoID string, file *multipart.FileHeader) (*todo.TodoAttachment, error) {
+// GetInbox lists the todos assigned to the user in every workspace they belong to
+func (s *TodoService) GetInbox(ctx echo.Context, userID string, query *todo.GetInboxQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
 	logger := middleware.GetLogger(ctx)
 
-	//parse todo UUID
-	todoUUID, err := uuid.Parse(todoID)
+	memberships, err := s.authService.ListUserMemberships(ctx.Request().Context(), userID)
 	if err != nil {
-		logger.Error().Err(err).Msg("invalid todo ID")
-		return nil, errs.NewBadRequestError("invalid todo id", false, nil, nil, nil)
+		logger.Error().Err(err).Msg("failed to fetch organization memberships")
+		return nil, err
 	}
 
-	//verify exist or not
-	_, err = s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoUUID)
+	result, err := s.todoRepo.GetAssignedTodos(ctx.Request().Context(), userID, memberships, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch inbox")
+		return nil, err
+	}
+
+	for i := range result.Data {
+		s.presignTodoThumbnails(ctx.Request().Context(), &result.Data[i])
+	}
+
+	return result, nil
+}
+
+// AssignTodo assigns users who can already reach the todo and emails each new assignee
+func (s *TodoService) AssignTodo(ctx echo.Context, userID string, payload *todo.AssignTodoPayload) ([]todo.TodoAssignee, error) {
+	logger := middleware.GetLogger(ctx)
+
+	todoItem, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleEditor)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return nil, err
 	}
 
-	//open upload file
-	src, err := file.Open()
+	for _, assigneeID := range payload.UserIDs {
+		if err := s.checkAssignable(ctx, todoItem, assigneeID); err != nil {
+			logger.Warn().Err(err).Str("assignee_id", assigneeID).Msg("assignee validation failed")
+			return nil, err
+		}
+	}
+
+	added, err := s.todoRepo.AddTodoAssignees(ctx.Request().Context(), todoItem.ID, userID, payload.UserIDs)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to open the file")
-		return nil, errs.NewBadRequestError("failed to open uploaded file", false, nil, nil, nil)
+		logger.Error().Err(err).Msg("failed to assign todo")
+		return nil, err
 	}
 
-	defer src.Close()
+	audience := todoAudience(ctx, s.shareRepo, s.authService, todoItem.ID, userID)
+	for _, assignee := range added {
+		// Business event log
+		eventLogger := middleware.GetLogger(ctx)
+		eventLogger.Info().
+			Str("event", "todo_assigned").
+			Str("todo_id", todoItem.ID.String()).
+			Str("assignee_id", assignee.UserID).
+			Msg("Todo assigned successfully")
 
-	//uploading to S3
+		publishEvent(ctx, s.server, withActor(audience, assignee.UserID), realtime.EventTodoAssigned, assignee)
 
-	s3Key, err := s.awsClient.S3.UploadFile(
-		ctx.Request().Context(),
-		s.server.Config.AWS.UploadBucket,
-		"todos/attachments/"+file.Filename,
-		src,
-	)
+		if assignee.UserID != userID {
+			s.enqueueAssignedEmail(ctx, todoItem, assignee)
+		}
+	}
+
+	assignees, err := s.todoRepo.GetTodoAssignees(ctx.Request().Context(), todoItem.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch todo assignees")
+		return nil, err
+	}
+
+	return assignees, nil
+}
+
+// checkAssignable only lets work go to people who can see the todo: its owner,
+// its accepted grantees and, for organization todos, members who can read todos
+func (s *TodoService) checkAssignable(ctx echo.Context, todoItem *todo.Todo, assigneeID string) error {
+	hasAccess, err := canAccessTodo(ctx, s.shareRepo, s.authService, todoItem, assigneeID)
+	if err != nil {
+		return err
+	}
+	if hasAccess {
+		return nil
+	}
+
+	code := "ASSIGNEE_NO_ACCESS"
+	return errs.NewBadRequestError("todos can only be assigned to users who have access to them", true, &code, nil, nil)
+}
+
+// canAccessTodo reports whether another user can see the todo, through ownership,
+// an accepted share or a role in the todo's organization that lets them read todos.
+// It matches the audience the todo's realtime events go to.
+func canAccessTodo(ctx echo.Context, shareRepo *repository.ShareRepository, authService *AuthService, todoItem *todo.Todo, userID string) (bool, error) {
+	if userID == todoItem.UserID {
+		return true, nil
+	}
+
+	audience, err := shareRepo.GetTodoAudience(ctx.Request().Context(), todoItem.ID)
+	if err != nil {
+		return false, err
+	}
+	if slices.Contains(audience, userID) {
+		return true, nil
+	}
+
+	if todoItem.OrganizationID != nil {
+		return authService.HasOrganizationPermission(ctx.Request().Context(), *todoItem.OrganizationID, userID, organization.PermissionTodosRead)
+	}
+
+	return false, nil
+}
+
+// enqueueAssignedEmail queues the assignment notification; the assignment itself
+// is already stored, so a failure is only logged
+func (s *TodoService) enqueueAssignedEmail(ctx echo.Context, todoItem *todo.Todo, assignee todo.TodoAssignee) {
+	logger := middleware.GetLogger(ctx)
+
+	task, err := job.NewTodoAssignedEmailTask(assignee.UserID, assignee.AssignedBy, todoItem.ID.String(), todoItem.Title, todoItem.DueDate)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create todo assigned email task")
+		return
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil {
+		logger.Error().Err(err).Str("assignee_id", assignee.UserID).Msg("failed to enqueue todo assigned email")
+	}
+}
+
+// UnassignTodo removes an assignee; editors can unassign anyone and assignees can step down
+func (s *TodoService) UnassignTodo(ctx echo.Context, userID string, payload *todo.UnassignTodoPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	// an assignment grants no role, so stepping down only needs the access the assignee already has
+	required := share.RoleEditor
+	if payload.UserID == userID {
+		required = share.RoleViewer
+	}
+
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, required)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to upload file to s3")
-		return nil, errors.Wrap(err, " failed to upload file")
+		logger.Error().Err(err).Msg("todo validation failed")
+		return err
 	}
 
-	//Delete Mime type
-	src, err = file.Open()
+	audience := withActor(todoAudience(ctx, s.shareRepo, s.authService, payload.TodoID, userID), payload.UserID)
+
+	err = s.todoRepo.RemoveTodoAssignee(ctx.Request().Context(), payload.TodoID, payload.UserID)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to reopen for MIME detection")
-		return nil, errs.NewBadRequestError("failed to process file", false, nil, nil, nil)
+		logger.Error().Err(err).Msg("failed to unassign todo")
+		return err
 	}
-	defer src.Close()
 
-	buffer := make([]byte, 512)
-	_, err = src.Read(buffer)
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_unassigned").
+		Str("todo_id", payload.TodoID.String()).
+		Str("assignee_id", payload.UserID).
+		Msg("Todo unassigned successfully")
+
+	publishEvent(ctx, s.server, audience, realtime.EventTodoUnassigned, todo.TodoAssignee{
+		TodoID: payload.TodoID,
+		UserID: payload.UserID,
+	})
+
+	return nil
+}
+
+// UploadTodoAttachment streams a file sent through the API to storage and records it.
+// A client that disconnects mid-upload cancels the request context, which aborts the upload.
+func (s *TodoService) UploadTodoAttachment(ctx echo.Context, userID string, todoID uuid.UUID, fileName string, file io.Reader) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+	fileName = filename.Sanitize(fileName)
+
+	//verify exist or not
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleEditor)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to read for MIME detection")
-		return nil, errs.NewBadRequestError("failed to process file", false, nil, nil, nil)
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
 
+	attachmentID := uuid.New()
+	uploaded, checksum, err := s.storeAttachmentFile(ctx, userID, attachmentKey(userID, todoID, attachmentID), file)
+	if err != nil {
+		return nil, err
 	}
 
-	mimeType := http.DetectContentType(buffer)
+	blob, err := s.attachmentBlob(ctx.Request().Context(), uploaded.Key, checksum, uploaded.Size, uploaded.ContentType)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to store attachment blob")
+		s.enqueueStorageDeletion(ctx.Request().Context(), []string{uploaded.Key})
+		return nil, err
+	}
 
 	attachment, err := s.todoRepo.UploadTodoAttachment(
 		ctx.Request().Context(),
-		todoUUID,
+		attachmentID,
+		todoID,
 		userID,
-		s3Key,
-		file.Filename,
-		file.Size,
-		mimeType,
+		fileName,
+		blob,
 	)
 
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create attachment record")
+		s.enqueueUnusedObjects(ctx.Request().Context(), "", uploaded.Key, blob.StorageKey)
 		return nil, err
 	}
+	s.enqueueUnusedObjects(ctx.Request().Context(), attachment.DownloadKey, uploaded.Key, blob.StorageKey)
 
 	logger.Info().
 		Str("attachmentID", attachment.ID.String()).
-		Str("s3_key", s3Key).
+		Str("s3_key", attachment.DownloadKey).
+		Str("checksum", checksum).
+		Int64("file_size", uploaded.Size).
 		Msg("uploaded todo attachment")
 
+	s.enqueueAttachmentScan(ctx, attachment)
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, todoID, userID), realtime.EventAttachmentCreated, attachment)
+
 	return attachment, nil
 }
 
-func (s *TodoService) DeleteTodoAttachment(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) error {
+// CreateAttachmentLink attaches a URL to a todo. Links share the attachment lists with files
+// but are never stored, scanned or versioned.
+func (s *TodoService) CreateAttachmentLink(ctx echo.Context, userID string, payload *todo.CreateAttachmentLinkPayload) (*todo.TodoAttachment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	_, err := s.todoRepo.GetTodoAttachment(ctx.Request().Context(), todoID, attachmentID)
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleEditor)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
-		return err
+		return nil, err
 	}
 
-	//get attachment details for s3 deletion
-	attachment, err := s.todoRepo.GetTodoAttachment(
+	name := payload.URL
+	if payload.Title != nil {
+		name = *payload.Title
+	}
+
+	attachment, err := s.todoRepo.CreateAttachmentLink(
 		ctx.Request().Context(),
-		todoID,
-		attachmentID,
+		uuid.New(),
+		payload.TodoID,
+		userID,
+		name,
+		payload.URL,
+		payload.Description,
 	)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to get attachment detailes")
-		return err
+		logger.Error().Err(err).Msg("failed to create link attachment")
+		return nil, err
 	}
 
-	//delete attachment record
-	err = s.todoRepo.DeleteTodoAttachment(ctx.Request().Context(),
-		todoID, attachmentID)
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "attachment_link_created").
+		Str("todo_id", payload.TodoID.String()).
+		Str("attachment_id", attachment.ID.String()).
+		Msg("Link attachment created successfully")
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, payload.TodoID, userID), realtime.EventAttachmentCreated, attachment)
+
+	return attachment, nil
+}
+
+// storeAttachmentFile streams an uploaded file to storage under key once its sniffed type is allowed
+// and returns its SHA-256, computed on the way. The size is unknown up front, so the upload may use
+// whatever is left of the quota.
+func (s *TodoService) storeAttachmentFile(ctx echo.Context, userID string, key string, file io.Reader) (*storage.ObjectInfo, string, error) {
+	logger := middleware.GetLogger(ctx)
+	cfg := s.server.Config.Attachment
+
+	remaining, err := s.remainingStorage(ctx, userID)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to delete attachment record")
-		return err
+		logger.Error().Err(err).Msg("failed to get storage usage")
+		return nil, "", err
+	}
+	if remaining == 0 {
+		return nil, "", errStorageQuotaExceeded(cfg.UserQuota)
+	}
+	maxFileSize := min(cfg.MaxFileSize, remaining)
+
+	//check the type from the first bytes before anything is stored
+	buffered := bufio.NewReaderSize(file, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		logger.Warn().Err(err).Msg("failed to read attachment")
+		return nil, "", errs.NewBadRequestError("failed to read uploaded file", false, nil, nil, nil)
+	}
+	if mimeType := http.DetectContentType(head); !cfg.AllowsMimeType(mimeType) {
+		return nil, "", errMimeTypeNotAllowed(mimeType)
 	}
 
-	//delete the object without stoping for the response
-	go func() {
-		err := s.awsClient.S3.DeleteObject(ctx.Request().Context(), s.server.Config.AWS.UploadBucket, attachment.DownloadKey)
-
-		if err != nil {
-			s.server.Logger.Error().Err(err).Str("s3_key", attachment.DownloadKey).Msg("failed to delete attachment from s3")
+	//streaming to storage, the MIME type is detected from the first bytes and the checksum computed on the way
+	hash := sha256.New()
+	uploaded, err := s.storage.Put(
+		ctx.Request().Context(),
+		key,
+		io.TeeReader(buffered, hash),
+		storage.PutOptions{MaxSize: maxFileSize},
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrTooLarge) {
+			logger.Warn().Int64("max_file_size", maxFileSize).Msg("attachment exceeds the maximum size")
+			if maxFileSize < cfg.MaxFileSize {
+				return nil, "", errStorageQuotaExceeded(cfg.UserQuota)
+			}
+			return nil, "", errFileTooLarge(maxFileSize)
+		}
+		if ctx.Request().Context().Err() != nil {
+			logger.Warn().Err(err).Msg("client disconnected during attachment upload")
+			return nil, "", err
 		}
+		logger.Error().Err(err).Msg("failed to upload file to storage")
+		return nil, "", errors.Wrap(err, " failed to upload file")
+	}
 
-	}()
-	logger.Info().Msg("deleted todo message")
-	return nil
+	return uploaded, hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-func (s *TodoService) GetAttachmentPresignedURL(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) (string, error) {
+// UploadAttachmentVersion stores a file as the new current version of an attachment. The previous
+// version stays downloadable from the history until the retention limit pushes it out.
+func (s *TodoService) UploadAttachmentVersion(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID, fileName string, file io.Reader) (*todo.TodoAttachment, error) {
 	logger := middleware.GetLogger(ctx)
+	fileName = filename.Sanitize(fileName)
 
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	if _, err := s.getReplaceableAttachment(ctx, userID, todoID, attachmentID); err != nil {
+		return nil, err
+	}
+
+	versionID := uuid.New()
+	uploaded, checksum, err := s.storeAttachmentFile(ctx, userID, attachmentKey(userID, todoID, versionID), file)
 	if err != nil {
-		logger.Error().Err(err).Msg("todo validation failed")
-		return "", err
+		return nil, err
 	}
 
-	attachment, err := s.todoRepo.GetTodoAttachment(
+	blob, err := s.attachmentBlob(ctx.Request().Context(), uploaded.Key, checksum, uploaded.Size, uploaded.ContentType)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to store attachment blob")
+		s.enqueueStorageDeletion(ctx.Request().Context(), []string{uploaded.Key})
+		return nil, err
+	}
+
+	attachment, err := s.todoRepo.AddAttachmentVersion(
 		ctx.Request().Context(),
+		userID,
 		todoID,
 		attachmentID,
+		versionID,
+		fileName,
+		blob,
 	)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to record attachment version")
+		s.enqueueUnusedObjects(ctx.Request().Context(), "", uploaded.Key, blob.StorageKey)
+		return nil, err
+	}
+	s.enqueueUnusedObjects(ctx.Request().Context(), attachment.DownloadKey, uploaded.Key, blob.StorageKey)
+
+	s.attachmentVersionAdded(ctx, userID, attachment)
+
+	return attachment, nil
+}
+
+// getReplaceableAttachment loads an attachment the user can edit that may take a new version.
+// A version still waiting for its scan would never be scanned once replaced, so it has to finish first.
+func (s *TodoService) getReplaceableAttachment(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
 
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleEditor)
 	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
 
-		logger.Error().Err(err).Msg("failed to get attachment file")
-		return "", err
+	attachment, err := s.todoRepo.GetTodoAttachment(ctx.Request().Context(), userID, todoID, attachmentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment")
+		return nil, err
 	}
 
-	//generate the PResigned URL
-	url, err := s.awsClient.S3.CreatePresignedUrl(
-		ctx.Request().Context(),
-		s.server.Config.AWS.UploadBucket,
-		attachment.DownloadKey,
-	)
+	if err := checkAttachmentFile(attachment.Kind); err != nil {
+		return nil, err
+	}
+
+	if attachment.ScanStatus == todo.ScanStatusPending {
+		code := "ATTACHMENT_SCAN_PENDING"
+		return nil, errs.NewConflictError("the current version is still being scanned, try again shortly", true, &code)
+	}
+
+	return attachment, nil
+}
+
+// attachmentVersionAdded starts scanning a new current version, trims the history and tells the todo's audience
+func (s *TodoService) attachmentVersionAdded(ctx echo.Context, userID string, attachment *todo.TodoAttachment) {
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "attachment_version_added").
+		Str("todo_id", attachment.TodoID.String()).
+		Str("attachment_id", attachment.ID.String()).
+		Int("version", attachment.Version).
+		Str("s3_key", attachment.DownloadKey).
+		Str("checksum", *attachment.Checksum).
+		Msg("Attachment version added successfully")
+
+	if attachment.ScanStatus == todo.ScanStatusPending {
+		s.enqueueAttachmentScan(ctx, attachment)
+	}
+
+	s.pruneAttachmentVersions(ctx.Request().Context(), attachment.ID)
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, attachment.TodoID, userID), realtime.EventAttachmentUpdated, attachment)
+}
 
+// pruneAttachmentVersions drops the versions past the retention limit and queues their objects for deletion.
+// A failure only leaves extra versions behind until the next one is added, so it is just logged.
+func (s *TodoService) pruneAttachmentVersions(ctx context.Context, attachmentID uuid.UUID) {
+	keep := s.server.Config.Attachment.MaxVersions - 1
+
+	pruned, err := s.todoRepo.PruneAttachmentVersions(ctx, attachmentID, keep)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to generate presigned URL")
-		return "", err
+		s.server.Logger.Error().Err(err).Str("attachment_id", attachmentID.String()).Msg("failed to prune attachment versions")
+		return
+	}
+
+	keys := []string{}
+	for i := range pruned {
+		keys = append(keys, pruned[i].StorageKeys()...)
+	}
+	s.enqueueStorageDeletion(ctx, keys)
+}
+
+// GetAttachmentVersions lists every version of an attachment, newest first
+func (s *TodoService) GetAttachmentVersions(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) ([]todo.AttachmentVersion, error) {
+	logger := middleware.GetLogger(ctx)
+
+	versions, err := s.todoRepo.GetAttachmentVersions(ctx.Request().Context(), userID, todoID, attachmentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment versions")
+		return nil, err
+	}
+
+	for i := range versions {
+		s.presignThumbnails(ctx.Request().Context(), versions[i].Thumbnails)
+	}
+
+	return versions, nil
+}
+
+// GetAttachmentVersionPresignedURL hands out a download link for any version of an attachment
+func (s *TodoService) GetAttachmentVersionPresignedURL(ctx echo.Context, userID string, payload *todo.GetAttachmentVersionPresignedURLPayload) (*todo.AttachmentDownloadURL, error) {
+	logger := middleware.GetLogger(ctx)
+
+	expiry, err := s.downloadExpiry(payload.ExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.todoRepo.GetAttachmentVersion(ctx.Request().Context(), userID, payload.TodoID, payload.AttachmentID, payload.VersionID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment version")
+		return nil, err
+	}
+
+	if err := checkAttachmentDownloadable(version.ScanStatus); err != nil {
+		logger.Warn().Str("scan_status", string(version.ScanStatus)).Msg("attachment version is not downloadable")
+		return nil, err
 	}
 
-	return url, nil
+	download, err := s.presignDownload(ctx, version.DownloadKey, version.Name, version.ContentType(), payload.Disposition, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	logAttachmentDownload(ctx, userID, payload.TodoID, version.AttachmentID, version.Version, "presigned", download.Disposition)
+
+	return download, nil
+}
+
+// RestoreAttachmentVersion brings an earlier version back as the new current one. It shares the earlier
+// version's blob, which stays in the history on its own and the limit applies as usual.
+func (s *TodoService) RestoreAttachmentVersion(ctx echo.Context, userID string, payload *todo.RestoreAttachmentVersionPayload) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if _, err := s.getReplaceableAttachment(ctx, userID, payload.TodoID, payload.AttachmentID); err != nil {
+		return nil, err
+	}
+
+	version, err := s.todoRepo.GetAttachmentVersion(ctx.Request().Context(), userID, payload.TodoID, payload.AttachmentID, payload.VersionID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment version")
+		return nil, err
+	}
+
+	if version.Current {
+		code := "ATTACHMENT_VERSION_CURRENT"
+		return nil, errs.NewBadRequestError("this version is already the current one", true, &code, nil, nil)
+	}
+
+	if err := checkAttachmentDownloadable(version.ScanStatus); err != nil {
+		return nil, err
+	}
+
+	if version.FileSize != nil {
+		remaining, err := s.remainingStorage(ctx, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get storage usage")
+			return nil, err
+		}
+		if *version.FileSize > remaining {
+			return nil, errStorageQuotaExceeded(s.server.Config.Attachment.UserQuota)
+		}
+	}
+
+	attachment, err := s.todoRepo.RestoreAttachmentVersion(
+		ctx.Request().Context(),
+		userID,
+		payload.TodoID,
+		payload.AttachmentID,
+		version.ID,
+		uuid.New(),
+	)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to restore attachment version")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "attachment_version_restored").
+		Str("todo_id", attachment.TodoID.String()).
+		Str("attachment_id", attachment.ID.String()).
+		Int("restored_version", version.Version).
+		Int("version", attachment.Version).
+		Msg("Attachment version restored successfully")
+
+	s.pruneAttachmentVersions(ctx.Request().Context(), attachment.ID)
+	s.presignThumbnails(ctx.Request().Context(), attachment.Thumbnails)
+
+	// the restored version was scanned clean already, only its text has to be read again
+	s.enqueueAttachmentExtraction(ctx.Request().Context(), attachment.ID)
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, attachment.TodoID, userID), realtime.EventAttachmentUpdated, attachment)
+
+	return attachment, nil
+}
+
+func (s *TodoService) GetTodoAttachments(ctx echo.Context, userID string, todoID uuid.UUID) ([]todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleViewer)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	attachments, err := s.todoRepo.GetTodoAttachments(ctx.Request().Context(), userID, todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get todo attachments")
+		return nil, err
+	}
+
+	for i := range attachments {
+		s.presignThumbnails(ctx.Request().Context(), attachments[i].Thumbnails)
+	}
+
+	return attachments, nil
+}
+
+func (s *TodoService) GetTodoAttachment(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	attachment, err := s.todoRepo.GetTodoAttachment(ctx.Request().Context(), userID, todoID, attachmentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get todo attachment")
+		return nil, err
+	}
+
+	s.presignThumbnails(ctx.Request().Context(), attachment.Thumbnails)
+
+	return attachment, nil
+}
+
+// GetStorageUsage reports the caller's attachment storage against their quota,
+// broken down by attachment type and by todo category
+func (s *TodoService) GetStorageUsage(ctx echo.Context, userID string) (*todo.StorageUsage, error) {
+	logger := middleware.GetLogger(ctx)
+
+	usage, err := s.todoRepo.GetStorageUsage(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get storage usage")
+		return nil, err
+	}
+	usage.QuotaBytes = s.server.Config.Attachment.UserQuota
+	usage.RemainingBytes = usage.Remaining()
+
+	byMimeType, err := s.todoRepo.GetStorageUsageByMimeType(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get storage usage by type")
+		return nil, err
+	}
+
+	//fold the media types into attachment types
+	typeIndex := map[string]int{}
+	usage.ByType = []todo.StorageUsageByType{}
+	for _, row := range byMimeType {
+		attachmentType := todo.AttachmentType(row.MimeType)
+		i, ok := typeIndex[attachmentType]
+		if !ok {
+			i = len(usage.ByType)
+			typeIndex[attachmentType] = i
+			usage.ByType = append(usage.ByType, todo.StorageUsageByType{Type: attachmentType})
+		}
+		usage.ByType[i].FileCount += row.FileCount
+		usage.ByType[i].Bytes += row.Bytes
+	}
+	slices.SortFunc(usage.ByType, func(a, b todo.StorageUsageByType) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+
+	usage.ByCategory, err = s.todoRepo.GetStorageUsageByCategory(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get storage usage by category")
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// remainingStorage is how many bytes the user may still upload
+func (s *TodoService) remainingStorage(ctx echo.Context, userID string) (int64, error) {
+	usage, err := s.todoRepo.GetStorageUsage(ctx.Request().Context(), userID)
+	if err != nil {
+		return 0, err
+	}
+	usage.QuotaBytes = s.server.Config.Attachment.UserQuota
+
+	return usage.Remaining(), nil
+}
+
+func (s *TodoService) DeleteTodoAttachment(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	//delete attachment record, the keys of every version come back for s3 deletion
+	keys, err := s.todoRepo.DeleteTodoAttachment(ctx.Request().Context(),
+		userID, todoID, attachmentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete attachment record")
+		return err
+	}
+
+	//the objects are deleted by a retried job, not in the request
+	s.enqueueStorageDeletion(ctx.Request().Context(), keys)
+	logger.Info().Msg("deleted todo message")
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, todoID, userID), realtime.EventAttachmentDeleted, realtime.Deleted{
+		ID:     attachmentID,
+		TodoID: &todoID,
+	})
+
+	return nil
+}
+
+func (s *TodoService) GetAttachmentPresignedURL(ctx echo.Context, userID string, payload *todo.GetAttachmentPresignedURLPayload) (*todo.AttachmentDownloadURL, error) {
+	expiry, err := s.downloadExpiry(payload.ExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.getDownloadableAttachment(ctx, userID, payload.TodoID, payload.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	//links are not in storage, the bookmarked URL is handed out as it is
+	if attachment.Kind == todo.AttachmentKindLink {
+		logAttachmentDownload(ctx, userID, attachment.TodoID, attachment.ID, attachment.Version, "link", filename.DispositionInline)
+		return &todo.AttachmentDownloadURL{
+			URL:         *attachment.URL,
+			Disposition: filename.DispositionInline,
+		}, nil
+	}
+
+	download, err := s.presignDownload(ctx, attachment.DownloadKey, attachment.Name, attachment.ContentType(), payload.Disposition, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	logAttachmentDownload(ctx, userID, attachment.TodoID, attachment.ID, attachment.Version, "presigned", download.Disposition)
+
+	return download, nil
+}
+
+// downloadExpiry is the link lifetime the client asked for in seconds, or the configured default
+func (s *TodoService) downloadExpiry(expiresIn *int) (time.Duration, error) {
+	cfg := s.server.Config.Attachment
+	if expiresIn == nil {
+		return cfg.DownloadURLExpiry, nil
+	}
+
+	expiry := time.Duration(*expiresIn) * time.Second
+	if expiry > cfg.MaxDownloadURLExpiry {
+		return 0, errDownloadExpiryTooLong(cfg.MaxDownloadURLExpiry)
+	}
+
+	return expiry, nil
+}
+
+// presignDownload signs a link to one stored file, inline only when the type is safe to show
+func (s *TodoService) presignDownload(ctx echo.Context, key, name, mimeType, requested string, expiry time.Duration) (*todo.AttachmentDownloadURL, error) {
+	logger := middleware.GetLogger(ctx)
+
+	disposition := filename.Disposition(requested, mimeType)
+	expiresAt := time.Now().Add(expiry)
+
+	//generate the PResigned URL
+	url, err := s.storage.PresignGet(
+		ctx.Request().Context(),
+		key,
+		expiry,
+		storage.PresignGetOptions{
+			ContentDisposition: filename.ContentDisposition(disposition, name),
+		},
+	)
+
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate presigned URL")
+		return nil, err
+	}
+
+	return &todo.AttachmentDownloadURL{
+		URL:         url,
+		Disposition: disposition,
+		ExpiresAt:   &expiresAt,
+	}, nil
+}
+
+// OpenAttachmentContent opens an attachment for streaming through the API, for clients that
+// cannot reach storage directly. The body seeks lazily so Range requests only fetch what they need.
+func (s *TodoService) OpenAttachmentContent(ctx echo.Context, userID string, payload *todo.GetAttachmentContentPayload) (*todo.AttachmentContent, error) {
+	logger := middleware.GetLogger(ctx)
+
+	attachment, err := s.getDownloadableAttachment(ctx, userID, payload.TodoID, payload.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAttachmentFile(attachment.Kind); err != nil {
+		return nil, err
+	}
+
+	body, info, err := storage.OpenSeeker(ctx.Request().Context(), s.storage, attachment.DownloadKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			logger.Error().Str("key", attachment.DownloadKey).Msg("attachment object is missing from storage")
+			code := "ATTACHMENT_OBJECT_MISSING"
+			return nil, errs.NewNotFoundError("attachment file not found", true, &code)
+		}
+		logger.Error().Err(err).Msg("failed to open attachment object")
+		return nil, err
+	}
+
+	disposition := filename.Disposition(payload.Disposition, attachment.ContentType())
+
+	logAttachmentDownload(ctx, userID, attachment.TodoID, attachment.ID, attachment.Version, "proxy", disposition)
+
+	return &todo.AttachmentContent{
+		Attachment:  attachment,
+		Disposition: disposition,
+		Body:        body,
+		Size:        info.Size,
+		ModifiedAt:  info.ModifiedAt,
+	}, nil
+}
+
+// getDownloadableAttachment loads an attachment the user may read and that passed the virus scan
+func (s *TodoService) getDownloadableAttachment(ctx echo.Context, userID string, todoID uuid.UUID, attachmentID uuid.UUID) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID, share.RoleViewer)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	attachment, err := s.todoRepo.GetTodoAttachment(
+		ctx.Request().Context(),
+		userID,
+		todoID,
+		attachmentID,
+	)
+
+	if err != nil {
+
+		logger.Error().Err(err).Msg("failed to get attachment file")
+		return nil, err
+	}
+
+	if err := checkAttachmentDownloadable(attachment.ScanStatus); err != nil {
+		logger.Warn().Str("scan_status", string(attachment.ScanStatus)).Msg("attachment is not downloadable")
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// logAttachmentDownload leaves an audit trail of who fetched which file and how
+func logAttachmentDownload(ctx echo.Context, userID string, todoID, attachmentID uuid.UUID, version int, method, disposition string) {
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "attachment_downloaded").
+		Str("user_id", userID).
+		Str("todo_id", todoID.String()).
+		Str("attachment_id", attachmentID.String()).
+		Int("version", version).
+		Str("method", method).
+		Str("disposition", disposition).
+		Str("ip", ctx.RealIP()).
+		Str("user_agent", ctx.Request().UserAgent()).
+		Msg("Attachment downloaded")
+}
+
+// errDownloadExpiryTooLong rejects download links asked to outlive the configured maximum
+func errDownloadExpiryTooLong(max time.Duration) error {
+	code := "DOWNLOAD_EXPIRY_TOO_LONG"
+	return errs.NewBadRequestError(fmt.Sprintf("download links may last at most %d seconds", int(max.Seconds())), true, &code, nil, nil)
+}
+
+// errWorkspaceMismatch rejects links between items of different workspaces
+func errWorkspaceMismatch(entity string) error {
+	code := "WORKSPACE_MISMATCH"
+	return errs.NewBadRequestError(entity+" belongs to another workspace", true, &code, nil, nil)
+}
+
+// GetAttachmentArchive zips the downloadable attachments of a todo, and optionally of its subtasks.
+// Archives up to the stream limit are written to the client while they are produced, larger ones
+// are queued for a job and their presigned link is handed out once they are ready.
+func (s *TodoService) GetAttachmentArchive(ctx echo.Context, userID string, payload *todo.GetAttachmentArchivePayload) (*todo.AttachmentArchiveDownload, error) {
+	logger := middleware.GetLogger(ctx)
+	cfg := s.server.Config.Attachment
+
+	todoItem, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleViewer)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	attachments, err := s.getArchiveAttachments(ctx, userID, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		code := "ARCHIVE_EMPTY"
+		return nil, errs.NewBadRequestError("there are no downloadable attachments to archive", true, &code, nil, nil)
+	}
+
+	var totalSize int64
+	attachmentIDs := make([]uuid.UUID, 0, len(attachments))
+	for i := range attachments {
+		if attachments[i].FileSize != nil {
+			totalSize += *attachments[i].FileSize
+		}
+		attachmentIDs = append(attachmentIDs, attachments[i].ID)
+	}
+	if totalSize > cfg.ArchiveMaxSize {
+		code := "ARCHIVE_TOO_LARGE"
+		return nil, errs.NewBadRequestError(fmt.Sprintf("archives can hold at most %d bytes of attachments", cfg.ArchiveMaxSize), true, &code, nil, nil)
+	}
+
+	name := filename.Sanitize(todoItem.Title) + ".zip"
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "attachment_archive_requested").
+		Str("todo_id", payload.TodoID.String()).
+		Int("file_count", len(attachments)).
+		Int64("total_size", totalSize).
+		Bool("include_subtasks", payload.IncludeSubtasks).
+		Bool("background", totalSize > cfg.ArchiveStreamMaxSize).
+		Msg("Attachment archive requested")
+
+	if totalSize > cfg.ArchiveStreamMaxSize {
+		fileCount := len(attachments)
+		archive, err := s.todoRepo.CreateAttachmentArchive(ctx.Request().Context(), &todo.AttachmentArchive{
+			TodoID:        &payload.TodoID,
+			RequestedBy:   userID,
+			Name:          name,
+			AttachmentIDs: attachmentIDs,
+			FileCount:     &fileCount,
+			ExpiresAt:     time.Now().Add(cfg.ArchiveTTL),
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to record attachment archive")
+			return nil, err
+		}
+
+		task, err := job.NewBuildAttachmentArchiveTask(archive.ID.String())
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create archive task")
+			return nil, err
+		}
+		if _, err := s.server.Job.Client.Enqueue(task); err != nil {
+			logger.Error().Err(err).Msg("failed to enqueue archive task")
+			return nil, err
+		}
+
+		return &todo.AttachmentArchiveDownload{Name: name, Archive: archive}, nil
+	}
+
+	return &todo.AttachmentArchiveDownload{
+		Name: name,
+		Write: func(w io.Writer) error {
+			return s.writeAttachmentArchive(ctx.Request().Context(), w, payload.TodoID, attachments)
+		},
+	}, nil
+}
+
+// getArchiveAttachments picks the attachments that go into an archive. Without a selection every
+// downloadable one is taken, a selected one that is missing or not downloadable fails the request.
+func (s *TodoService) getArchiveAttachments(ctx echo.Context, userID string, payload *todo.GetAttachmentArchivePayload) ([]todo.AttachmentWithTodoTitle, error) {
+	logger := middleware.GetLogger(ctx)
+
+	attachments, err := s.todoRepo.GetArchiveAttachments(ctx.Request().Context(), userID, payload.TodoID, payload.IncludeSubtasks)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get archive attachments")
+		return nil, err
+	}
+
+	if len(payload.AttachmentIDs) == 0 {
+		return slices.DeleteFunc(attachments, func(attachment todo.AttachmentWithTodoTitle) bool {
+			return attachment.Kind != todo.AttachmentKindFile || attachment.ScanStatus != todo.ScanStatusClean
+		}), nil
+	}
+
+	for _, attachmentID := range payload.AttachmentIDs {
+		index := slices.IndexFunc(attachments, func(attachment todo.AttachmentWithTodoTitle) bool {
+			return attachment.ID == attachmentID
+		})
+		if index < 0 {
+			code := "ATTACHMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError(fmt.Sprintf("attachment %s not found", attachmentID), true, &code)
+		}
+		if err := checkAttachmentFile(attachments[index].Kind); err != nil {
+			return nil, err
+		}
+		if err := checkAttachmentDownloadable(attachments[index].ScanStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	return slices.DeleteFunc(attachments, func(attachment todo.AttachmentWithTodoTitle) bool {
+		return !slices.Contains(payload.AttachmentIDs, attachment.ID)
+	}), nil
+}
+
+// writeAttachmentArchive streams a ZIP of the attachments to w one object at a time, so nothing is
+// held in memory. Subtask attachments go into a folder per subtask and clashing names are numbered.
+func (s *TodoService) writeAttachmentArchive(ctx context.Context, w io.Writer, todoID uuid.UUID, attachments []todo.AttachmentWithTodoTitle) error {
+	archive := zip.NewWriter(w)
+	taken := map[string]bool{}
+	folders := map[uuid.UUID]string{}
+
+	for i := range attachments {
+		attachment := &attachments[i]
+
+		name := attachment.Name
+		if attachment.TodoID != todoID {
+			folder, ok := folders[attachment.TodoID]
+			if !ok {
+				folder = filename.Dedupe(filename.Sanitize(attachment.TodoTitle), taken)
+				folders[attachment.TodoID] = folder
+			}
+			name = folder + "/" + name
+		}
+
+		if err := s.writeArchiveEntry(ctx, archive, filename.Dedupe(name, taken), &attachment.TodoAttachment); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// writeArchiveEntry copies one stored attachment into the archive
+func (s *TodoService) writeArchiveEntry(ctx context.Context, archive *zip.Writer, name string, attachment *todo.TodoAttachment) error {
+	body, _, err := s.storage.Get(ctx, attachment.DownloadKey)
+	if err != nil {
+		return fmt.Errorf("failed to open attachment_id=%s: %w", attachment.ID.String(), err)
+	}
+	defer body.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   archiveMethod(attachment.ContentType()),
+		Modified: attachment.VersionedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+
+	if _, err := io.Copy(entry, body); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+
+	return nil
+}
+
+// archiveMethod stores media and documents that are compressed already as they are and deflates the rest
+func archiveMethod(mimeType string) uint16 {
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	family, _, _ := strings.Cut(mediaType, "/")
+
+	switch {
+	case family == "audio", family == "video", family == "image" && mediaType != "image/svg+xml":
+		return zip.Store
+	case mediaType == "application/pdf", mediaType == "application/zip", mediaType == "application/gzip":
+		return zip.Store
+	}
+
+	return zip.Deflate
+}
+
+// GetAttachmentArchiveStatus reports on an archive built in the background, with a download link once it is ready
+func (s *TodoService) GetAttachmentArchiveStatus(ctx echo.Context, userID string, payload *todo.GetAttachmentArchiveStatusPayload) (*todo.AttachmentArchive, error) {
+	logger := middleware.GetLogger(ctx)
+
+	archive, err := s.todoRepo.GetAttachmentArchive(ctx.Request().Context(), userID, payload.TodoID, payload.ArchiveID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment archive")
+		return nil, err
+	}
+
+	remaining := time.Until(archive.ExpiresAt)
+	if remaining <= 0 {
+		code := "ARCHIVE_EXPIRED"
+		return nil, errs.NewNotFoundError("this archive has expired, request a new one", true, &code)
+	}
+
+	if archive.Status == todo.ArchiveStatusReady && archive.StorageKey != nil {
+		download, err := s.presignDownload(ctx, *archive.StorageKey, archive.Name, "application/zip", "attachment", min(s.server.Config.Attachment.DownloadURLExpiry, remaining))
+		if err != nil {
+			return nil, err
+		}
+		archive.Download = download
+	}
+
+	return archive, nil
+}
+
+// RequestAttachmentUpload hands the client a presigned PUT URL or POST policy so the file goes
+// straight to storage. The upload only becomes an attachment once it is confirmed.
+func (s *TodoService) RequestAttachmentUpload(ctx echo.Context, userID string, payload *todo.CreateAttachmentUploadPayload) (*todo.AttachmentUpload, error) {
+	logger := middleware.GetLogger(ctx)
+	cfg := s.server.Config.Attachment
+
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, payload.TodoID, share.RoleEditor)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	if payload.AttachmentID != nil {
+		if _, err := s.getReplaceableAttachment(ctx, userID, payload.TodoID, *payload.AttachmentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if payload.FileSize > cfg.MaxFileSize {
+		return nil, errFileTooLarge(cfg.MaxFileSize)
+	}
+
+	if !cfg.AllowsMimeType(payload.MimeType) {
+		return nil, errMimeTypeNotAllowed(payload.MimeType)
+	}
+
+	remaining, err := s.remainingStorage(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get storage usage")
+		return nil, err
+	}
+	if payload.FileSize > remaining {
+		return nil, errStorageQuotaExceeded(cfg.UserQuota)
+	}
+
+	//the confirmed attachment or version takes over the upload's ID and key
+	uploadID := uuid.New()
+	uploadKey := attachmentKey(userID, payload.TodoID, uploadID)
+
+	var presigned *storage.PresignedUpload
+	if payload.Method == todo.UploadMethodPost {
+		presigned, err = s.storage.PresignPost(ctx.Request().Context(), uploadKey, payload.MimeType, payload.FileSize, cfg.UploadURLExpiry)
+	} else {
+		presigned, err = s.storage.PresignPut(ctx.Request().Context(), uploadKey, payload.MimeType, payload.FileSize, cfg.UploadURLExpiry)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to presign attachment upload")
+		return nil, err
+	}
+
+	now := time.Now()
+	todoID := payload.TodoID
+	upload, err := s.todoRepo.CreatePendingUpload(ctx.Request().Context(), &todo.PendingUpload{
+		BaseWithId:   model.BaseWithId{ID: uploadID},
+		TodoID:       &todoID,
+		UploadedBy:   userID,
+		UploadKey:    uploadKey,
+		Name:         filename.Sanitize(payload.Name),
+		MimeType:     payload.MimeType,
+		FileSize:     payload.FileSize,
+		Method:       payload.Method,
+		ExpiresAt:    now.Add(cfg.PendingUploadTTL),
+		AttachmentID: payload.AttachmentID,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to record pending upload")
+		return nil, err
+	}
+
+	logger.Info().
+		Str("upload_id", upload.ID.String()).
+		Str("method", upload.Method).
+		Int64("file_size", upload.FileSize).
+		Msg("requested attachment upload")
+
+	return &todo.AttachmentUpload{
+		UploadID:  upload.ID,
+		Method:    presigned.Method,
+		URL:       presigned.URL,
+		Headers:   presigned.Headers,
+		Fields:    presigned.Fields,
+		ExpiresAt: now.Add(cfg.UploadURLExpiry),
+	}, nil
+}
+
+// ConfirmAttachmentUpload checks the uploaded object against what was requested and creates the attachment
+func (s *TodoService) ConfirmAttachmentUpload(ctx echo.Context, userID string, payload *todo.ConfirmAttachmentUploadPayload) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	upload, err := s.todoRepo.GetPendingUpload(ctx.Request().Context(), userID, payload.TodoID, payload.UploadID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get pending upload")
+		return nil, err
+	}
+
+	if time.Now().After(upload.ExpiresAt) {
+		code := "UPLOAD_EXPIRED"
+		return nil, errs.NewBadRequestError("this upload has expired, request a new one", true, &code, nil, nil)
+	}
+
+	info, err := s.storage.Head(ctx.Request().Context(), upload.UploadKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			code := "UPLOAD_INCOMPLETE"
+			return nil, errs.NewBadRequestError("the file has not been uploaded yet", true, &code, nil, nil)
+		}
+		logger.Error().Err(err).Msg("failed to verify uploaded object")
+		return nil, err
+	}
+
+	sizeMatches := info.Size == upload.FileSize
+	if upload.Method == todo.UploadMethodPost {
+		// a POST policy only caps the size, so the declared size is an upper bound
+		sizeMatches = info.Size > 0 && info.Size <= upload.FileSize
+	}
+	if !sizeMatches || !sameMediaType(info.ContentType, upload.MimeType) {
+		logger.Warn().
+			Str("upload_id", upload.ID.String()).
+			Int64("stored_size", info.Size).
+			Str("stored_type", info.ContentType).
+			Msg("uploaded object does not match the requested upload")
+
+		s.discardPendingUpload(ctx.Request().Context(), upload)
+
+		code := "UPLOAD_MISMATCH"
+		return nil, errs.NewBadRequestError("the uploaded file does not match the requested size or type", true, &code, nil, nil)
+	}
+
+	//the declared type is only a header, the allow-list is checked against the stored bytes
+	mimeType, err := s.sniffObjectType(ctx.Request().Context(), upload.UploadKey)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to read uploaded object")
+		return nil, err
+	}
+	if !s.server.Config.Attachment.AllowsMimeType(mimeType) {
+		logger.Warn().Str("upload_id", upload.ID.String()).Str("sniffed_type", mimeType).Msg("uploaded object has a disallowed type")
+		s.discardPendingUpload(ctx.Request().Context(), upload)
+		return nil, errMimeTypeNotAllowed(mimeType)
+	}
+
+	//other uploads may have been confirmed since this one was requested, its own reservation is still counted
+	remaining, err := s.remainingStorage(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get storage usage")
+		return nil, err
+	}
+	if info.Size > remaining+upload.FileSize {
+		logger.Warn().Str("upload_id", upload.ID.String()).Int64("stored_size", info.Size).Msg("uploaded object exceeds the storage quota")
+		s.discardPendingUpload(ctx.Request().Context(), upload)
+		return nil, errStorageQuotaExceeded(s.server.Config.Attachment.UserQuota)
+	}
+
+	//the client never sent a checksum we could trust, so the stored object is read back
+	checksum, _, err := s.hashAttachmentObject(ctx.Request().Context(), upload.UploadKey)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to hash uploaded object")
+		return nil, err
+	}
+
+	blob, err := s.attachmentBlob(ctx.Request().Context(), upload.UploadKey, checksum, info.Size, upload.MimeType)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to store attachment blob")
+		return nil, err
+	}
+
+	if upload.AttachmentID != nil {
+		attachment, err := s.todoRepo.ConfirmPendingVersionUpload(ctx.Request().Context(), upload.ID, blob)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to record attachment version")
+			// an upload still pending keeps its object, one that went with a deleted attachment loses it
+			s.enqueueUnusedObjects(ctx.Request().Context(), "", upload.UploadKey, blob.StorageKey)
+			return nil, err
+		}
+		s.enqueueUnusedObjects(ctx.Request().Context(), attachment.DownloadKey, upload.UploadKey, blob.StorageKey)
+
+		s.attachmentVersionAdded(ctx, userID, attachment)
+
+		return attachment, nil
+	}
+
+	attachment, err := s.todoRepo.ConfirmPendingUpload(ctx.Request().Context(), upload.ID, blob)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create attachment record")
+		s.enqueueUnusedObjects(ctx.Request().Context(), "", upload.UploadKey, blob.StorageKey)
+		return nil, err
+	}
+	s.enqueueUnusedObjects(ctx.Request().Context(), attachment.DownloadKey, upload.UploadKey, blob.StorageKey)
+
+	logger.Info().
+		Str("attachmentID", attachment.ID.String()).
+		Str("s3_key", attachment.DownloadKey).
+		Str("checksum", checksum).
+		Msg("confirmed todo attachment upload")
+
+	s.enqueueAttachmentScan(ctx, attachment)
+
+	publishEvent(ctx, s.server, todoAudience(ctx, s.shareRepo, s.authService, payload.TodoID, userID), realtime.EventAttachmentCreated, attachment)
+
+	return attachment, nil
+}
+
+// archiveKeyPrefix keeps archives apart from attachment objects, so reconciliation leaves them to their own cleanup
+const archiveKeyPrefix = "todos/archives/"
+
+// archiveKey is where the ZIP of an archive built in the background is stored
+func archiveKey(archive *todo.AttachmentArchive) string {
+	return fmt.Sprintf("%s%s/%s.zip", archiveKeyPrefix, url.PathEscape(archive.RequestedBy), archive.ID)
+}
+
+func (s *TodoService) handleBuildAttachmentArchiveTask(ctx context.Context, t *asynq.Task) error {
+	var p job.BuildAttachmentArchivePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal build attachment archive payload: %w", err)
+	}
+
+	archiveID, err := uuid.Parse(p.ArchiveID)
+	if err != nil {
+		return fmt.Errorf("invalid archive id %q: %w", p.ArchiveID, err)
+	}
+
+	return s.BuildAttachmentArchive(ctx, archiveID)
+}
+
+// BuildAttachmentArchive writes the ZIP of a pending archive to storage and tells the requester it
+// is ready. Attachments deleted since the request, or replaced by a version still being scanned, are
+// left out. The archive is only marked failed once the task runs out of retries.
+func (s *TodoService) BuildAttachmentArchive(ctx context.Context, archiveID uuid.UUID) error {
+	logger := s.server.Logger.With().Str("archive_id", archiveID.String()).Logger()
+
+	archive, err := s.todoRepo.GetAttachmentArchiveForJob(ctx, archiveID)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			logger.Info().Msg("archive was cleaned up before it was built")
+			return nil
+		}
+		return err
+	}
+
+	if archive.Status != todo.ArchiveStatusPending {
+		return nil
+	}
+	if archive.TodoID == nil {
+		s.failAttachmentArchive(ctx, archive, "the todo was deleted")
+		return nil
+	}
+
+	attachments, err := s.todoRepo.GetAttachmentsForArchive(ctx, *archive.TodoID, archive.AttachmentIDs)
+	if err != nil {
+		return err
+	}
+	attachments = slices.DeleteFunc(attachments, func(attachment todo.AttachmentWithTodoTitle) bool {
+		return attachment.Kind != todo.AttachmentKindFile || attachment.ScanStatus != todo.ScanStatusClean
+	})
+
+	//the ZIP is written into a pipe that storage reads from, so it is never held in memory
+	key := archiveKey(archive)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.writeAttachmentArchive(ctx, writer, *archive.TodoID, attachments))
+	}()
+	stored, err := s.storage.Put(ctx, key, reader, storage.PutOptions{ContentType: "application/zip"})
+	reader.CloseWithError(err)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to build attachment archive")
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retried >= maxRetry {
+			s.failAttachmentArchive(ctx, archive, "the archive could not be built")
+		}
+		return err
+	}
+
+	ready, err := s.todoRepo.CompleteAttachmentArchive(ctx, archive.ID, key, stored.Size, len(attachments))
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			// built by another run, or cleaned up meanwhile and the object goes with the next cleanup
+			return nil
+		}
+		return err
+	}
+
+	// Business event log
+	logger.Info().
+		Str("event", "attachment_archive_built").
+		Str("todo_id", archive.TodoID.String()).
+		Int("file_count", len(attachments)).
+		Int64("file_size", stored.Size).
+		Msg("attachment archive built")
+
+	publishBackgroundEvent(ctx, s.server, []string{ready.RequestedBy}, realtime.EventAttachmentArchiveReady, ready)
+
+	return nil
+}
+
+// failAttachmentArchive gives up on an archive and tells the requester, failures are only logged
+func (s *TodoService) failAttachmentArchive(ctx context.Context, archive *todo.AttachmentArchive, message string) {
+	failed, err := s.todoRepo.FailAttachmentArchive(ctx, archive.ID, message)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Str("archive_id", archive.ID.String()).Msg("failed to mark attachment archive failed")
+		return
+	}
+
+	publishBackgroundEvent(ctx, s.server, []string{failed.RequestedBy}, realtime.EventAttachmentArchiveFailed, failed)
+}
+
+// archiveCleanupBatch is how many expired archives one pass of the cleanup loads at a time
+const archiveCleanupBatch = 100
+
+func (s *TodoService) handleCleanupArchivesTask(ctx context.Context, t *asynq.Task) error {
+	return s.CleanupArchives(ctx)
+}
+
+// CleanupArchives removes the objects and records of archives past their expiry. A record is
+// kept when its object could not be deleted so a later cleanup retries.
+func (s *TodoService) CleanupArchives(ctx context.Context) error {
+	removed := 0
+	for {
+		archives, err := s.todoRepo.GetExpiredAttachmentArchives(ctx, archiveCleanupBatch)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for i := range archives {
+			archive := &archives[i]
+			if err := s.storage.Delete(ctx, archiveKey(archive)); err != nil {
+				s.server.Logger.Error().Err(err).Str("archive_id", archive.ID.String()).Msg("failed to delete archive from storage")
+				failed++
+				continue
+			}
+			if err := s.todoRepo.DeleteAttachmentArchive(ctx, archive.ID); err != nil {
+				s.server.Logger.Error().Err(err).Str("archive_id", archive.ID.String()).Msg("failed to delete archive record")
+				failed++
+				continue
+			}
+		}
+		removed += len(archives) - failed
+
+		// a batch that could not be fully removed would be loaded again, leave it for the next run
+		if len(archives) < archiveCleanupBatch || failed > 0 {
+			break
+		}
+	}
+
+	s.server.Logger.Info().
+		Str("event", "attachment_archives_cleaned").
+		Int("removed", removed).
+		Msg("cleaned up expired attachment archives")
+
+	return nil
+}
+
+// pendingUploadCleanupBatch is how many expired uploads one pass of the cleanup loads at a time
+const pendingUploadCleanupBatch = 100
+
+// CleanupPendingUploads removes the objects and records of uploads that were never confirmed
+func (s *TodoService) CleanupPendingUploads(ctx context.Context) error {
+	removed := 0
+	for {
+		uploads, err := s.todoRepo.GetExpiredPendingUploads(ctx, pendingUploadCleanupBatch)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for i := range uploads {
+			if !s.discardPendingUpload(ctx, &uploads[i]) {
+				failed++
+			}
+		}
+		removed += len(uploads) - failed
+
+		// a batch that could not be fully removed would be loaded again, leave it for the next run
+		if len(uploads) < pendingUploadCleanupBatch || failed > 0 {
+			break
+		}
+	}
+
+	s.server.Logger.Info().
+		Str("event", "pending_uploads_cleaned").
+		Int("removed", removed).
+		Msg("cleaned up unconfirmed attachment uploads")
+
+	return nil
+}
+
+func (s *TodoService) handleCleanupPendingUploadsTask(ctx context.Context, t *asynq.Task) error {
+	return s.CleanupPendingUploads(ctx)
+}
+
+// discardPendingUpload deletes the uploaded object, if any, and then its record.
+// The record is kept when storage fails so a later cleanup retries.
+func (s *TodoService) discardPendingUpload(ctx context.Context, upload *todo.PendingUpload) bool {
+	err := s.storage.Delete(ctx, upload.UploadKey)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Str("s3_key", upload.UploadKey).Msg("failed to delete pending upload from storage")
+		return false
+	}
+
+	if err := s.todoRepo.DeletePendingUpload(ctx, upload.ID); err != nil {
+		s.server.Logger.Error().Err(err).Str("upload_id", upload.ID.String()).Msg("failed to delete pending upload record")
+		return false
+	}
+
+	return true
+}
+
+// errFileTooLarge rejects attachments over the configured size
+func errFileTooLarge(maxFileSize int64) error {
+	code := "FILE_TOO_LARGE"
+	return errs.NewBadRequestError(fmt.Sprintf("attachments can be at most %d bytes", maxFileSize), true, &code, nil, nil)
+}
+
+// enqueueAttachmentScan queues the virus scan of a new attachment. The attachment stays
+// pending and undownloadable until the scan finishes, so a failure here is only logged.
+func (s *TodoService) enqueueAttachmentScan(ctx echo.Context, attachment *todo.TodoAttachment) {
+	logger := middleware.GetLogger(ctx)
+
+	task, err := job.NewScanAttachmentTask(attachment.ID.String())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create attachment scan task")
+		return
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil {
+		logger.Error().Err(err).Str("attachment_id", attachment.ID.String()).Msg("failed to enqueue attachment scan")
+	}
+}
+
+func (s *TodoService) handleScanAttachmentTask(ctx context.Context, t *asynq.Task) error {
+	var p job.ScanAttachmentPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal scan attachment payload: %w", err)
+	}
+
+	attachmentID, err := uuid.Parse(p.AttachmentID)
+	if err != nil {
+		return fmt.Errorf("invalid attachment id %q: %w", p.AttachmentID, err)
+	}
+
+	return s.ScanAttachment(ctx, attachmentID)
+}
+
+// ScanAttachment runs the configured scanner over a pending attachment and records the verdict.
+// Quarantining notifies the uploader by realtime event and email.
+func (s *TodoService) ScanAttachment(ctx context.Context, attachmentID uuid.UUID) error {
+	logger := s.server.Logger.With().
+		Str("attachment_id", attachmentID.String()).
+		Str("scanner", s.scanner.Name()).
+		Logger()
+
+	attachment, err := s.todoRepo.GetAttachmentForJob(ctx, attachmentID)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			logger.Info().Msg("attachment was deleted before it was scanned")
+			return nil
+		}
+		return err
+	}
+
+	// a retried task may find the verdict already recorded
+	if attachment.ScanStatus != todo.ScanStatusPending {
+		return nil
+	}
+
+	file, _, err := s.storage.Get(ctx, attachment.DownloadKey)
+	if err != nil {
+		return fmt.Errorf("failed to open attachment for scanning: %w", err)
+	}
+	defer file.Close()
+
+	result, err := s.scanner.Scan(ctx, file)
+	if err != nil {
+		logger.Warn().Err(err).Msg("attachment scan failed, it will be retried")
+		return err
+	}
+
+	status := todo.ScanStatusClean
+	var signature *string
+	if !result.Clean {
+		status = todo.ScanStatusQuarantined
+		signature = &result.Signature
+	} else if err := s.stripAttachmentLocation(ctx, &attachment.TodoAttachment); err != nil {
+		// the file only becomes downloadable once its location data is gone
+		return err
+	}
+
+	updated, err := s.todoRepo.SetAttachmentScanResult(ctx, attachmentID, attachment.DownloadKey, status, signature)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			// a new version replaced the scanned file, it has a scan of its own queued
+			logger.Info().Msg("attachment changed while it was scanned")
+			return nil
+		}
+		return err
+	}
+
+	// Business event log
+	eventLogger := logger.With().
+		Str("event", "attachment_scanned").
+		Str("todo_id", updated.TodoID.String()).
+		Str("scan_status", string(updated.ScanStatus)).
+		Logger()
+	if signature != nil {
+		eventLogger.Warn().Str("signature", *signature).Msg("attachment quarantined")
+	} else {
+		eventLogger.Info().Msg("attachment scanned clean")
+	}
+
+	audience, err := resolveTodoAudience(ctx, s.shareRepo, s.authService, updated.TodoID)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to resolve todo audience")
+	}
+	publishBackgroundEvent(ctx, s.server, withActor(audience, updated.UploadedBy), realtime.EventAttachmentScanned, updated)
+
+	if updated.ScanStatus == todo.ScanStatusQuarantined {
+		s.notifyQuarantined(ctx, attachment.TodoTitle, updated)
+	} else {
+		s.enqueueAttachmentProcessing(ctx, updated)
+		s.enqueueAttachmentExtraction(ctx, updated.ID)
+	}
+
+	return nil
+}
+
+// notifyQuarantined tells the uploader their file was flagged, failures are only logged
+func (s *TodoService) notifyQuarantined(ctx context.Context, todoTitle string, attachment *todo.TodoAttachment) {
+	publishBackgroundEvent(ctx, s.server, []string{attachment.UploadedBy}, realtime.EventAttachmentQuarantined, attachment)
+
+	task, err := job.NewAttachmentQuarantinedEmailTask(attachment.UploadedBy, attachment.TodoID.String(), todoTitle,
+		attachment.Name, *attachment.ScanSignature)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Msg("failed to create attachment quarantined email task")
+		return
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil {
+		s.server.Logger.Error().Err(err).Str("uploader_id", attachment.UploadedBy).Msg("failed to enqueue attachment quarantined email")
+	}
+}
+
+// checkAttachmentDownloadable refuses files the scanner has not cleared
+func checkAttachmentDownloadable(status todo.ScanStatus) error {
+	switch status {
+	case todo.ScanStatusClean:
+		return nil
+	case todo.ScanStatusQuarantined:
+		code := "ATTACHMENT_QUARANTINED"
+		return errs.NewConflictError("this attachment was flagged by the virus scanner and cannot be downloaded", true, &code)
+	default:
+		code := "ATTACHMENT_SCAN_PENDING"
+		return errs.NewConflictError("this attachment is still being scanned, try again shortly", true, &code)
+	}
+}
+
+// checkAttachmentFile rejects link attachments where a stored file is needed
+func checkAttachmentFile(kind todo.AttachmentKind) error {
+	if kind == todo.AttachmentKindLink {
+		code := "ATTACHMENT_IS_LINK"
+		return errs.NewBadRequestError("link attachments have no file, open their url instead", true, &code, nil, nil)
+	}
+	return nil
+}
+
+// errStorageQuotaExceeded rejects uploads that do not fit in what is left of the user's quota
+func errStorageQuotaExceeded(quota int64) error {
+	code := "STORAGE_QUOTA_EXCEEDED"
+	return errs.NewBadRequestError(fmt.Sprintf("this upload would exceed your storage quota of %d bytes", quota), true, &code, nil, nil)
+}
+
+// errMimeTypeNotAllowed rejects attachment types outside the configured allowlist
+func errMimeTypeNotAllowed(mimeType string) error {
+	code := "MIME_TYPE_NOT_ALLOWED"
+	return errs.NewBadRequestError(fmt.Sprintf("files of type %s cannot be attached", mimeType), true, &code, nil, nil)
+}
+
+// sniffObjectType detects the type of a stored object from its first 512 bytes
+func (s *TodoService) sniffObjectType(ctx context.Context, key string) (string, error) {
+	body, err := s.storage.GetRange(ctx, key, 0, 512)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	defer body.Close()
+
+	head, err := io.ReadAll(io.LimitReader(body, 512))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	return http.DetectContentType(head), nil
+}
+
+// sameMediaType compares content types ignoring case and parameters such as charset
+func sameMediaType(a, b string) bool {
+	mediaType := func(value string) string {
+		if parsed, _, err := mime.ParseMediaType(value); err == nil {
+			return parsed
+		}
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+
+	return mediaType(a) == mediaType(b)
+}
+
+// errAttachmentTooLargeToProcess marks objects too big to load into memory for media work
+var errAttachmentTooLargeToProcess = errors.New("attachment is too large to process")
+
+// thumbnailFormats are rendered for every thumbnail size, WebP for browsers that take it and JPEG for the rest
+var thumbnailFormats = []string{media.FormatWebP, media.FormatJPEG}
+
+// readAttachmentObject loads a stored attachment into memory, refusing anything over the upload limit
+func (s *TodoService) readAttachmentObject(ctx context.Context, key string) ([]byte, error) {
+	body, _, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer body.Close()
+
+	limit := s.server.Config.Attachment.MaxFileSize
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, errAttachmentTooLargeToProcess
+	}
+
+	return data, nil
+}
+
+// stripAttachmentLocation removes GPS metadata from a stored image and rewrites the object.
+// Images whose metadata cannot be parsed are left as they are.
+func (s *TodoService) stripAttachmentLocation(ctx context.Context, attachment *todo.TodoAttachment) error {
+	if attachment.MimeType == nil || !media.IsImage(*attachment.MimeType) {
+		return nil
+	}
+
+	logger := s.server.Logger.With().Str("attachment_id", attachment.ID.String()).Logger()
+
+	data, err := s.readAttachmentObject(ctx, attachment.DownloadKey)
+	if err != nil {
+		if errors.Is(err, errAttachmentTooLargeToProcess) {
+			logger.Warn().Msg("attachment too large to strip location data")
+			return nil
+		}
+		return err
+	}
+
+	stripped, changed, err := media.StripLocation(data, *attachment.MimeType)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to parse image metadata, location data was not stripped")
+		return nil
+	}
+	if !changed {
+		return nil
+	}
+
+	//the original may be shared with other attachments, so the stripped file is stored as a blob of its own
+	checksum := sha256.Sum256(stripped)
+	blob := &todo.AttachmentBlob{
+		Checksum: hex.EncodeToString(checksum[:]),
+		FileSize: int64(len(stripped)),
+		MimeType: *attachment.MimeType,
+	}
+	blob.StorageKey = attachmentBlobKey(blob.Checksum)
+	_, err = s.storage.Put(ctx, blob.StorageKey, bytes.NewReader(stripped), storage.PutOptions{ContentType: blob.MimeType})
+	if err != nil {
+		return err
+	}
+
+	key, err := s.todoRepo.SetAttachmentBlob(ctx, attachment.ID, attachment.DownloadKey, attachment.Name, blob)
+	if err != nil {
+		s.enqueueUnusedObjects(ctx, "", blob.StorageKey)
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			// a new version replaced the file while it was scanned, its own scan is queued
+			return nil
+		}
+		return err
+	}
+	s.enqueueUnusedObjects(ctx, key, attachment.DownloadKey, blob.StorageKey)
+
+	attachment.DownloadKey = key
+	attachment.Checksum = &blob.Checksum
+	attachment.FileSize = &blob.FileSize
+
+	logger.Info().Msg("stripped location data from attachment")
+
+	return nil
+}
+
+// enqueueAttachmentProcessing queues thumbnails and metadata for an attachment that was scanned clean
+func (s *TodoService) enqueueAttachmentProcessing(ctx context.Context, attachment *todo.TodoAttachment) {
+	task, err := job.NewProcessAttachmentTask(attachment.ID.String())
+	if err != nil {
+		s.server.Logger.Error().Err(err).Msg("failed to create attachment process task")
+		return
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil {
+		s.server.Logger.Error().Err(err).Str("attachment_id", attachment.ID.String()).Msg("failed to enqueue attachment processing")
+	}
+}
+
+func (s *TodoService) handleProcessAttachmentTask(ctx context.Context, t *asynq.Task) error {
+	var p job.ProcessAttachmentPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal process attachment payload: %w", err)
+	}
+
+	attachmentID, err := uuid.Parse(p.AttachmentID)
+	if err != nil {
+		return fmt.Errorf("invalid attachment id %q: %w", p.AttachmentID, err)
+	}
+
+	return s.ProcessAttachment(ctx, attachmentID)
+}
+
+// ProcessAttachment records the dimensions or page count of a clean attachment and renders
+// image thumbnails in every size and format. Files that cannot be decoded are marked processed
+// without thumbnails so they are not retried forever.
+func (s *TodoService) ProcessAttachment(ctx context.Context, attachmentID uuid.UUID) error {
+	logger := s.server.Logger.With().Str("attachment_id", attachmentID.String()).Logger()
+
+	attachment, err := s.todoRepo.GetAttachmentForJob(ctx, attachmentID)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			logger.Info().Msg("attachment was deleted before it was processed")
+			return nil
+		}
+		return err
+	}
+
+	if attachment.ScanStatus != todo.ScanStatusClean || attachment.ProcessedAt != nil {
+		return nil
+	}
+
+	info, err := s.extractAttachmentMedia(ctx, &attachment.TodoAttachment)
+	if err != nil {
+		logger.Warn().Err(err).Msg("attachment processing failed, it will be retried")
+		return err
+	}
+
+	updated, err := s.todoRepo.SetAttachmentMedia(ctx, attachmentID, attachment.DownloadKey, info)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			// processed by another run, or the file was replaced by a new version and reconciliation
+			// removes the thumbnails made for it
+			return nil
+		}
+		return err
+	}
+
+	// Business event log
+	logger.Info().
+		Str("event", "attachment_processed").
+		Str("todo_id", updated.TodoID.String()).
+		Int("thumbnails", len(updated.Thumbnails)).
+		Msg("attachment processed")
+
+	s.presignThumbnails(ctx, updated.Thumbnails)
+
+	audience, err := resolveTodoAudience(ctx, s.shareRepo, s.authService, updated.TodoID)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to resolve todo audience")
+	}
+	publishBackgroundEvent(ctx, s.server, withActor(audience, updated.UploadedBy), realtime.EventAttachmentUpdated, updated)
+
+	return nil
+}
+
+// extractAttachmentMedia reads what the attachment's type allows: a page count for PDFs,
+// dimensions and stored thumbnails for images. Only storage failures are returned as errors.
+func (s *TodoService) extractAttachmentMedia(ctx context.Context, attachment *todo.TodoAttachment) (*todo.AttachmentMedia, error) {
+	logger := s.server.Logger.With().Str("attachment_id", attachment.ID.String()).Logger()
+	info := &todo.AttachmentMedia{Thumbnails: []todo.Thumbnail{}}
+
+	mimeType := ""
+	if attachment.MimeType != nil {
+		mimeType = *attachment.MimeType
+	}
+	isPDF := sameMediaType(mimeType, "application/pdf")
+	if !isPDF && !media.IsImage(mimeType) {
+		return info, nil
+	}
+
+	data, err := s.readAttachmentObject(ctx, attachment.DownloadKey)
+	if err != nil {
+		if errors.Is(err, errAttachmentTooLargeToProcess) {
+			logger.Info().Msg("attachment too large to process")
+			return info, nil
+		}
+		return nil, err
+	}
+
+	if isPDF {
+		pageCount, err := media.PDFPageCount(data)
+		if err != nil {
+			logger.Info().Err(err).Msg("failed to count pdf pages")
+			return info, nil
+		}
+		info.PageCount = &pageCount
+		return info, nil
+	}
+
+	img, err := media.DecodeImage(data)
+	if err != nil {
+		logger.Info().Err(err).Msg("failed to decode image attachment")
+		return info, nil
+	}
+
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	info.Width, info.Height = &width, &height
+
+	for _, size := range media.ThumbnailSizes {
+		resized := media.Resize(img, size.MaxEdge)
+		for _, format := range thumbnailFormats {
+			encoded, err := media.Encode(resized, format)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s thumbnail: %w", size.Name, err)
+			}
+
+			key := fmt.Sprintf("%s_thumb_%s.%s", attachment.DownloadKey, size.Name, format)
+			_, err = s.storage.Put(ctx, key, bytes.NewReader(encoded), storage.PutOptions{ContentType: media.ContentType(format)})
+			if err != nil {
+				return nil, err
+			}
+
+			info.Thumbnails = append(info.Thumbnails, todo.Thumbnail{
+				Size:   size.Name,
+				Format: format,
+				Width:  resized.Bounds().Dx(),
+				Height: resized.Bounds().Dy(),
+				Key:    key,
+			})
+		}
+	}
+
+	return info, nil
+}
+
+// extractionEnabled is false when the noop extractor turned search of attachment contents off.
+// Attachments are then left unextracted, so an extractor configured later still reads them.
+func (s *TodoService) extractionEnabled() bool {
+	_, off := s.extractor.(extractor.NoopExtractor)
+	return !off
+}
+
+// enqueueAttachmentExtraction queues reading the text of an attachment that was scanned clean.
+// The backfill picks up attachments whose task could not be queued, so a failure is only logged.
+func (s *TodoService) enqueueAttachmentExtraction(ctx context.Context, attachmentID uuid.UUID) {
+	if !s.extractionEnabled() {
+		return
+	}
+
+	task, err := job.NewExtractAttachmentTextTask(attachmentID.String(), s.server.Config.Extractor.Timeout)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Msg("failed to create attachment text extraction task")
+		return
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		s.server.Logger.Error().Err(err).Str("attachment_id", attachmentID.String()).Msg("failed to enqueue attachment text extraction")
+	}
+}
+
+func (s *TodoService) handleExtractAttachmentTextTask(ctx context.Context, t *asynq.Task) error {
+	var p job.ExtractAttachmentTextPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal extract attachment text payload: %w", err)
+	}
+
+	attachmentID, err := uuid.Parse(p.AttachmentID)
+	if err != nil {
+		return fmt.Errorf("invalid attachment id %q: %w", p.AttachmentID, err)
+	}
+
+	return s.ExtractAttachmentText(ctx, attachmentID)
+}
+
+// ExtractAttachmentText reads the text of a clean file attachment so searches find it. Types the
+// extractor cannot read, files over the size limit and documents that run out of time are recorded
+// without text and not tried again. Other failures are retried until the last attempt gives up the same way.
+func (s *TodoService) ExtractAttachmentText(ctx context.Context, attachmentID uuid.UUID) error {
+	logger := s.server.Logger.With().
+		Str("attachment_id", attachmentID.String()).
+		Str("extractor", s.extractor.Name()).
+		Logger()
+
+	attachment, err := s.todoRepo.GetAttachmentForJob(ctx, attachmentID)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			logger.Info().Msg("attachment was deleted before its text was extracted")
+			return nil
+		}
+		return err
+	}
+
+	if attachment.Kind != todo.AttachmentKindFile || attachment.ScanStatus != todo.ScanStatusClean || attachment.ExtractedAt != nil {
+		return nil
+	}
+
+	text, err := s.extractAttachmentText(ctx, &attachment.TodoAttachment)
+	if err != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retried < maxRetry {
+			logger.Warn().Err(err).Msg("attachment text extraction failed, it will be retried")
+			return err
+		}
+		logger.Warn().Err(err).Msg("attachment text extraction failed, its contents stay unsearchable")
+	}
+
+	updated, err := s.todoRepo.SetAttachmentText(ctx, attachmentID, attachment.DownloadKey, text)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			// extracted by another run, or replaced by a new version that is extracted on its own
+			return nil
+		}
+		return err
+	}
+
+	textSize := 0
+	if updated.ContentText != nil {
+		textSize = len(*updated.ContentText)
+	}
+
+	// Business event log
+	logger.Info().
+		Str("event", "attachment_text_extracted").
+		Str("todo_id", updated.TodoID.String()).
+		Int("text_size", textSize).
+		Msg("attachment text extracted")
+
+	return nil
+}
+
+// extractAttachmentText runs the extractor over the stored file within the configured size and time
+// bounds. It returns nil when there is no text to keep, errors are storage or extractor failures.
+func (s *TodoService) extractAttachmentText(ctx context.Context, attachment *todo.TodoAttachment) (*string, error) {
+	logger := s.server.Logger.With().Str("attachment_id", attachment.ID.String()).Logger()
+	cfg := s.server.Config.Extractor
+
+	mimeType := attachment.ContentType()
+	if !s.extractor.Supports(mimeType) {
+		return nil, nil
+	}
+	if attachment.FileSize != nil && *attachment.FileSize > cfg.MaxFileSize {
+		logger.Info().Int64("max_file_size", cfg.MaxFileSize).Msg("attachment too large to extract text from")
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	body, _, err := s.storage.Get(ctx, attachment.DownloadKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer body.Close()
+
+	text, err := s.extractor.Extract(ctx, io.LimitReader(body, cfg.MaxFileSize), mimeType)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logger.Info().Dur("timeout", cfg.Timeout).Msg("attachment text extraction timed out")
+		return nil, nil
+	case errors.Is(err, extractor.ErrUnsupported):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	text = extractor.Clean(text, cfg.MaxTextSize)
+	if text == "" {
+		return nil, nil
+	}
+
+	return &text, nil
+}
+
+// textBackfillBatchSize is how many unextracted attachments are queued per query
+const textBackfillBatchSize = 100
+
+func (s *TodoService) handleBackfillAttachmentTextTask(ctx context.Context, t *asynq.Task) error {
+	return s.BackfillAttachmentText(ctx)
+}
+
+// BackfillAttachmentText queues extraction for clean attachments whose text was never read,
+// those uploaded before extraction existed or whose task could not be queued
+func (s *TodoService) BackfillAttachmentText(ctx context.Context) error {
+	if !s.extractionEnabled() {
+		return nil
+	}
+
+	queued := 0
+	afterID := uuid.Nil
+	for {
+		attachmentIDs, err := s.todoRepo.GetUnextractedAttachmentIDs(ctx, afterID, textBackfillBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, attachmentID := range attachmentIDs {
+			s.enqueueAttachmentExtraction(ctx, attachmentID)
+		}
+		queued += len(attachmentIDs)
+
+		if len(attachmentIDs) < textBackfillBatchSize {
+			break
+		}
+		afterID = attachmentIDs[len(attachmentIDs)-1]
+	}
+
+	if queued > 0 {
+		// Business event log
+		s.server.Logger.Info().
+			Str("event", "attachment_text_backfilled").
+			Int("queued", queued).
+			Msg("queued text extraction for attachments")
+	}
+
+	return nil
+}
+
+// presignThumbnails fills in download URLs for an attachment's thumbnails in place, a thumbnail
+// that cannot be signed is served without one
+func (s *TodoService) presignThumbnails(ctx context.Context, thumbnails []todo.Thumbnail) {
+	for i := range thumbnails {
+		thumbnail := &thumbnails[i]
+		url, err := s.storage.PresignGet(ctx, thumbnail.Key, s.server.Config.Attachment.DownloadURLExpiry, storage.PresignGetOptions{})
+		if err != nil {
+			s.server.Logger.Warn().Err(err).Str("s3_key", thumbnail.Key).Msg("failed to presign thumbnail")
+			continue
+		}
+		thumbnail.URL = url
+	}
+}
+
+func (s *TodoService) presignTodoThumbnails(ctx context.Context, todoItem *todo.PopulatedTodo) {
+	for i := range todoItem.Attachment {
+		s.presignThumbnails(ctx, todoItem.Attachment[i].Thumbnails)
+	}
+}
+
+// attachmentKeyPrefix is where every attachment object, thumbnail and pending upload is stored
+const attachmentKeyPrefix = "todos/attachments/"
+
+// attachmentKey is where an uploaded file lands, by uploader and todo, until it is stored as a blob.
+// The version ID keeps it unique, client file names never end up in keys. A first version shares its
+// ID with the attachment.
+func attachmentKey(userID string, todoID, versionID uuid.UUID) string {
+	return fmt.Sprintf("%s%s/%s/%s", attachmentKeyPrefix, url.PathEscape(userID), todoID, versionID)
+}
+
+// attachmentBlobKey addresses a new blob by its checksum. The generation ID keeps content that was
+// released and uploaded again off a key whose deletion may still be queued.
+func attachmentBlobKey(checksum string) string {
+	return fmt.Sprintf("%sblobs/%s/%s", attachmentKeyPrefix, checksum, uuid.New())
+}
+
+// attachmentBlob describes the object stored at key as a blob to record attachments with. Content
+// that is already stored reuses that blob, anything else is copied to a content addressed key.
+// Key itself is only recorded when the existing blob is released before the attachment is.
+func (s *TodoService) attachmentBlob(ctx context.Context, key, checksum string, fileSize int64, mimeType string) (*todo.AttachmentBlob, error) {
+	blob := &todo.AttachmentBlob{
+		Checksum:   checksum,
+		StorageKey: key,
+		FileSize:   fileSize,
+		MimeType:   mimeType,
+	}
+
+	existing, err := s.todoRepo.GetAttachmentBlob(ctx, checksum)
+	if err == nil && existing.RefCount > 0 {
+		return blob, nil
+	}
+	var httpErr *errs.HTTPError
+	if err != nil && !(errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound) {
+		return nil, err
+	}
+
+	blob.StorageKey = attachmentBlobKey(checksum)
+	if err := s.storage.Copy(ctx, key, blob.StorageKey); err != nil {
+		return nil, fmt.Errorf("failed to copy attachment blob: %w", err)
+	}
+
+	return blob, nil
+}
+
+// hashAttachmentObject reads a stored object through SHA-256
+func (s *TodoService) hashAttachmentObject(ctx context.Context, key string) (string, *storage.ObjectInfo, error) {
+	body, info, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", nil, fmt.Errorf("failed to hash attachment: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), info, nil
+}
+
+// enqueueUnusedObjects queues the deletion of every key other than kept, the uploaded objects and
+// blob copies that an attachment did not end up pointing at
+func (s *TodoService) enqueueUnusedObjects(ctx context.Context, kept string, keys ...string) {
+	unused := []string{}
+	for _, key := range keys {
+		if key != kept && !slices.Contains(unused, key) {
+			unused = append(unused, key)
+		}
+	}
+
+	s.enqueueStorageDeletion(ctx, unused)
+}
+
+// reconcileBatchSize is how many listed keys are checked against the database at once
+const reconcileBatchSize = 500
+
+// enqueueStorageDeletion queues the removal of objects no record points at any more.
+// If even that fails the reconciliation job finds the objects later, so it is only logged.
+func (s *TodoService) enqueueStorageDeletion(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	task, err := job.NewDeleteStorageObjectsTask(keys)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Msg("failed to create storage deletion task")
+		return
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil {
+		s.server.Logger.Error().Err(err).Strs("s3_keys", keys).Msg("failed to enqueue storage deletion")
+	}
+}
+
+func (s *TodoService) handleDeleteStorageObjectsTask(ctx context.Context, t *asynq.Task) error {
+	var p job.DeleteStorageObjectsPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal delete storage objects payload: %w", err)
+	}
+
+	return s.DeleteStorageObjects(ctx, p.Keys)
+}
+
+// DeleteStorageObjects removes the keys nothing references any more, failing if any of them could
+// not be deleted so the whole task is retried. Keys that are already gone count as deleted. Blobs
+// shared with other attachments are kept until their last reference is deleted.
+func (s *TodoService) DeleteStorageObjects(ctx context.Context, keys []string) error {
+	if err := s.todoRepo.ReleaseAttachmentBlobs(ctx, keys); err != nil {
+		return err
+	}
+
+	unreferenced, err := s.todoRepo.FindUnreferencedStorageKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, key := range unreferenced {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			s.server.Logger.Warn().Err(err).Str("s3_key", key).Msg("failed to delete object from storage")
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d objects", failed, len(unreferenced))
+	}
+
+	return nil
+}
+
+func (s *TodoService) handleReconcileStorageTask(ctx context.Context, t *asynq.Task) error {
+	var p job.ReconcileStoragePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal reconcile storage payload: %w", err)
+	}
+
+	_, err := s.ReconcileStorage(ctx, p.DryRun)
+	return err
+}
+
+// ReconcileStorage lists the attachment prefix and compares it against the database. Objects
+// nothing points at are deleted, or only reported in a dry run, and attachments whose object is
+// missing are reported. Anything younger than the grace period is left out of both checks.
+func (s *TodoService) ReconcileStorage(ctx context.Context, dryRun bool) (*todo.StorageReconciliation, error) {
+	logger := s.server.Logger.With().
+		Str("prefix", attachmentKeyPrefix).
+		Bool("dry_run", dryRun).
+		Logger()
+	cutoff := time.Now().Add(-s.server.Config.Attachment.OrphanGracePeriod)
+
+	report := &todo.StorageReconciliation{
+		Prefix:       attachmentKeyPrefix,
+		DryRun:       dryRun,
+		OrphanedKeys: []string{},
+		MissingKeys:  []string{},
+	}
+
+	//blobs whose release was never queued would keep their objects forever
+	if !dryRun {
+		released, err := s.todoRepo.ReleaseUnusedAttachmentBlobs(ctx, cutoff)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to release unused blobs")
+			return nil, err
+		}
+		report.ReleasedBlobs = int(released)
+	}
+
+	seen := map[string]bool{}
+	batch := []string{}
+	checkBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		orphans, err := s.todoRepo.FindUnreferencedStorageKeys(ctx, batch)
+		if err != nil {
+			return err
+		}
+		batch = batch[:0]
+
+		for _, key := range orphans {
+			report.OrphanedKeys = append(report.OrphanedKeys, key)
+			if dryRun {
+				logger.Info().Str("s3_key", key).Msg("found orphaned object")
+				continue
+			}
+
+			if err := s.storage.Delete(ctx, key); err != nil {
+				logger.Warn().Err(err).Str("s3_key", key).Msg("failed to delete orphaned object")
+				continue
+			}
+			report.DeletedObjects++
+		}
+
+		return nil
+	}
+
+	err := s.storage.List(ctx, attachmentKeyPrefix, func(info storage.ObjectInfo) error {
+		report.ScannedObjects++
+		seen[info.Key] = true
+
+		if info.ModifiedAt.After(cutoff) {
+			return nil
+		}
+
+		batch = append(batch, info.Key)
+		if len(batch) >= reconcileBatchSize {
+			return checkBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list stored objects")
+		return nil, err
+	}
+	if err := checkBatch(); err != nil {
+		logger.Error().Err(err).Msg("failed to check stored objects")
+		return nil, err
+	}
+
+	keys, err := s.todoRepo.GetAttachmentDownloadKeys(ctx, attachmentKeyPrefix, cutoff)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load attachment keys")
+		return nil, err
+	}
+	for _, key := range keys {
+		if !seen[key] {
+			logger.Warn().Str("s3_key", key).Msg("attachment object is missing from storage")
+			report.MissingKeys = append(report.MissingKeys, key)
+		}
+	}
+
+	// Business event log
+	logger.Info().
+		Str("event", "storage_reconciled").
+		Int("scanned_objects", report.ScannedObjects).
+		Int("released_blobs", report.ReleasedBlobs).
+		Int("orphaned_objects", len(report.OrphanedKeys)).
+		Int("deleted_objects", report.DeletedObjects).
+		Int("missing_objects", len(report.MissingKeys)).
+		Msg("attachment storage reconciled")
+
+	return report, nil
+}
+
+// blobBackfillBatchSize is how many attachments or versions without a checksum are hashed per query
+const blobBackfillBatchSize = 100
+
+func (s *TodoService) handleBackfillAttachmentBlobsTask(ctx context.Context, t *asynq.Task) error {
+	return s.BackfillAttachmentBlobs(ctx)
+}
+
+// BackfillAttachmentBlobs hashes the attachments and versions stored before deduplication and moves
+// them onto blobs, sanitizing display names on the way. Records are switched before their old objects
+// are deleted, so downloads keep working throughout. Thumbnails stay where they are.
+func (s *TodoService) BackfillAttachmentBlobs(ctx context.Context) error {
+	logger := s.server.Logger.With().Str("operation", "backfill_attachment_blobs").Logger()
+
+	migrated, failed := 0, 0
+	afterID := uuid.Nil
+	for {
+		attachments, err := s.todoRepo.GetUnhashedAttachments(ctx, afterID, blobBackfillBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to load attachments without checksum")
+			return err
+		}
+		if len(attachments) == 0 {
+			break
+		}
+
+		for i := range attachments {
+			attachment := &attachments[i]
+			afterID = attachment.ID
+			err := s.backfillAttachmentBlob(ctx, attachment.DownloadKey, func(blob *todo.AttachmentBlob) (string, error) {
+				return s.todoRepo.SetAttachmentBlob(ctx, attachment.ID, attachment.DownloadKey, filename.Sanitize(attachment.Name), blob)
+			})
+			if err != nil {
+				logger.Warn().Err(err).Str("attachment_id", attachment.ID.String()).Msg("failed to backfill attachment blob")
+				failed++
+				continue
+			}
+			migrated++
+		}
+	}
+
+	afterID = uuid.Nil
+	for {
+		versions, err := s.todoRepo.GetUnhashedAttachmentVersions(ctx, afterID, blobBackfillBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to load attachment versions without checksum")
+			return err
+		}
+		if len(versions) == 0 {
+			break
+		}
+
+		for i := range versions {
+			version := &versions[i]
+			afterID = version.ID
+			err := s.backfillAttachmentBlob(ctx, version.DownloadKey, func(blob *todo.AttachmentBlob) (string, error) {
+				return s.todoRepo.SetAttachmentVersionBlob(ctx, version.ID, version.DownloadKey, filename.Sanitize(version.Name), blob)
+			})
+			if err != nil {
+				logger.Warn().Err(err).Str("version_id", version.ID.String()).Msg("failed to backfill attachment version blob")
+				failed++
+				continue
+			}
+			migrated++
+		}
+	}
+
+	if migrated > 0 || failed > 0 {
+		// Business event log
+		logger.Info().
+			Str("event", "attachment_blobs_backfilled").
+			Int("migrated", migrated).
+			Int("failed", failed).
+			Msg("attachment blobs backfilled")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to backfill %d attachment blobs", failed)
+	}
+
+	return nil
+}
+
+// backfillAttachmentBlob hashes the object at key and records it as a blob through setBlob,
+// which returns the key the record ended up with. The object at key goes once nothing uses it.
+func (s *TodoService) backfillAttachmentBlob(ctx context.Context, key string, setBlob func(blob *todo.AttachmentBlob) (string, error)) error {
+	checksum, info, err := s.hashAttachmentObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			// nothing to hash, reconciliation reports the record as missing its object
+			return nil
+		}
+		return err
+	}
+
+	blob, err := s.attachmentBlob(ctx, key, checksum, info.Size, info.ContentType)
+	if err != nil {
+		return err
+	}
+
+	kept, err := setBlob(blob)
+	if err != nil {
+		s.enqueueUnusedObjects(ctx, key, blob.StorageKey)
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+			// the record was deleted or replaced by another run while hashing
+			return nil
+		}
+		return err
+	}
+	s.enqueueUnusedObjects(ctx, kept, key, blob.StorageKey)
+
+	return nil
 }
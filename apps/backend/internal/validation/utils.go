@@ -15,6 +15,12 @@ type Validatable interface {
 	Validate() error
 }
 
+// StreamedBody is implemented by payloads whose request body is read by the handler itself,
+// such as streamed file uploads. Only their path parameters are bound.
+type StreamedBody interface {
+	StreamedBody()
+}
+
 type CustomValidationError struct {
 	Field   string
 	Message string
@@ -27,7 +33,14 @@ func (c CustomValidationErrors) Error() string {
 }
 
 func BindAndValidate(c echo.Context, payload Validatable) error {
-	if err := c.Bind(payload); err != nil {
+	bind := c.Bind
+	if _, ok := payload.(StreamedBody); ok {
+		bind = func(i interface{}) error {
+			return (&echo.DefaultBinder{}).BindPathParams(c, i)
+		}
+	}
+
+	if err := bind(payload); err != nil {
 		message := strings.Split(strings.Split(err.Error(), ",")[1], "message=")[1]
 		return errs.NewBadRequestError(message, false, nil, nil, nil)
 	}
@@ -86,6 +99,12 @@ func extractValidationErrors(err error) (string, []errs.FieldError) {
 			msg = "must be a valid phone number with country code"
 		case "uuid":
 			msg = "must be a valid UUID"
+		case "http_url":
+			msg = "must be an http or https URL"
+		case "required_if":
+			msg = "is required when " + conditionText(err.Param())
+		case "excluded_unless":
+			msg = "is only allowed when " + conditionText(err.Param())
 		case "uuidList":
 			msg = "must be a comma-separated list of valid UUIDs"
 		case "dive":
@@ -112,3 +131,9 @@ var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4
 func IsValidUUID(uuid string) bool {
 	return uuidRegex.MatchString(uuid)
 }
+
+// conditionText turns the "Field value" param of a conditional tag into "field is value"
+func conditionText(param string) string {
+	field, value, _ := strings.Cut(param, " ")
+	return strings.ToLower(field) + " is " + value
+}
@@ -0,0 +1,112 @@
+import {
+  Body,
+  Button,
+  Container,
+  Head,
+  Heading,
+  Hr,
+  Html,
+  Img,
+  Link,
+  Preview,
+  Section,
+  Text,
+  Tailwind,
+} from "@react-email/components";
+
+interface AttachmentQuarantinedEmailProps {
+  todoTitle: string;
+  todoID: string;
+  fileName: string;
+  signature: string;
+}
+
+export const AttachmentQuarantinedEmail = ({
+  todoTitle = "{{.TodoTitle}}",
+  todoID = "{{.TodoID}}",
+  fileName = "{{.FileName}}",
+  signature = "{{.Signature}}",
+}: AttachmentQuarantinedEmailProps) => {
+  return (
+    <Html>
+      <Head />
+      <Preview>"{fileName}" was quarantined</Preview>
+      <Tailwind>
+        <Body className="bg-gray-100 font-sans">
+          <Container className="bg-white p-8 rounded-lg shadow-sm my-10 mx-auto max-w-[600px]">
+            <Section className="mb-6 text-center">
+              <Img
+                src="http://localhost:8080/static/full_logo.png?height=48&width=48"
+                width="48"
+                height="48"
+                alt="Tasker Logo"
+                className="mx-auto"
+              />
+              <Heading className="text-2xl font-bold text-gray-800 mt-4">
+                🛡️ Attachment Quarantined
+              </Heading>
+            </Section>
+
+            <Section className="bg-red-50 border-l-4 border-red-400 p-4 mb-6">
+              <Text className="font-semibold text-red-700 text-lg mb-2">
+                {fileName}
+              </Text>
+              <Text className="text-gray-700 text-base">
+                Detected: {signature}
+              </Text>
+            </Section>
+
+            <Section>
+              <Text className="text-gray-700 text-base">
+                The file you attached to "{todoTitle}" was flagged by our virus
+                scanner. It has been quarantined and can no longer be
+                downloaded. If you believe this is a mistake, remove it and
+                upload a clean copy.
+              </Text>
+            </Section>
+
+            <Section className="my-8 text-center">
+              <Button
+                className="bg-blue-600 hover:bg-blue-700 text-white font-medium rounded-md px-6 py-3"
+                href={`/todos?id=${todoID}`}
+              >
+                View Todo
+              </Button>
+            </Section>
+
+            <Hr className="border-gray-200 my-6" />
+
+            <Section>
+              <Text className="text-gray-600 text-sm">
+                You're receiving this email because a file you uploaded was
+                quarantined.{" "}
+                <Link
+                  href={`/settings/notifications`}
+                  className="text-blue-600 underline"
+                >
+                  Manage notification preferences
+                </Link>
+                .
+              </Text>
+            </Section>
+
+            <Section className="mt-8 text-center">
+              <Text className="text-gray-500 text-xs">
+                © {new Date().getFullYear()} Tasker. All rights reserved.
+              </Text>
+            </Section>
+          </Container>
+        </Body>
+      </Tailwind>
+    </Html>
+  );
+};
+
+AttachmentQuarantinedEmail.PreviewProps = {
+  todoTitle: "Complete quarterly report",
+  todoID: "123e4567-e89b-12d3-a456-426614174000",
+  fileName: "invoice.pdf.exe",
+  signature: "Win.Trojan.Agent-1234",
+};
+
+export default AttachmentQuarantinedEmail;
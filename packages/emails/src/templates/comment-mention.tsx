@@ -0,0 +1,112 @@
+import {
+  Body,
+  Button,
+  Container,
+  Head,
+  Heading,
+  Hr,
+  Html,
+  Img,
+  Link,
+  Preview,
+  Section,
+  Text,
+  Tailwind,
+} from "@react-email/components";
+
+interface CommentMentionEmailProps {
+  todoTitle: string;
+  todoID: string;
+  authorName: string;
+  commentExcerpt: string;
+}
+
+export const CommentMentionEmail = ({
+  todoTitle = "{{.TodoTitle}}",
+  todoID = "{{.TodoID}}",
+  authorName = "{{.AuthorName}}",
+  commentExcerpt = "{{.CommentExcerpt}}",
+}: CommentMentionEmailProps) => {
+  return (
+    <Html>
+      <Head />
+      <Preview>
+        {authorName} mentioned you on "{todoTitle}"
+      </Preview>
+      <Tailwind>
+        <Body className="bg-gray-100 font-sans">
+          <Container className="bg-white p-8 rounded-lg shadow-sm my-10 mx-auto max-w-[600px]">
+            <Section className="mb-6 text-center">
+              <Img
+                src="http://localhost:8080/static/full_logo.png?height=48&width=48"
+                width="48"
+                height="48"
+                alt="Tasker Logo"
+                className="mx-auto"
+              />
+              <Heading className="text-2xl font-bold text-gray-800 mt-4">
+                💬 You Were Mentioned
+              </Heading>
+            </Section>
+
+            <Section className="bg-blue-50 border-l-4 border-blue-400 p-4 mb-6">
+              <Text className="font-semibold text-blue-700 text-lg mb-2">
+                "{todoTitle}"
+              </Text>
+              <Text className="text-gray-700 text-base italic">
+                {commentExcerpt}
+              </Text>
+            </Section>
+
+            <Section>
+              <Text className="text-gray-700 text-base">
+                {authorName} mentioned you in a comment on this todo. Reply
+                there to keep the conversation in one place.
+              </Text>
+            </Section>
+
+            <Section className="my-8 text-center">
+              <Button
+                className="bg-blue-600 hover:bg-blue-700 text-white font-medium rounded-md px-6 py-3"
+                href={`/todos?id=${todoID}`}
+              >
+                View Todo
+              </Button>
+            </Section>
+
+            <Hr className="border-gray-200 my-6" />
+
+            <Section>
+              <Text className="text-gray-600 text-sm">
+                You're receiving this email because someone mentioned you in a
+                comment.{" "}
+                <Link
+                  href={`/settings/notifications`}
+                  className="text-blue-600 underline"
+                >
+                  Manage notification preferences
+                </Link>
+                .
+              </Text>
+            </Section>
+
+            <Section className="mt-8 text-center">
+              <Text className="text-gray-500 text-xs">
+                © {new Date().getFullYear()} Tasker. All rights reserved.
+              </Text>
+            </Section>
+          </Container>
+        </Body>
+      </Tailwind>
+    </Html>
+  );
+};
+
+CommentMentionEmail.PreviewProps = {
+  todoTitle: "Complete quarterly report",
+  todoID: "123e4567-e89b-12d3-a456-426614174000",
+  authorName: "Jane Cooper",
+  commentExcerpt: "@john can you double check the revenue numbers before Friday?",
+};
+
+export default CommentMentionEmail;
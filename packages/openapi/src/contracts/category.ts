@@ -1,5 +1,11 @@
 import { getSecurityMetadata } from "../utils.js";
-import { schemaWithPagination, ZTodoCategory } from "@tasker/zod";
+import {
+  schemaWithPagination,
+  ZCategoryNode,
+  ZCategoryWithStats,
+  ZDeleteCategoryResult,
+  ZTodoCategory,
+} from "@tasker/zod";
 import { initContract } from "@ts-rest/core";
 import z from "zod";
 
@@ -17,12 +23,30 @@ export const categoryContract = c.router(
       query: z.object({
         page: z.number().min(1).optional(),
         limit: z.number().min(1).max(100).optional(),
-        sort: z.enum(["created_at", "updated_at", "name"]).optional(),
+        sort: z
+          .enum(["created_at", "updated_at", "name", "position"])
+          .optional(),
         order: z.enum(["asc", "desc"]).optional(),
         search: z.string().min(1).optional(),
+        includeArchived: z.boolean().optional(),
       }),
       responses: {
-        200: schemaWithPagination(ZTodoCategory),
+        200: schemaWithPagination(ZCategoryWithStats),
+      },
+      metadata: metadata,
+    },
+
+    getCategoryTree: {
+      summary: "Get the category tree",
+      path: "/categories/tree",
+      method: "GET",
+      description:
+        "Get all categories of the workspace nested under their parent categories",
+      query: z.object({
+        includeArchived: z.boolean().optional(),
+      }),
+      responses: {
+        200: z.array(ZCategoryNode),
       },
       metadata: metadata,
     },
@@ -36,8 +60,10 @@ export const categoryContract = c.router(
         name: true,
         color: true,
         description: true,
+        parentCategoryId: true,
       }).partial({
         description: true,
+        parentCategoryId: true,
       }),
       responses: {
         201: ZTodoCategory,
@@ -72,13 +98,74 @@ export const categoryContract = c.router(
       metadata: metadata,
     },
 
+    moveCategory: {
+      summary: "Move category",
+      path: "/categories/:id/move",
+      method: "POST",
+      description:
+        "Move a category with its subcategories under another parent, or to the top level when parentCategoryId is null",
+      body: z.object({
+        parentCategoryId: z.string().uuid().nullable(),
+      }),
+      responses: {
+        200: ZTodoCategory,
+      },
+      metadata: metadata,
+    },
+
+    reorderCategories: {
+      summary: "Reorder categories",
+      path: "/categories/reorder",
+      method: "POST",
+      description:
+        "Order the subcategories of a parent, or the top level categories when parentCategoryId is null. Categories left out follow the listed ones",
+      body: z.object({
+        parentCategoryId: z.string().uuid().nullable().optional(),
+        categoryIds: z.array(z.string().uuid()).min(1).max(500),
+      }),
+      responses: {
+        200: z.array(ZTodoCategory),
+      },
+      metadata: metadata,
+    },
+
+    archiveCategory: {
+      summary: "Archive category",
+      path: "/categories/:id/archive",
+      method: "POST",
+      description:
+        "Archive a category with its subcategories, hiding them and their todos from default listings",
+      body: z.object({}),
+      responses: {
+        200: ZTodoCategory,
+      },
+      metadata: metadata,
+    },
+
+    unarchiveCategory: {
+      summary: "Unarchive category",
+      path: "/categories/:id/unarchive",
+      method: "POST",
+      description: "Restore an archived category with its subcategories",
+      body: z.object({}),
+      responses: {
+        200: ZTodoCategory,
+      },
+      metadata: metadata,
+    },
+
     deleteCategory: {
       summary: "Delete category",
       path: "/categories/:id",
       method: "DELETE",
-      description: "Delete category",
+      description:
+        "Delete a category with its subcategories. Their todos are moved to the target category (reassign), kept without a category (uncategorize, the default) or deleted with their subtasks (cascade)",
+      query: z.object({
+        strategy: z.enum(["reassign", "uncategorize", "cascade"]).optional(),
+        target: z.string().uuid().optional(),
+      }),
       responses: {
-        204: z.void(),
+        200: ZDeleteCategoryResult,
       },
       metadata: metadata,
     },
@@ -1,5 +1,12 @@
 import { getSecurityMetadata } from "../utils.js";
-import { ZTodoComment } from "@tasker/zod";
+import {
+  schemaWithCursor,
+  schemaWithPagination,
+  ZCommentRevision,
+  ZPopulatedComment,
+  ZPopulatedMention,
+  ZTodoComment,
+} from "@tasker/zod";
 import { initContract } from "@ts-rest/core";
 import z from "zod";
 
@@ -13,8 +20,11 @@ export const commentContract = c.router(
       summary: "Add comment to todo",
       path: "/todos/:id/comments",
       method: "POST",
-      body: ZTodoComment.pick({
-        content: true,
+      description:
+        "Add a comment, or a reply when parentCommentId is set. Replies to a reply join the thread of its top-level comment",
+      body: z.object({
+        content: z.string().min(1).max(1000),
+        parentCommentId: z.string().uuid().optional(),
       }),
       responses: {
         201: ZTodoComment,
@@ -26,8 +36,30 @@ export const commentContract = c.router(
       summary: "Get comments for todo",
       path: "/todos/:id/comments",
       method: "GET",
+      description:
+        "Get the top-level comments of a todo, oldest first, a page at a time with the nextCursor of the previous page",
+      query: z.object({
+        cursor: z.string().optional(),
+        limit: z.number().min(1).max(100).optional(),
+      }),
       responses: {
-        200: z.array(ZTodoComment),
+        200: schemaWithCursor(ZPopulatedComment),
+      },
+      metadata: metadata,
+    },
+
+    getCommentReplies: {
+      summary: "Get comment replies",
+      path: "/comments/:id/replies",
+      method: "GET",
+      description:
+        "Get the replies to a top-level comment, oldest first, a page at a time with the nextCursor of the previous page",
+      query: z.object({
+        cursor: z.string().optional(),
+        limit: z.number().min(1).max(100).optional(),
+      }),
+      responses: {
+        200: schemaWithCursor(ZPopulatedComment),
       },
       metadata: metadata,
     },
@@ -49,11 +81,91 @@ export const commentContract = c.router(
       summary: "Delete comment",
       path: "/comments/:id",
       method: "DELETE",
+      description:
+        "Delete a comment as its author or as the todo owner. Comments with replies and those removed by the todo owner are kept as placeholders",
       responses: {
         204: z.void(),
       },
       metadata: metadata,
     },
+
+    getCommentRevisions: {
+      summary: "Get comment revisions",
+      path: "/comments/:id/revisions",
+      method: "GET",
+      description:
+        "Get the versions of a comment that edits replaced, newest first",
+      responses: {
+        200: z.array(ZCommentRevision),
+      },
+      metadata: metadata,
+    },
+
+    hideComment: {
+      summary: "Hide comment",
+      path: "/comments/:id/hide",
+      method: "POST",
+      description: "Collapse a comment as the todo owner",
+      body: z.object({}),
+      responses: {
+        200: ZTodoComment,
+      },
+      metadata: metadata,
+    },
+
+    unhideComment: {
+      summary: "Unhide comment",
+      path: "/comments/:id/unhide",
+      method: "POST",
+      description: "Show a hidden comment again as the todo owner",
+      body: z.object({}),
+      responses: {
+        200: ZTodoComment,
+      },
+      metadata: metadata,
+    },
+
+    addReaction: {
+      summary: "React to comment",
+      path: "/comments/:id/reactions",
+      method: "POST",
+      description:
+        "Add the caller's reaction with a single emoji, reacting twice with the same emoji has no effect",
+      body: z.object({
+        emoji: z.string().min(1).max(32),
+      }),
+      responses: {
+        200: ZPopulatedComment,
+      },
+      metadata: metadata,
+    },
+
+    removeReaction: {
+      summary: "Remove reaction",
+      path: "/comments/:id/reactions/:emoji",
+      method: "DELETE",
+      description: "Remove the caller's reaction with the percent-encoded emoji",
+      responses: {
+        204: z.void(),
+      },
+      metadata: metadata,
+    },
+
+    getMentions: {
+      summary: "Get my mentions",
+      path: "/comments/mentions",
+      method: "GET",
+      description:
+        "Get the comments that @mention the caller in any workspace, newest first",
+      query: z.object({
+        page: z.number().min(1).optional(),
+        limit: z.number().min(1).max(100).optional(),
+      }),
+      responses: {
+        200: schemaWithPagination(ZPopulatedMention),
+      },
+      metadata: metadata,
+    },
   },
   {
     pathPrefix: "/v1",
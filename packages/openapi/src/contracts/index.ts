@@ -3,6 +3,9 @@ import { healthContract } from "./health.js";
 import { todoContract } from "./todo.js";
 import { commentContract } from "./comment.js";
 import { categoryContract } from "./category.js";
+import { realtimeContract } from "./realtime.js";
+import { shareContract } from "./share.js";
+import { markdownContract } from "./markdown.js";
 
 const c = initContract();
 
@@ -11,4 +14,7 @@ export const apiContract = c.router({
   Todo: todoContract,
   Comment: commentContract,
   Categroy: categoryContract,
+  Realtime: realtimeContract,
+  Share: shareContract,
+  Markdown: markdownContract,
 });
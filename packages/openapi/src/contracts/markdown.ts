@@ -0,0 +1,30 @@
+import { getSecurityMetadata } from "../utils.js";
+import { ZMarkdownPreview } from "@tasker/zod";
+import { initContract } from "@ts-rest/core";
+import z from "zod";
+
+const c = initContract();
+
+const metadata = getSecurityMetadata();
+
+export const markdownContract = c.router(
+  {
+    previewMarkdown: {
+      summary: "Preview markdown",
+      path: "/markdown/preview",
+      method: "POST",
+      description:
+        "Render markdown to the sanitized HTML that comments and descriptions are stored with",
+      body: z.object({
+        content: z.string().max(10000),
+      }),
+      responses: {
+        200: ZMarkdownPreview,
+      },
+      metadata: metadata,
+    },
+  },
+  {
+    pathPrefix: "/v1",
+  }
+);
@@ -0,0 +1,53 @@
+import { getSecurityMetadata } from "../utils.js";
+import { ZRealtimeEvent } from "@tasker/zod";
+import { initContract } from "@ts-rest/core";
+import z from "zod";
+
+const c = initContract();
+
+const metadata = getSecurityMetadata();
+
+export const realtimeContract = c.router(
+  {
+    streamEvents: {
+      summary: "Stream change events",
+      path: "/realtime/events",
+      method: "GET",
+      description:
+        "Stream the caller's change events as Server-Sent Events. After a reconnect the events since Last-Event-ID are replayed, a resync event asks the client to refetch when they are no longer buffered. Browsers that cannot set headers pass the session token as token",
+      headers: z.object({
+        "last-event-id": z.string().optional(),
+      }),
+      query: z.object({
+        lastEventId: z.string().optional(),
+        token: z.string().optional(),
+      }),
+      responses: {
+        200: c.otherResponse({
+          contentType: "text/event-stream",
+          body: ZRealtimeEvent,
+        }),
+      },
+      metadata: metadata,
+    },
+
+    streamEventsWebSocket: {
+      summary: "Stream change events over WebSocket",
+      path: "/realtime/ws",
+      method: "GET",
+      description:
+        "Upgrade to a WebSocket that sends the caller's change events as JSON text messages, replaying those after lastEventId first",
+      query: z.object({
+        lastEventId: z.string().optional(),
+        token: z.string().optional(),
+      }),
+      responses: {
+        101: c.noBody(),
+      },
+      metadata: metadata,
+    },
+  },
+  {
+    pathPrefix: "/v1",
+  }
+);
@@ -0,0 +1,134 @@
+import { getSecurityMetadata } from "../utils.js";
+import { ZPopulatedShare, ZShare, ZShareRole, ZShareStatus } from "@tasker/zod";
+import { initContract } from "@ts-rest/core";
+import z from "zod";
+
+const c = initContract();
+
+const metadata = getSecurityMetadata();
+
+const createShareBody = z.object({
+  granteeId: z.string().min(1).optional(),
+  email: z.string().email().optional(),
+  role: ZShareRole,
+});
+
+export const shareContract = c.router(
+  {
+    shareTodo: {
+      summary: "Share todo",
+      path: "/todos/:id/shares",
+      method: "POST",
+      description:
+        "Invite a user, by ID or email, to the todo and its subtasks. The share is pending until the grantee accepts it",
+      body: createShareBody,
+      responses: {
+        201: ZShare,
+      },
+      metadata: metadata,
+    },
+
+    getTodoShares: {
+      summary: "Get todo shares",
+      path: "/todos/:id/shares",
+      method: "GET",
+      description: "Get the shares of a todo",
+      responses: {
+        200: z.array(ZShare),
+      },
+      metadata: metadata,
+    },
+
+    shareCategory: {
+      summary: "Share category",
+      path: "/categories/:id/shares",
+      method: "POST",
+      description:
+        "Invite a user, by ID or email, to the category, its subcategories and their todos. The share is pending until the grantee accepts it",
+      body: createShareBody,
+      responses: {
+        201: ZShare,
+      },
+      metadata: metadata,
+    },
+
+    getCategoryShares: {
+      summary: "Get category shares",
+      path: "/categories/:id/shares",
+      method: "GET",
+      description: "Get the shares of a category",
+      responses: {
+        200: z.array(ZShare),
+      },
+      metadata: metadata,
+    },
+
+    getSharedWithMe: {
+      summary: "Get items shared with me",
+      path: "/shares/shared-with-me",
+      method: "GET",
+      description:
+        "Get the shares granted to the caller with the shared todo or category, accepted ones unless another status is asked for",
+      query: z.object({
+        status: ZShareStatus.optional(),
+      }),
+      responses: {
+        200: z.array(ZPopulatedShare),
+      },
+      metadata: metadata,
+    },
+
+    updateShare: {
+      summary: "Update share",
+      path: "/shares/:id",
+      method: "PATCH",
+      description: "Change the role a share grants",
+      body: ZShare.pick({
+        role: true,
+      }),
+      responses: {
+        200: ZShare,
+      },
+      metadata: metadata,
+    },
+
+    acceptShare: {
+      summary: "Accept share invitation",
+      path: "/shares/:id/accept",
+      method: "POST",
+      description: "Accept a pending share invitation",
+      body: z.object({}),
+      responses: {
+        200: ZShare,
+      },
+      metadata: metadata,
+    },
+
+    declineShare: {
+      summary: "Decline share invitation",
+      path: "/shares/:id/decline",
+      method: "POST",
+      description: "Decline a pending share invitation",
+      body: z.object({}),
+      responses: {
+        200: ZShare,
+      },
+      metadata: metadata,
+    },
+
+    deleteShare: {
+      summary: "Delete share",
+      path: "/shares/:id",
+      method: "DELETE",
+      description:
+        "Revoke a share when called by its owner, or leave it when called by its grantee",
+      responses: {
+        204: z.void(),
+      },
+      metadata: metadata,
+    },
+  },
+  {
+    pathPrefix: "/v1",
+  }
+);
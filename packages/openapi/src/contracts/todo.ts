@@ -1,9 +1,13 @@
 import { getSecurityMetadata } from "@/utils.js";
 import {
   schemaWithPagination,
+  ZAttachmentArchive,
+  ZAttachmentUpload,
   ZPopulatedTodo,
+  ZStorageUsage,
   ZTodo,
   ZTodoAttachment,
+  ZTodoAssignee,
   ZTodoStats,
 } from "@tasker/zod";
 import { initContract } from "@ts-rest/core";
@@ -37,11 +41,15 @@ export const todoContract = c.router({
       status: ZTodo.shape.status.optional(),
       priority: ZTodo.shape.priority.optional(),
       categoryId: z.string().uuid().optional(),
+      includeSubcategories: z.boolean().optional(),
       parentTodoId: z.string().uuid().optional(),
       dueFrom: z.string().datetime().optional(),
       dueTo: z.string().datetime().optional(),
       overdue: z.boolean().optional(),
       completed: z.boolean().optional(),
+      assignee: z.string().min(1).optional(),
+      unassigned: z.boolean().optional(),
+      includeArchivedCategories: z.boolean().optional(),
     }),
     responses: {
       200: schemaWithPagination(ZPopulatedTodo),
@@ -105,6 +113,21 @@ export const todoContract = c.router({
     metadata: metadata,
   },
 
+  toggleDescriptionTask: {
+    summary: "Toggle description task",
+    path: "/todos/:id/description/tasks/:index",
+    method: "PATCH",
+    description:
+      "Check or uncheck the task list item at the zero based index in the markdown description, leaving the rest untouched",
+    body: z.object({
+      checked: z.boolean(),
+    }),
+    responses: {
+      200: ZTodo,
+    },
+    metadata: metadata,
+  },
+
   deleteTodo: {
     summary: "Delete todo",
     path: "/todos/:id",
@@ -126,6 +149,89 @@ export const todoContract = c.router({
     },
     metadata: metadata,
   },
+
+  getStorageUsage: {
+    summary: "Get attachment storage usage",
+    path: "/todos/attachments/usage",
+    method: "GET",
+    description:
+      "Get how much of the storage quota the caller's attachments use, by attachment type and by category",
+    responses: {
+      200: ZStorageUsage,
+    },
+    metadata: metadata,
+  },
+
+  getInbox: {
+    summary: "Get todos assigned to me",
+    path: "/todos/inbox",
+    method: "GET",
+    description:
+      "Get the todos assigned to the caller in any workspace, by due date unless sorted otherwise",
+    query: z.object({
+      page: z.number().min(1).optional(),
+      limit: z.number().min(1).max(100).optional(),
+      sort: z
+        .enum(["created_at", "updated_at", "title", "priority", "due_date"])
+        .optional(),
+      order: z.enum(["asc", "desc"]).optional(),
+      status: ZTodo.shape.status.optional(),
+      completed: z.boolean().optional(),
+    }),
+    responses: {
+      200: schemaWithPagination(ZPopulatedTodo),
+    },
+    metadata: metadata,
+  },
+
+  assignTodo: {
+    summary: "Assign todo",
+    path: "/todos/:id/assignees",
+    method: "POST",
+    description:
+      "Assign users who can already see the todo. Assignment grants no access on its own",
+    body: z.object({
+      userIds: z.array(z.string().min(1)).min(1).max(20),
+    }),
+    responses: {
+      200: z.array(ZTodoAssignee),
+    },
+    metadata: metadata,
+  },
+
+  unassignTodo: {
+    summary: "Unassign todo",
+    path: "/todos/:id/assignees/:userId",
+    method: "DELETE",
+    description:
+      "Remove an assignee, editors may remove anyone and assignees themselves",
+    responses: {
+      204: z.void(),
+    },
+    metadata: metadata,
+  },
+    getTodoAttachments: {
+      summary: "Get todo attachments",
+      path: "/todos/:id/attachments",
+      method: "GET",
+      description: "Get the file and link attachments of a todo",
+      responses: {
+        200: z.array(ZTodoAttachment),
+      },
+      metadata: metadata,
+    },
+
+    getTodoAttachment: {
+      summary: "Get todo attachment",
+      path: "/todos/:id/attachments/:attachmentId",
+      method: "GET",
+      description: "Get the metadata of an attachment",
+      responses: {
+        200: ZTodoAttachment,
+      },
+      metadata: metadata,
+    },
+
      uploadTodoAttachment: {
       summary: "Upload attachment to todo",
       path: "/todos/:id/attachments",
@@ -143,6 +249,54 @@ export const todoContract = c.router({
       metadata: metadata,
     },
 
+    requestAttachmentUpload: {
+      summary: "Request a direct upload",
+      path: "/todos/:id/attachments/uploads",
+      method: "POST",
+      description:
+        "Get a presigned PUT URL, or a POST policy, to send the file straight to storage. Headers must be sent with a PUT, fields go before the file in a multipart POST. The file becomes an attachment once the upload is confirmed",
+      body: z.object({
+        name: z.string().min(1).max(255),
+        mimeType: z.string().max(255),
+        fileSize: z.number().min(1),
+        method: z.enum(["PUT", "POST"]).optional(),
+      }),
+      responses: {
+        201: ZAttachmentUpload,
+      },
+      metadata: metadata,
+    },
+
+    confirmAttachmentUpload: {
+      summary: "Confirm a direct upload",
+      path: "/todos/:id/attachments/uploads/:uploadId/confirm",
+      method: "POST",
+      description:
+        "Create the attachment once the uploaded file has been checked against the requested size and type, the allowed types and the storage quota",
+      body: z.object({}),
+      responses: {
+        201: ZTodoAttachment,
+      },
+      metadata: metadata,
+    },
+
+    createAttachmentLink: {
+      summary: "Attach a link to todo",
+      path: "/todos/:id/attachments/links",
+      method: "POST",
+      description:
+        "Attach an http(s) URL with an optional title and description, it is listed with the file attachments",
+      body: z.object({
+        url: z.string().url().max(2048),
+        title: z.string().min(1).max(255).optional(),
+        description: z.string().max(1000).optional(),
+      }),
+      responses: {
+        201: ZTodoAttachment,
+      },
+      metadata: metadata,
+    },
+
     deleteTodoAttachment: {
       summary: "Delete todo attachment",
       path: "/todos/:id/attachments/:attachmentId",
@@ -158,11 +312,72 @@ export const todoContract = c.router({
       summary: "Get attachment download URL",
       path: "/todos/:id/attachments/:attachmentId/download",
       method: "GET",
-      description: "Get a presigned URL to download an attachment",
+      description:
+        "Get a presigned URL to download an attachment, or the URL of a link attachment which does not expire",
+      query: z.object({
+        expiresIn: z.number().min(60).optional(),
+        disposition: z.enum(["inline", "attachment"]).optional(),
+      }),
       responses: {
         200: z.object({
           url: z.string().url(),
+          disposition: z.enum(["inline", "attachment"]),
+          expiresAt: z.string().datetime().optional(),
+        }),
+      },
+      metadata: metadata,
+    },
+
+    getAttachmentContent: {
+      summary: "Stream attachment content",
+      path: "/todos/:id/attachments/:attachmentId/content",
+      method: "GET",
+      description:
+        "Stream an attachment through the API, with Range support, for clients that cannot reach storage directly",
+      query: z.object({
+        disposition: z.enum(["inline", "attachment"]).optional(),
+      }),
+      responses: {
+        200: c.otherResponse({
+          contentType: "application/octet-stream",
+          body: z.any(),
+        }),
+        206: c.otherResponse({
+          contentType: "application/octet-stream",
+          body: z.any(),
+        }),
+      },
+      metadata: metadata,
+    },
+
+    getAttachmentArchive: {
+      summary: "Download attachments as a ZIP",
+      path: "/todos/:id/attachments/archive",
+      method: "GET",
+      description:
+        "Stream a ZIP of all or the selected attachments, optionally with those of subtasks. Archives too large to stream are built in the background and answered with 202",
+      query: z.object({
+        attachmentIds: z.array(z.string().uuid()).max(500).optional(),
+        includeSubtasks: z.boolean().optional(),
+      }),
+      responses: {
+        200: c.otherResponse({
+          contentType: "application/zip",
+          body: z.any(),
         }),
+        202: ZAttachmentArchive,
+      },
+      metadata: metadata,
+    },
+
+    getAttachmentArchiveStatus: {
+      summary: "Get attachment archive",
+      path: "/todos/:id/attachments/archive/:archiveId",
+      method: "GET",
+      description:
+        "Get the status of an archive built in the background, with a download URL once it is ready",
+      responses: {
+        200: ZAttachmentArchive,
       },
       metadata: metadata,
     },
@@ -6,6 +6,40 @@ export const ZTodoCategory = z.object({
   name: z.string(),
   color: z.string(),
   description: z.string().nullable(),
+  parentCategoryId: z.string().uuid().nullable(),
+  position: z.number(),
+  archivedAt: z.string().nullable(),
   createdAt: z.string(),
   updatedAt: z.string(),
 });
+
+export const ZCategoryStats = z.object({
+  total: z.number(),
+  draft: z.number(),
+  active: z.number(),
+  completed: z.number(),
+  archived: z.number(),
+  overdue: z.number(),
+  completionPercent: z.number(),
+});
+
+export const ZCategoryWithStats = ZTodoCategory.extend({
+  stats: ZCategoryStats,
+});
+
+export const ZDeleteCategoryResult = z.object({
+  categoryId: z.string().uuid(),
+  strategy: z.enum(["reassign", "uncategorize", "cascade"]),
+  targetCategoryId: z.string().uuid().optional(),
+  deletedCategories: z.number(),
+  affectedTodos: z.number(),
+  todoIds: z.array(z.string().uuid()),
+});
+
+export type TCategoryNode = z.infer<typeof ZTodoCategory> & {
+  children: TCategoryNode[];
+};
+
+export const ZCategoryNode: z.ZodType<TCategoryNode> = ZTodoCategory.extend({
+  children: z.lazy(() => z.array(ZCategoryNode)),
+});
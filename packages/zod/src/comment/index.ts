@@ -5,6 +5,53 @@ export const ZTodoComment = z.object({
   todoId: z.string().uuid(),
   userId: z.string(),
   content: z.string(),
+  contentHtml: z.string(),
+  parentCommentId: z.string().uuid().nullable(),
+  editedAt: z.string().nullable(),
+  deletedAt: z.string().nullable(),
+  deletedBy: z.string().nullable(),
+  hiddenAt: z.string().nullable(),
+  hiddenBy: z.string().nullable(),
   createdAt: z.string(),
   updatedAt: z.string(),
 });
+
+export const ZCommentRevision = z.object({
+  id: z.string().uuid(),
+  commentId: z.string().uuid(),
+  content: z.string(),
+  contentHtml: z.string(),
+  editedBy: z.string(),
+  createdAt: z.string(),
+});
+
+export const ZCommentReaction = z.object({
+  commentId: z.string().uuid(),
+  userId: z.string(),
+  emoji: z.string(),
+  createdAt: z.string(),
+});
+
+export const ZReactionSummary = z.object({
+  emoji: z.string(),
+  count: z.number(),
+  reactedByMe: z.boolean(),
+});
+
+export const ZPopulatedComment = ZTodoComment.extend({
+  replyCount: z.number(),
+  reactions: z.array(ZReactionSummary),
+});
+
+export const ZMention = z.object({
+  commentId: z.string().uuid(),
+  todoId: z.string().uuid(),
+  userId: z.string(),
+  mentionedBy: z.string(),
+  createdAt: z.string(),
+});
+
+export const ZPopulatedMention = ZMention.extend({
+  todoTitle: z.string(),
+  comment: ZTodoComment,
+});
@@ -8,3 +8,6 @@ export * from "./health.js";
 export * from "./todo/index.js";
 export * from "./comment/index.js";
 export * from "./category/index.js";
+export * from "./realtime/index.js";
+export * from "./share/index.js";
+export * from "./markdown/index.js";
@@ -0,0 +1,5 @@
+import z from "zod";
+
+export const ZMarkdownPreview = z.object({
+  html: z.string(),
+});
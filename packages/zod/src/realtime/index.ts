@@ -0,0 +1,36 @@
+import z from "zod";
+
+export const ZRealtimeEventType = z.enum([
+  "todo.created",
+  "todo.updated",
+  "todo.deleted",
+  "todo.assigned",
+  "todo.unassigned",
+  "comment.created",
+  "comment.updated",
+  "comment.deleted",
+  "comment.mentioned",
+  "comment.reaction_added",
+  "comment.reaction_removed",
+  "category.created",
+  "category.updated",
+  "category.deleted",
+  "attachment.created",
+  "attachment.deleted",
+  "attachment.scanned",
+  "attachment.quarantined",
+  "attachment.updated",
+  "attachment.archive_ready",
+  "attachment.archive_failed",
+  "share.invited",
+  "share.updated",
+  "share.deleted",
+  "resync",
+]);
+
+export const ZRealtimeEvent = z.object({
+  id: z.string(),
+  type: ZRealtimeEventType,
+  data: z.record(z.unknown()),
+  occurredAt: z.string(),
+});
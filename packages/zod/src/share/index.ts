@@ -0,0 +1,25 @@
+import { ZTodoCategory } from "@/category/index.js";
+import { ZTodo } from "@/todo/index.js";
+import z from "zod";
+
+export const ZShareRole = z.enum(["viewer", "commenter", "editor"]);
+
+export const ZShareStatus = z.enum(["pending", "accepted", "declined"]);
+
+export const ZShare = z.object({
+  id: z.string().uuid(),
+  ownerId: z.string(),
+  granteeId: z.string(),
+  categoryId: z.string().uuid().nullable(),
+  todoId: z.string().uuid().nullable(),
+  role: ZShareRole,
+  status: ZShareStatus,
+  respondedAt: z.string().nullable(),
+  createdAt: z.string(),
+  updatedAt: z.string(),
+});
+
+export const ZPopulatedShare = ZShare.extend({
+  category: ZTodoCategory.nullable(),
+  todo: ZTodo.nullable(),
+});
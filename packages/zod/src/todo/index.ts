@@ -18,6 +18,7 @@ export const ZTodo = z.object({
   userId: z.string(),
   title: z.string(),
   description: z.string().nullable(),
+  descriptionHtml: z.string().nullable(),
   status: ZTodoStatus,
   priority: ZTodoPriority,
   dueDate: z.string().nullable(),
@@ -33,11 +34,79 @@ export const ZTodo = z.object({
 export const ZTodoAttachment = z.object({
   id: z.string().uuid(),
   todoId: z.string().uuid(),
+  kind: z.enum(["file", "link"]),
   name: z.string(),
+  url: z.string().url().nullable(),
+  description: z.string().nullable(),
   uploadedBy: z.string(),
-  downloadKey: z.string(),
+  downloadKey: z.string().optional(),
   fileSize: z.number().nullable(),
   mimeType: z.string().nullable(),
+  checksum: z.string().nullable(),
+  extractedAt: z.string().nullable(),
+  createdAt: z.string(),
+  updatedAt: z.string(),
+});
+
+export const ZTodoAssignee = z.object({
+  todoId: z.string().uuid(),
+  userId: z.string(),
+  assignedBy: z.string(),
+  createdAt: z.string(),
+});
+
+export const ZStorageUsage = z.object({
+  quotaBytes: z.number(),
+  usedBytes: z.number(),
+  versionBytes: z.number(),
+  reservedBytes: z.number(),
+  remainingBytes: z.number(),
+  fileCount: z.number(),
+  byType: z.array(
+    z.object({
+      type: z.enum(["image", "video", "audio", "document", "archive", "other"]),
+      fileCount: z.number(),
+      bytes: z.number(),
+    }),
+  ),
+  byCategory: z.array(
+    z.object({
+      categoryId: z.string().uuid().nullable(),
+      categoryName: z.string().nullable(),
+      fileCount: z.number(),
+      bytes: z.number(),
+    }),
+  ),
+});
+
+export const ZAttachmentUpload = z.object({
+  uploadId: z.string().uuid(),
+  method: z.enum(["PUT", "POST"]),
+  url: z.string().url(),
+  headers: z.record(z.string()).optional(),
+  fields: z.record(z.string()).optional(),
+  expiresAt: z.string(),
+});
+
+export const ZAttachmentArchive = z.object({
+  id: z.string().uuid(),
+  todoId: z.string().uuid().nullable(),
+  requestedBy: z.string(),
+  name: z.string(),
+  attachmentIds: z.array(z.string().uuid()),
+  status: z.enum(["pending", "ready", "failed"]),
+  fileSize: z.number().nullable(),
+  fileCount: z.number().nullable(),
+  error: z.string().nullable(),
+  completedAt: z.string().nullable(),
+  expiresAt: z.string(),
+  download: z
+    .object({
+      url: z.string().url(),
+      disposition: z.enum(["inline", "attachment"]),
+      expiresAt: z.string().datetime(),
+    })
+    .optional(),
   createdAt: z.string(),
   updatedAt: z.string(),
 });
@@ -47,6 +116,17 @@ export const ZPopulatedTodo = ZTodo.extend({
   children: z.array(ZTodo),
   comments: z.array(ZTodoComment),
   attachments: z.array(ZTodoAttachment),
+  assignees: z.array(ZTodoAssignee),
+  accessRole: z.enum(["viewer", "commenter", "editor", "owner"]),
+  matchedAttachments: z
+    .array(
+      z.object({
+        attachmentId: z.string().uuid(),
+        name: z.string(),
+        snippet: z.string(),
+      }),
+    )
+    .optional(),
 });
 
 export const ZTodoStats = z.object({
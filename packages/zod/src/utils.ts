@@ -18,3 +18,18 @@ export const schemaWithPagination = <T>(
     limit: z.number(),
     totalPages: z.number(),
   });
+
+export type CursorPage<T> = {
+  data: T[];
+  nextCursor: string | null;
+  hasMore: boolean;
+};
+
+export const schemaWithCursor = <T>(
+  schema: z.ZodSchema<T>
+): z.ZodSchema<CursorPage<T>> =>
+  z.object({
+    data: z.array(schema),
+    nextCursor: z.string().nullable(),
+    hasMore: z.boolean(),
+  });